@@ -0,0 +1,334 @@
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ControllerConfiguration configures the msi-acrpull controller manager.
+type ControllerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Defaults are used for AcrPullBindings that do not set the ACR server or managed identity.
+	// Changes are applied without a restart.
+	// +optional
+	Defaults DefaultsConfiguration `json:"defaults,omitempty"`
+
+	// Cloud selects the Azure cloud the controller authenticates against.
+	// +optional
+	Cloud CloudConfiguration `json:"cloud,omitempty"`
+
+	// RateLimit throttles requests to the instance metadata service and to ACR.
+	// Changes are applied without a restart.
+	// +optional
+	RateLimit RateLimitConfiguration `json:"rateLimit,omitempty"`
+
+	// Refresh controls when tokens are renewed. Changes to tokenRefreshBuffer are applied without a restart.
+	// +optional
+	Refresh RefreshConfiguration `json:"refresh,omitempty"`
+
+	// Concurrency controls how many AcrPullBindings are reconciled in parallel.
+	// +optional
+	Concurrency ConcurrencyConfiguration `json:"concurrency,omitempty"`
+
+	// Namespaces restricts the namespaces whose AcrPullBindings are reconciled.
+	// +optional
+	Namespaces NamespaceConfiguration `json:"namespaces,omitempty"`
+
+	// Mirroring allows AcrPullBindings to copy their pull secret into other namespaces. Mirroring is
+	// off unless source and target namespaces are listed.
+	// +optional
+	Mirroring MirroringConfiguration `json:"mirroring,omitempty"`
+
+	// Tracing exports OpenTelemetry spans of reconciles, token requests and API server writes.
+	// Tracing is off unless an exporter is set.
+	// +optional
+	Tracing TracingConfiguration `json:"tracing,omitempty"`
+
+	// Audit records every credential the controller issues, rotates or revokes.
+	// Auditing is off unless a sink is set.
+	// +optional
+	Audit AuditConfiguration `json:"audit,omitempty"`
+
+	// ExpiryWatchdog flags bindings whose credential is about to expire because it could not be refreshed.
+	// +optional
+	ExpiryWatchdog ExpiryWatchdogConfiguration `json:"expiryWatchdog,omitempty"`
+
+	// Health configures the readiness and liveness checks of the manager.
+	// +optional
+	Health HealthConfiguration `json:"health,omitempty"`
+
+	// LeaderElection configures the election of the replica that reconciles when the manager runs with
+	// --leader-elect.
+	// +optional
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+}
+
+// DefaultsConfiguration holds the values used when an AcrPullBinding leaves them empty.
+type DefaultsConfiguration struct {
+	// The full server name for the ACR. For example, test.azurecr.io
+	// +optional
+	ACRServer string `json:"acrServer,omitempty"`
+
+	// The Managed Identity resource ID that is used to authenticate with ACR
+	// +optional
+	ManagedIdentityResourceID string `json:"managedIdentityResourceID,omitempty"`
+
+	// The Managed Identity client ID that is used to authenticate with ACR
+	// +optional
+	ManagedIdentityClientID string `json:"managedIdentityClientID,omitempty"`
+}
+
+// CloudName identifies an Azure cloud.
+type CloudName string
+
+const (
+	// AzurePublicCloud is the global Azure cloud.
+	AzurePublicCloud CloudName = "AzurePublicCloud"
+	// AzureUSGovernmentCloud is the Azure US Government cloud.
+	AzureUSGovernmentCloud CloudName = "AzureUSGovernmentCloud"
+	// AzureChinaCloud is the Azure China cloud operated by 21Vianet.
+	AzureChinaCloud CloudName = "AzureChinaCloud"
+)
+
+// CloudConfiguration selects the endpoints used to acquire tokens.
+type CloudConfiguration struct {
+	// Name of the Azure cloud. Defaults to AzurePublicCloud.
+	// +optional
+	Name CloudName `json:"name,omitempty"`
+
+	// ARMResource overrides the resource ARM tokens are requested for. Defaults to the
+	// Resource Manager endpoint of the selected cloud.
+	// +optional
+	ARMResource string `json:"armResource,omitempty"`
+
+	// MetadataEndpoint overrides the instance metadata service token endpoint.
+	// +optional
+	MetadataEndpoint string `json:"metadataEndpoint,omitempty"`
+
+	// ActiveDirectoryEndpoint overrides the Microsoft Entra endpoint workload identities and service
+	// principals request ARM tokens from. Defaults to the endpoint of the selected cloud.
+	// +optional
+	ActiveDirectoryEndpoint string `json:"activeDirectoryEndpoint,omitempty"`
+
+	// TokenExchangeAudience overrides the audience of the service account tokens exchanged for the
+	// tokens of workload identities. Defaults to the audience federated credentials use in the selected cloud.
+	// +optional
+	TokenExchangeAudience string `json:"tokenExchangeAudience,omitempty"`
+
+	// TenantID is the Microsoft Entra tenant of the identities. When set, ACR tokens issued for
+	// another tenant are rejected before they are written into a pull secret, and workload
+	// identities without a tenant use it.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+}
+
+// RateLimitConfiguration configures the client side rate limiter.
+type RateLimitConfiguration struct {
+	// QPS is the sustained number of requests per second. Defaults to 1.
+	// +optional
+	QPS float64 `json:"qps,omitempty"`
+
+	// Burst is the maximum number of requests sent at once. Defaults to 5.
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+// RefreshConfiguration configures token renewal.
+type RefreshConfiguration struct {
+	// TokenRefreshBuffer is how long before expiry an ACR token is replaced. Defaults to 30m.
+	// +optional
+	TokenRefreshBuffer metav1.Duration `json:"tokenRefreshBuffer,omitempty"`
+
+	// ARMTokenCacheDuration is how long an ARM token is reused before a new one is requested
+	// from the instance metadata service. Defaults to 10m. Changes take effect after a restart.
+	// +optional
+	ARMTokenCacheDuration metav1.Duration `json:"armTokenCacheDuration,omitempty"`
+}
+
+// ConcurrencyConfiguration configures reconcile parallelism.
+type ConcurrencyConfiguration struct {
+	// MaxConcurrentReconciles is the number of AcrPullBindings reconciled at the same time. Defaults to 1.
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+}
+
+// TracingExporter selects where spans are sent.
+type TracingExporter string
+
+const (
+	// TracingExporterNone disables tracing.
+	TracingExporterNone TracingExporter = "None"
+	// TracingExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP.
+	TracingExporterOTLP TracingExporter = "OTLP"
+	// TracingExporterStdout writes spans to standard output, for debugging.
+	TracingExporterStdout TracingExporter = "Stdout"
+)
+
+// TracingConfiguration configures the OpenTelemetry tracer provider.
+type TracingConfiguration struct {
+	// Exporter is None, OTLP or Stdout. Defaults to None.
+	// +optional
+	Exporter TracingExporter `json:"exporter,omitempty"`
+
+	// Endpoint is the host and port of the OTLP/HTTP collector, for example otel-collector:4318.
+	// Defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable, then to localhost:4318.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure sends spans to the collector over plain HTTP instead of HTTPS.
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+
+	// SamplingRatio is the fraction of reconciles that are traced, between 0 and 1. Defaults to 1 when
+	// unset, 0 traces none.
+	// +optional
+	SamplingRatio *float64 `json:"samplingRatio,omitempty"`
+}
+
+// AuditSink selects where audit records are written.
+type AuditSink string
+
+const (
+	// AuditSinkNone disables auditing.
+	AuditSinkNone AuditSink = "None"
+	// AuditSinkFile appends audit records to a file as JSON lines.
+	AuditSinkFile AuditSink = "File"
+	// AuditSinkStdout writes audit records to standard output as JSON lines.
+	AuditSinkStdout AuditSink = "Stdout"
+	// AuditSinkWebhook posts every audit record as JSON to a URL.
+	AuditSinkWebhook AuditSink = "Webhook"
+)
+
+// AuditConfiguration configures the audit log of issued credentials.
+type AuditConfiguration struct {
+	// Sink is None, File, Stdout or Webhook. Defaults to None.
+	// +optional
+	Sink AuditSink `json:"sink,omitempty"`
+
+	// Path is the file audit records are appended to when the sink is File.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// WebhookURL is the URL audit records are posted to when the sink is Webhook.
+	// +optional
+	WebhookURL string `json:"webhookURL,omitempty"`
+
+	// WebhookTimeout bounds each request to the webhook. Defaults to 10s.
+	// +optional
+	WebhookTimeout metav1.Duration `json:"webhookTimeout,omitempty"`
+}
+
+// ExpiryWatchdogConfiguration configures the scan of the pull secrets for expiring credentials.
+type ExpiryWatchdogConfiguration struct {
+	// Interval is how often the pull secrets are scanned. Defaults to 1m.
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// ExpiringThreshold is how long before its expiry a credential is reported as expiring. It must be
+	// shorter than refresh.tokenRefreshBuffer, as credentials are only that close to expiring when their
+	// refresh failed. Defaults to 10m.
+	// +optional
+	ExpiringThreshold metav1.Duration `json:"expiringThreshold,omitempty"`
+
+	// ReconcileExpiring reconciles the bindings with an expiring or expired credential on every scan,
+	// ahead of the backoff of their failed reconciles.
+	// +optional
+	ReconcileExpiring bool `json:"reconcileExpiring,omitempty"`
+}
+
+// HealthConfiguration configures the checks served on /readyz and /healthz.
+type HealthConfiguration struct {
+	// MetadataEndpointTimeout bounds the request the readiness check sends to the instance metadata
+	// service. It must be shorter than the timeout of the readiness probe. Defaults to 3s.
+	// +optional
+	MetadataEndpointTimeout metav1.Duration `json:"metadataEndpointTimeout,omitempty"`
+
+	// StuckReconcileWindow is how long the leader can go without a successful reconcile while bindings are
+	// due for refresh before the liveness check fails. It must be longer than the longest retry backoff of a
+	// failing binding, which is about 17 minutes, so that a binding recovering after a backoff is not cut
+	// short. Defaults to 30m.
+	// +optional
+	StuckReconcileWindow metav1.Duration `json:"stuckReconcileWindow,omitempty"`
+}
+
+// LeaderElectionConfiguration configures the lease the replicas of the controller compete for.
+type LeaderElectionConfiguration struct {
+	// ID is the name of the lease. Every deployment of the controller in a namespace needs its own ID,
+	// sharded deployments append the shard to it. Defaults to msi-acrpull.aks.azure.com. Earlier releases
+	// used aks.azure.com and do not see the new lease: scale them down before upgrading, or set
+	// aks.azure.com while replicas of such a release are still running.
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Namespace is the namespace of the lease. Defaults to the namespace the controller runs in.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// LeaseDuration is how long standby replicas wait before taking over a lease that was not renewed.
+	// Defaults to 15s.
+	// +optional
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// RenewDeadline is how long the leader keeps trying to renew the lease before it stops reconciling.
+	// It must be shorter than LeaseDuration. Defaults to 10s.
+	// +optional
+	RenewDeadline metav1.Duration `json:"renewDeadline,omitempty"`
+
+	// RetryPeriod is how often the replicas try to acquire or renew the lease. Defaults to 2s.
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// NamespaceConfiguration filters namespaces by name.
+type NamespaceConfiguration struct {
+	// Include lists the namespaces to reconcile. All namespaces are reconciled when empty.
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude lists namespaces that are never reconciled, even if they are included.
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// MirroringConfiguration lists the namespaces pull secrets may be mirrored from and into. Mirroring reads
+// and writes secrets outside of the namespace of a binding, so it needs the cluster-wide role of the
+// controller and can not be combined with namespaces.include.
+type MirroringConfiguration struct {
+	// SourceNamespaces lists the namespaces whose AcrPullBindings may mirror their pull secret.
+	// +optional
+	SourceNamespaces []string `json:"sourceNamespaces,omitempty"`
+
+	// TargetNamespaces lists the namespaces copies may be written into. Selected namespaces that are
+	// not listed are reported in the SecretsMirrored condition of the binding.
+	// +optional
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+}
+
+// Enabled reports whether any binding may mirror its pull secret.
+func (c MirroringConfiguration) Enabled() bool {
+	return len(c.SourceNamespaces) > 0 && len(c.TargetNamespaces) > 0
+}
@@ -0,0 +1,157 @@
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package v1alpha1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DefaultQPS                     = 1
+	DefaultBurst                   = 5
+	DefaultTokenRefreshBuffer      = 30 * time.Minute
+	DefaultARMTokenCacheDuration   = 10 * time.Minute
+	DefaultMaxConcurrentReconciles = 1
+	DefaultMetadataEndpoint        = "http://169.254.169.254/metadata/identity/oauth2/token"
+	DefaultTracingSamplingRatio    = 1.0
+	DefaultAuditWebhookTimeout     = 10 * time.Second
+	DefaultExpiryWatchdogInterval  = time.Minute
+	DefaultExpiringThreshold       = 10 * time.Minute
+	DefaultMetadataEndpointTimeout = 3 * time.Second
+	DefaultStuckReconcileWindow    = 30 * time.Minute
+	DefaultLeaderElectionID        = "msi-acrpull.aks.azure.com"
+	DefaultLeaseDuration           = 15 * time.Second
+	DefaultRenewDeadline           = 10 * time.Second
+	DefaultRetryPeriod             = 2 * time.Second
+)
+
+// ARMResources maps each known cloud to the resource its ARM tokens are issued for.
+var ARMResources = map[CloudName]string{
+	AzurePublicCloud:       "https://management.azure.com/",
+	AzureUSGovernmentCloud: "https://management.usgovcloudapi.net/",
+	AzureChinaCloud:        "https://management.chinacloudapi.cn/",
+}
+
+// ActiveDirectoryEndpoints maps each known cloud to the Microsoft Entra endpoint applications request tokens from.
+var ActiveDirectoryEndpoints = map[CloudName]string{
+	AzurePublicCloud:       "https://login.microsoftonline.com/",
+	AzureUSGovernmentCloud: "https://login.microsoftonline.us/",
+	AzureChinaCloud:        "https://login.chinacloudapi.cn/",
+}
+
+// TokenExchangeAudiences maps each known cloud to the audience of the tokens its federated credentials accept.
+var TokenExchangeAudiences = map[CloudName]string{
+	AzurePublicCloud:       "api://AzureADTokenExchange",
+	AzureUSGovernmentCloud: "api://AzureADTokenExchangeUSGov",
+	AzureChinaCloud:        "api://AzureADTokenExchangeChina",
+}
+
+// SetDefaults fills in the unset fields of the configuration.
+func SetDefaults(cfg *ControllerConfiguration) {
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = GroupVersion.String()
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = ControllerConfigurationKind
+	}
+
+	if cfg.Cloud.Name == "" {
+		cfg.Cloud.Name = AzurePublicCloud
+	}
+	if cfg.Cloud.ARMResource == "" {
+		cfg.Cloud.ARMResource = ARMResources[cfg.Cloud.Name]
+	}
+	if cfg.Cloud.MetadataEndpoint == "" {
+		cfg.Cloud.MetadataEndpoint = DefaultMetadataEndpoint
+	}
+	if cfg.Cloud.ActiveDirectoryEndpoint == "" {
+		cfg.Cloud.ActiveDirectoryEndpoint = ActiveDirectoryEndpoints[cfg.Cloud.Name]
+	}
+	if cfg.Cloud.TokenExchangeAudience == "" {
+		cfg.Cloud.TokenExchangeAudience = TokenExchangeAudiences[cfg.Cloud.Name]
+	}
+
+	if cfg.RateLimit.QPS == 0 {
+		cfg.RateLimit.QPS = DefaultQPS
+	}
+	if cfg.RateLimit.Burst == 0 {
+		cfg.RateLimit.Burst = DefaultBurst
+	}
+
+	if cfg.Refresh.TokenRefreshBuffer.Duration == 0 {
+		cfg.Refresh.TokenRefreshBuffer = metav1.Duration{Duration: DefaultTokenRefreshBuffer}
+	}
+	if cfg.Refresh.ARMTokenCacheDuration.Duration == 0 {
+		cfg.Refresh.ARMTokenCacheDuration = metav1.Duration{Duration: DefaultARMTokenCacheDuration}
+	}
+
+	if cfg.Concurrency.MaxConcurrentReconciles == 0 {
+		cfg.Concurrency.MaxConcurrentReconciles = DefaultMaxConcurrentReconciles
+	}
+
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = TracingExporterNone
+	}
+	if cfg.Tracing.SamplingRatio == nil {
+		samplingRatio := DefaultTracingSamplingRatio
+		cfg.Tracing.SamplingRatio = &samplingRatio
+	}
+
+	if cfg.Audit.Sink == "" {
+		cfg.Audit.Sink = AuditSinkNone
+	}
+	if cfg.Audit.WebhookTimeout.Duration == 0 {
+		cfg.Audit.WebhookTimeout = metav1.Duration{Duration: DefaultAuditWebhookTimeout}
+	}
+
+	if cfg.ExpiryWatchdog.Interval.Duration == 0 {
+		cfg.ExpiryWatchdog.Interval = metav1.Duration{Duration: DefaultExpiryWatchdogInterval}
+	}
+	if cfg.ExpiryWatchdog.ExpiringThreshold.Duration == 0 {
+		cfg.ExpiryWatchdog.ExpiringThreshold = metav1.Duration{Duration: DefaultExpiringThreshold}
+	}
+
+	if cfg.Health.MetadataEndpointTimeout.Duration == 0 {
+		cfg.Health.MetadataEndpointTimeout = metav1.Duration{Duration: DefaultMetadataEndpointTimeout}
+	}
+	if cfg.Health.StuckReconcileWindow.Duration == 0 {
+		cfg.Health.StuckReconcileWindow = metav1.Duration{Duration: DefaultStuckReconcileWindow}
+	}
+
+	if cfg.LeaderElection.ID == "" {
+		cfg.LeaderElection.ID = DefaultLeaderElectionID
+	}
+	if cfg.LeaderElection.LeaseDuration.Duration == 0 {
+		cfg.LeaderElection.LeaseDuration = metav1.Duration{Duration: DefaultLeaseDuration}
+	}
+	if cfg.LeaderElection.RenewDeadline.Duration == 0 {
+		cfg.LeaderElection.RenewDeadline = metav1.Duration{Duration: DefaultRenewDeadline}
+	}
+	if cfg.LeaderElection.RetryPeriod.Duration == 0 {
+		cfg.LeaderElection.RetryPeriod = metav1.Duration{Duration: DefaultRetryPeriod}
+	}
+}
@@ -0,0 +1,274 @@
+//go:build !ignore_autogenerated
+
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import ()
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditConfiguration) DeepCopyInto(out *AuditConfiguration) {
+	*out = *in
+	out.WebhookTimeout = in.WebhookTimeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditConfiguration.
+func (in *AuditConfiguration) DeepCopy() *AuditConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudConfiguration) DeepCopyInto(out *CloudConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudConfiguration.
+func (in *CloudConfiguration) DeepCopy() *CloudConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConcurrencyConfiguration) DeepCopyInto(out *ConcurrencyConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyConfiguration.
+func (in *ConcurrencyConfiguration) DeepCopy() *ConcurrencyConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ConcurrencyConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerConfiguration) DeepCopyInto(out *ControllerConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.Defaults = in.Defaults
+	out.Cloud = in.Cloud
+	out.RateLimit = in.RateLimit
+	out.Refresh = in.Refresh
+	out.Concurrency = in.Concurrency
+	in.Namespaces.DeepCopyInto(&out.Namespaces)
+	in.Mirroring.DeepCopyInto(&out.Mirroring)
+	in.Tracing.DeepCopyInto(&out.Tracing)
+	out.Audit = in.Audit
+	out.ExpiryWatchdog = in.ExpiryWatchdog
+	out.Health = in.Health
+	out.LeaderElection = in.LeaderElection
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerConfiguration.
+func (in *ControllerConfiguration) DeepCopy() *ControllerConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultsConfiguration) DeepCopyInto(out *DefaultsConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultsConfiguration.
+func (in *DefaultsConfiguration) DeepCopy() *DefaultsConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultsConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExpiryWatchdogConfiguration) DeepCopyInto(out *ExpiryWatchdogConfiguration) {
+	*out = *in
+	out.Interval = in.Interval
+	out.ExpiringThreshold = in.ExpiringThreshold
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExpiryWatchdogConfiguration.
+func (in *ExpiryWatchdogConfiguration) DeepCopy() *ExpiryWatchdogConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ExpiryWatchdogConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthConfiguration) DeepCopyInto(out *HealthConfiguration) {
+	*out = *in
+	out.MetadataEndpointTimeout = in.MetadataEndpointTimeout
+	out.StuckReconcileWindow = in.StuckReconcileWindow
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthConfiguration.
+func (in *HealthConfiguration) DeepCopy() *HealthConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaderElectionConfiguration) DeepCopyInto(out *LeaderElectionConfiguration) {
+	*out = *in
+	out.LeaseDuration = in.LeaseDuration
+	out.RenewDeadline = in.RenewDeadline
+	out.RetryPeriod = in.RetryPeriod
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaderElectionConfiguration.
+func (in *LeaderElectionConfiguration) DeepCopy() *LeaderElectionConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaderElectionConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MirroringConfiguration) DeepCopyInto(out *MirroringConfiguration) {
+	*out = *in
+	if in.SourceNamespaces != nil {
+		in, out := &in.SourceNamespaces, &out.SourceNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MirroringConfiguration.
+func (in *MirroringConfiguration) DeepCopy() *MirroringConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(MirroringConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceConfiguration) DeepCopyInto(out *NamespaceConfiguration) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceConfiguration.
+func (in *NamespaceConfiguration) DeepCopy() *NamespaceConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitConfiguration) DeepCopyInto(out *RateLimitConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitConfiguration.
+func (in *RateLimitConfiguration) DeepCopy() *RateLimitConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RefreshConfiguration) DeepCopyInto(out *RefreshConfiguration) {
+	*out = *in
+	out.TokenRefreshBuffer = in.TokenRefreshBuffer
+	out.ARMTokenCacheDuration = in.ARMTokenCacheDuration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RefreshConfiguration.
+func (in *RefreshConfiguration) DeepCopy() *RefreshConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(RefreshConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingConfiguration) DeepCopyInto(out *TracingConfiguration) {
+	*out = *in
+	if in.SamplingRatio != nil {
+		in, out := &in.SamplingRatio, &out.SamplingRatio
+		*out = new(float64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingConfiguration.
+func (in *TracingConfiguration) DeepCopy() *TracingConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
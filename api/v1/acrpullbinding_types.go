@@ -0,0 +1,430 @@
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AcrPullBindingSpec defines the desired state of AcrPullBinding
+type AcrPullBindingSpec struct {
+	// The full server name for the ACR, for example test.azurecr.io. Defaults to the ACR server configured for the
+	// controller.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	AcrServer string `json:"acrServer,omitempty"`
+
+	// The identity that is used to authenticate with ACR. Defaults to the managed identity configured for the
+	// controller.
+	// +optional
+	Identity *Identity `json:"identity,omitempty"`
+
+	// The Service Account to associate the image pull secret with.
+	// +optional
+	// +kubebuilder:default=default
+	// +kubebuilder:validation:MaxLength=253
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// The format of the image pull secret. DockerConfigJSON, the default, writes a kubernetes.io/dockerconfigjson
+	// secret with a username and password. DockerConfigJSONIdentityToken writes the ACR refresh token as an
+	// identitytoken instead, for clients that exchange it for access tokens themselves; the kubelet does not
+	// support it. DockerCfg writes a legacy kubernetes.io/dockercfg secret.
+	// +optional
+	// +kubebuilder:default=DockerConfigJSON
+	SecretFormat SecretFormat `json:"secretFormat,omitempty"`
+
+	// The name, labels and annotations of the image pull secret created by the controller. The controller adds
+	// its own labels and annotations on top, describing the identity, registry and token expiry.
+	// +optional
+	SecretTemplate *SecretTemplate `json:"secretTemplate,omitempty"`
+
+	// The name of an existing image pull secret in the namespace, for example a shared secret that also holds
+	// credentials of other registries. When set, no secret is created; the controller only maintains the entries
+	// of the ACR server and its host aliases in the existing secret and removes them, along with the reference
+	// the controller added to the service account, when the binding is deleted or targets another secret.
+	// The format of the credential follows the type of the existing secret.
+	// +optional
+	ExistingSecretName string `json:"existingSecretName,omitempty"`
+
+	// Additional host names of the registry to write the credential for, for example geo-replica or
+	// data endpoint host names.
+	// +optional
+	// +listType=set
+	HostAliases []string `json:"hostAliases,omitempty"`
+
+	// Copy the image pull secret into other namespaces. The copies are owned by the controller, refreshed together
+	// with the secret and deleted when their namespace no longer matches.
+	// +optional
+	Mirror *SecretMirror `json:"mirror,omitempty"`
+
+	// Verify the pull credential against the registry after it is minted. The outcome is recorded in the
+	// PullVerified condition. Verification is skipped when this is not specified.
+	// +optional
+	PullVerification *PullVerification `json:"pullVerification,omitempty"`
+}
+
+// IdentityType is the kind of identity used to authenticate with ACR.
+// +kubebuilder:validation:Enum=ClientID;ResourceID;Workload;ServicePrincipal
+type IdentityType string
+
+const (
+	// IdentityTypeClientID is a managed identity of the node pool selected by its client ID.
+	IdentityTypeClientID IdentityType = "ClientID"
+	// IdentityTypeResourceID is a managed identity of the node pool selected by its resource ID.
+	IdentityTypeResourceID IdentityType = "ResourceID"
+	// IdentityTypeWorkload is a Microsoft Entra application or managed identity federated with a service account.
+	IdentityTypeWorkload IdentityType = "Workload"
+	// IdentityTypeServicePrincipal is a Microsoft Entra application authenticating with a client secret.
+	IdentityTypeServicePrincipal IdentityType = "ServicePrincipal"
+)
+
+// Identity is the identity used to authenticate with ACR. Exactly the member named by type is set.
+// +union
+// +kubebuilder:validation:XValidation:rule="self.type == 'ClientID' ? has(self.clientID) : !has(self.clientID)",message="clientID must be set if and only if type is ClientID"
+// +kubebuilder:validation:XValidation:rule="self.type == 'ResourceID' ? has(self.resourceID) : !has(self.resourceID)",message="resourceID must be set if and only if type is ResourceID"
+// +kubebuilder:validation:XValidation:rule="self.type == 'Workload' ? has(self.workload) : !has(self.workload)",message="workload must be set if and only if type is Workload"
+// +kubebuilder:validation:XValidation:rule="self.type == 'ServicePrincipal' ? has(self.servicePrincipal) : !has(self.servicePrincipal)",message="servicePrincipal must be set if and only if type is ServicePrincipal"
+type Identity struct {
+	// The kind of identity.
+	// +unionDiscriminator
+	Type IdentityType `json:"type"`
+
+	// The client ID of a managed identity assigned to the node pool.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	ClientID string `json:"clientID,omitempty"`
+
+	// The resource ID of a managed identity assigned to the node pool.
+	// +optional
+	// +kubebuilder:validation:MaxLength=1024
+	// +kubebuilder:validation:XValidation:rule="self.startsWith('/subscriptions/')",message="resourceID must be an Azure resource ID"
+	ResourceID string `json:"resourceID,omitempty"`
+
+	// A workload identity federated with a service account of the namespace.
+	// +optional
+	Workload *WorkloadIdentity `json:"workload,omitempty"`
+
+	// A service principal authenticating with a client secret.
+	// +optional
+	ServicePrincipal *ServicePrincipalIdentity `json:"servicePrincipal,omitempty"`
+}
+
+// WorkloadIdentity is a Microsoft Entra application or managed identity that trusts the tokens of a service account.
+type WorkloadIdentity struct {
+	// The client ID of the application or managed identity.
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	ClientID string `json:"clientID"`
+
+	// The tenant of the application. Defaults to the tenant configured for the controller.
+	// +optional
+	TenantID string `json:"tenantID,omitempty"`
+
+	// The service account whose tokens are exchanged. Defaults to the service account of the binding.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// ServicePrincipalIdentity is a Microsoft Entra application authenticating with a client secret.
+type ServicePrincipalIdentity struct {
+	// The tenant of the application.
+	TenantID string `json:"tenantID"`
+
+	// The client ID of the application.
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	ClientID string `json:"clientID"`
+
+	// The secret in the namespace of the binding holding the client secret.
+	ClientSecretRef SecretKeySelector `json:"clientSecretRef"`
+}
+
+// SecretKeySelector selects a key of a secret in the namespace of the binding.
+type SecretKeySelector struct {
+	// The name of the secret.
+	Name string `json:"name"`
+
+	// The key of the value in the secret.
+	// +optional
+	// +kubebuilder:default=clientSecret
+	Key string `json:"key,omitempty"`
+}
+
+// SecretTemplate describes the metadata of a generated image pull secret.
+type SecretTemplate struct {
+	// The name of the secret. Defaults to <binding name>-msi-acrpull-secret.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name,omitempty"`
+
+	// Labels added to the secret.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the secret.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SecretMirror selects the namespaces an image pull secret is copied into.
+type SecretMirror struct {
+	// Namespaces whose labels match the selector receive a copy of the secret. Namespaces can be selected by name
+	// with the kubernetes.io/metadata.name label. The namespace of the binding is never a target.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+}
+
+// SecretFormat is the format of an image pull secret.
+// +kubebuilder:validation:Enum=DockerConfigJSON;DockerConfigJSONIdentityToken;DockerCfg
+type SecretFormat string
+
+const (
+	// SecretFormatDockerConfigJSON is a kubernetes.io/dockerconfigjson secret with a username and password.
+	SecretFormatDockerConfigJSON SecretFormat = "DockerConfigJSON"
+	// SecretFormatDockerConfigJSONIdentityToken is a kubernetes.io/dockerconfigjson secret with an identitytoken.
+	SecretFormatDockerConfigJSONIdentityToken SecretFormat = "DockerConfigJSONIdentityToken"
+	// SecretFormatDockerCfg is a legacy kubernetes.io/dockercfg secret.
+	SecretFormatDockerCfg SecretFormat = "DockerCfg"
+)
+
+// PullVerification configures checking a newly minted pull credential against the registry.
+type PullVerification struct {
+	// An image in the registry whose manifest is requested to check pull access, for example
+	// team/probe:latest or test.azurecr.io/team/probe@sha256:... When empty, only the registry
+	// /v2/ endpoint is called, which checks that the registry accepts the credential.
+	// +optional
+	ProbeImage string `json:"probeImage,omitempty"`
+}
+
+const (
+	// ConditionReady summarizes whether pods can pull with the credential of the binding. It is False with
+	// the reason of the first failing condition, or ReasonReconcileFailed when the credential could not be issued.
+	ConditionReady = "Ready"
+
+	// ReasonCredentialIssued is the reason when the credential is issued and every other condition is True.
+	ReasonCredentialIssued = "CredentialIssued"
+	// ReasonReconcileFailed is the reason when the credential could not be issued or stored.
+	ReasonReconcileFailed = "ReconcileFailed"
+	// ReasonSecretConflict is the reason when a secret of the pull secret name exists that the controller did
+	// not write, or of another type. The controller leaves it alone.
+	ReasonSecretConflict = "SecretConflict"
+
+	// ConditionPullVerified reports whether the pull credential was accepted by the registry.
+	ConditionPullVerified = "PullVerified"
+
+	// ReasonPullVerified is the reason of a successful pull verification.
+	ReasonPullVerified = "Verified"
+	// ReasonRegistryAccessDenied is the reason when the registry rejects the credential.
+	ReasonRegistryAccessDenied = "RegistryAccessDenied"
+
+	// ConditionSecretsMirrored reports whether the image pull secret was copied into every target namespace.
+	ConditionSecretsMirrored = "SecretsMirrored"
+
+	// ReasonSecretsMirrored is the reason when every target namespace has a copy.
+	ReasonSecretsMirrored = "Mirrored"
+	// ReasonMirrorConflict is the reason when a target namespace has a secret of the same name not owned by the binding.
+	ReasonMirrorConflict = "Conflict"
+	// ReasonMirrorNotAllowed is the reason when the controller configuration does not allow mirroring from the
+	// namespace of the binding or into a selected namespace.
+	ReasonMirrorNotAllowed = "NotAllowed"
+
+	// ConditionServiceAccountBound reports whether the image pull secret is referenced by the service account.
+	ConditionServiceAccountBound = "ServiceAccountBound"
+	// ReasonServiceAccountBound is the reason when the service account references the image pull secret.
+	ReasonServiceAccountBound = "Bound"
+	// ReasonServiceAccountNotFound is the reason when the service account does not exist yet.
+	ReasonServiceAccountNotFound = "ServiceAccountNotFound"
+
+	// ConditionCredentialValid reports whether the credential in the pull secrets of the binding is still
+	// valid. The expiry watchdog of the controller updates it independently of reconciles, so that a binding
+	// whose refresh keeps failing is flagged before pods fail to pull.
+	ConditionCredentialValid = "CredentialValid"
+	// ReasonCredentialValid is the reason when the credential is not close to expiring.
+	ReasonCredentialValid = "Valid"
+	// ReasonCredentialExpiring is the reason when the credential expires within the threshold of the watchdog.
+	// The condition is still True.
+	ReasonCredentialExpiring = "Expiring"
+	// ReasonCredentialExpired is the reason when the credential has expired.
+	ReasonCredentialExpired = "Expired"
+)
+
+const (
+	// ManagedByLabel is set to ManagedByValue on every image pull secret created by the controller.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue identifies the controller in ManagedByLabel.
+	ManagedByValue = "msi-acrpull"
+
+	// AcrServerAnnotation is the ACR server the credential of an image pull secret is for.
+	AcrServerAnnotation = "msi-acrpull.microsoft.com/acr-server"
+	// ManagedIdentityAnnotation is the client ID or resource ID of the managed identity behind the credential.
+	ManagedIdentityAnnotation = "msi-acrpull.microsoft.com/managed-identity"
+	// TokenExpiryAnnotation is the expiration time of the credential in RFC 3339 format.
+	TokenExpiryAnnotation = "msi-acrpull.microsoft.com/token-expiry"
+	// TokenIDAnnotation is the jti claim of the credential, which identifies it in the audit log.
+	TokenIDAnnotation = "msi-acrpull.microsoft.com/token-id"
+	// LastRefreshAnnotation is the time the credential was last refreshed in RFC 3339 format.
+	LastRefreshAnnotation = "msi-acrpull.microsoft.com/last-refresh"
+	// MirrorSourceAnnotation is the namespace/name of the AcrPullBinding a mirrored image pull secret is copied from.
+	MirrorSourceAnnotation = "msi-acrpull.microsoft.com/mirror-source"
+	// CorrelationIDAnnotation is the ID of the reconcile that recorded an event, which is also logged as
+	// reconcileID and recorded in the audit log.
+	CorrelationIDAnnotation = "msi-acrpull.microsoft.com/correlation-id"
+)
+
+// AcrPullBindingStatus defines the observed state of AcrPullBinding
+type AcrPullBindingStatus struct {
+	// Information when was the last time the ACR token was refreshed.
+	// +optional
+	LastTokenRefreshTime *metav1.Time `json:"lastTokenRefreshTime,omitempty"`
+
+	// The expiration date of the current ACR token.
+	// +optional
+	TokenExpirationTime *metav1.Time `json:"tokenExpirationTime,omitempty"`
+
+	// Error message if there was an error updating the token.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// The ACR server the credential is issued for, after applying the defaults of the controller.
+	// +optional
+	AcrServer string `json:"acrServer,omitempty"`
+
+	// A short form of the identity behind the credential: the name of a managed identity given by
+	// resource ID, or its client ID.
+	// +optional
+	Identity string `json:"identity,omitempty"`
+
+	// The comma separated names of the service accounts the binding targets and is bound to.
+	// +optional
+	ServiceAccounts string `json:"serviceAccounts,omitempty"`
+
+	// The service accounts the controller added an image pull secret reference to. They are unbound when
+	// the binding targets another service account or secret, and when the binding is deleted.
+	// +optional
+	// +listType=atomic
+	BoundServiceAccounts []BoundServiceAccount `json:"boundServiceAccounts,omitempty"`
+
+	// The name of the existing secret the controller last wrote the credential into. The entries of the
+	// binding are removed from it when spec.existingSecretName changes or is cleared.
+	// +optional
+	ExistingSecretName string `json:"existingSecretName,omitempty"`
+
+	// The namespaces the image pull secret is currently copied into.
+	// +optional
+	MirroredNamespaces []string `json:"mirroredNamespaces,omitempty"`
+
+	// Coverage reports which workloads of the namespace pull from the registry of the binding and which
+	// registries no binding of the namespace covers. It is only set when the controller audits registry coverage.
+	// +optional
+	Coverage *RegistryCoverage `json:"coverage,omitempty"`
+
+	// Conditions describe the state of the pull credential.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BoundServiceAccount is a service account that references the image pull secret of a binding.
+type BoundServiceAccount struct {
+	// Name of the service account.
+	Name string `json:"name"`
+
+	// The name of the image pull secret the service account references.
+	PullSecretName string `json:"pullSecretName"`
+}
+
+// MaxCoverageEntries is the maximum number of workloads and registries listed in each field of RegistryCoverage.
+const MaxCoverageEntries = 50
+
+// RegistryCoverage is the result of matching the image references of the workloads of a namespace against
+// the registries of its AcrPullBindings.
+type RegistryCoverage struct {
+	// The workloads with images served by the registry of this binding.
+	// +optional
+	CoveredWorkloads []WorkloadImages `json:"coveredWorkloads,omitempty"`
+
+	// The registries referenced by workloads of the namespace that no AcrPullBinding of the namespace covers.
+	// +optional
+	UncoveredRegistries []string `json:"uncoveredRegistries,omitempty"`
+
+	// The workloads with images from registries that no AcrPullBinding of the namespace covers.
+	// +optional
+	UncoveredWorkloads []WorkloadImages `json:"uncoveredWorkloads,omitempty"`
+
+	// Truncated is set when there were more than 50 entries in one of the lists.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+
+	// The time the workloads were last inspected.
+	// +optional
+	AuditTime *metav1.Time `json:"auditTime,omitempty"`
+}
+
+// WorkloadImages lists image references of a workload.
+type WorkloadImages struct {
+	// Kind of the workload, for example Deployment or Pod.
+	Kind string `json:"kind"`
+
+	// Name of the workload.
+	Name string `json:"name"`
+
+	// The image references of the workload.
+	// +optional
+	Images []string `json:"images,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:resource:shortName=apb,categories=acr
+//+kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.status.acrServer`
+//+kubebuilder:printcolumn:name="Identity",type=string,JSONPath=`.status.identity`
+//+kubebuilder:printcolumn:name="Service Accounts",type=string,JSONPath=`.status.serviceAccounts`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+//+kubebuilder:printcolumn:name="Token Expiry",type=string,JSONPath=`.status.tokenExpirationTime`
+//+kubebuilder:printcolumn:name="Last Refresh",type=date,JSONPath=`.status.lastTokenRefreshTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AcrPullBinding is the Schema for the acrpullbindings API
+type AcrPullBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AcrPullBindingSpec   `json:"spec,omitempty"`
+	Status AcrPullBindingStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AcrPullBindingList contains a list of AcrPullBinding
+type AcrPullBindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AcrPullBinding `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AcrPullBinding{}, &AcrPullBindingList{})
+}
@@ -0,0 +1,209 @@
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+const (
+	// identityAnnotation preserves a v1 identity that can not be expressed with the managed identity fields
+	// of v1beta1, such as a workload identity.
+	identityAnnotation = "msi-acrpull.microsoft.com/v1-identity"
+	// ignoredResourceIDAnnotation preserves a v1beta1 managed identity resource ID that is ignored because
+	// the client ID is set, as v1 only holds one of them.
+	ignoredResourceIDAnnotation = "msi-acrpull.microsoft.com/v1beta1-managed-identity-resource-id"
+)
+
+var _ conversion.Convertible = &AcrPullBinding{}
+
+// ConvertTo converts this AcrPullBinding to the hub version.
+func (src *AcrPullBinding) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*msiacrpullv1.AcrPullBinding)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	annotations := dst.GetAnnotations()
+	delete(annotations, identityAnnotation)
+	delete(annotations, ignoredResourceIDAnnotation)
+
+	switch {
+	case src.Spec.ManagedIdentityClientID != "":
+		dst.Spec.Identity = &msiacrpullv1.Identity{
+			Type:     msiacrpullv1.IdentityTypeClientID,
+			ClientID: src.Spec.ManagedIdentityClientID,
+		}
+		if src.Spec.ManagedIdentityResourceID != "" {
+			annotations = setAnnotation(annotations, ignoredResourceIDAnnotation, src.Spec.ManagedIdentityResourceID)
+		}
+	case src.Spec.ManagedIdentityResourceID != "":
+		dst.Spec.Identity = &msiacrpullv1.Identity{
+			Type:       msiacrpullv1.IdentityTypeResourceID,
+			ResourceID: src.Spec.ManagedIdentityResourceID,
+		}
+	case src.Annotations[identityAnnotation] != "":
+		identity := &msiacrpullv1.Identity{}
+		if err := json.Unmarshal([]byte(src.Annotations[identityAnnotation]), identity); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", identityAnnotation, err)
+		}
+		dst.Spec.Identity = identity
+	default:
+		dst.Spec.Identity = nil
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	dst.SetAnnotations(annotations)
+
+	dst.Spec.AcrServer = src.Spec.AcrServer
+	dst.Spec.ServiceAccountName = src.Spec.ServiceAccountName
+	dst.Spec.SecretFormat = msiacrpullv1.SecretFormat(src.Spec.SecretFormat)
+	dst.Spec.SecretTemplate = (*msiacrpullv1.SecretTemplate)(src.Spec.SecretTemplate.DeepCopy())
+	dst.Spec.ExistingSecretName = src.Spec.ExistingSecretName
+	dst.Spec.HostAliases = append([]string(nil), src.Spec.HostAliases...)
+	dst.Spec.Mirror = (*msiacrpullv1.SecretMirror)(src.Spec.Mirror.DeepCopy())
+	dst.Spec.PullVerification = (*msiacrpullv1.PullVerification)(src.Spec.PullVerification.DeepCopy())
+
+	status := src.Status.DeepCopy()
+	dst.Status = msiacrpullv1.AcrPullBindingStatus{
+		LastTokenRefreshTime: status.LastTokenRefreshTime,
+		TokenExpirationTime:  status.TokenExpirationTime,
+		Error:                status.Error,
+		AcrServer:            status.AcrServer,
+		Identity:             status.Identity,
+		ServiceAccounts:      status.ServiceAccounts,
+		ExistingSecretName:   status.ExistingSecretName,
+		MirroredNamespaces:   status.MirroredNamespaces,
+		Conditions:           status.Conditions,
+	}
+	for _, bound := range status.BoundServiceAccounts {
+		dst.Status.BoundServiceAccounts = append(dst.Status.BoundServiceAccounts, msiacrpullv1.BoundServiceAccount(bound))
+	}
+	if status.Coverage != nil {
+		dst.Status.Coverage = &msiacrpullv1.RegistryCoverage{
+			CoveredWorkloads:    convertWorkloadsTo(status.Coverage.CoveredWorkloads),
+			UncoveredRegistries: status.Coverage.UncoveredRegistries,
+			UncoveredWorkloads:  convertWorkloadsTo(status.Coverage.UncoveredWorkloads),
+			Truncated:           status.Coverage.Truncated,
+			AuditTime:           status.Coverage.AuditTime,
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version to this version.
+func (dst *AcrPullBinding) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*msiacrpullv1.AcrPullBinding)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	annotations := dst.GetAnnotations()
+	delete(annotations, identityAnnotation)
+	delete(annotations, ignoredResourceIDAnnotation)
+
+	dst.Spec.ManagedIdentityClientID = ""
+	dst.Spec.ManagedIdentityResourceID = ""
+	if identity := src.Spec.Identity; identity != nil {
+		switch identity.Type {
+		case msiacrpullv1.IdentityTypeClientID:
+			dst.Spec.ManagedIdentityClientID = identity.ClientID
+			dst.Spec.ManagedIdentityResourceID = src.Annotations[ignoredResourceIDAnnotation]
+		case msiacrpullv1.IdentityTypeResourceID:
+			dst.Spec.ManagedIdentityResourceID = identity.ResourceID
+		default:
+			data, err := json.Marshal(identity)
+			if err != nil {
+				return err
+			}
+			annotations = setAnnotation(annotations, identityAnnotation, string(data))
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	dst.SetAnnotations(annotations)
+
+	dst.Spec.AcrServer = src.Spec.AcrServer
+	dst.Spec.ServiceAccountName = src.Spec.ServiceAccountName
+	dst.Spec.SecretFormat = SecretFormat(src.Spec.SecretFormat)
+	dst.Spec.SecretTemplate = (*SecretTemplate)(src.Spec.SecretTemplate.DeepCopy())
+	dst.Spec.ExistingSecretName = src.Spec.ExistingSecretName
+	dst.Spec.HostAliases = append([]string(nil), src.Spec.HostAliases...)
+	dst.Spec.Mirror = (*SecretMirror)(src.Spec.Mirror.DeepCopy())
+	dst.Spec.PullVerification = (*PullVerification)(src.Spec.PullVerification.DeepCopy())
+
+	status := src.Status.DeepCopy()
+	dst.Status = AcrPullBindingStatus{
+		LastTokenRefreshTime: status.LastTokenRefreshTime,
+		TokenExpirationTime:  status.TokenExpirationTime,
+		Error:                status.Error,
+		AcrServer:            status.AcrServer,
+		Identity:             status.Identity,
+		ServiceAccounts:      status.ServiceAccounts,
+		ExistingSecretName:   status.ExistingSecretName,
+		MirroredNamespaces:   status.MirroredNamespaces,
+		Conditions:           status.Conditions,
+	}
+	for _, bound := range status.BoundServiceAccounts {
+		dst.Status.BoundServiceAccounts = append(dst.Status.BoundServiceAccounts, BoundServiceAccount(bound))
+	}
+	if status.Coverage != nil {
+		dst.Status.Coverage = &RegistryCoverage{
+			CoveredWorkloads:    convertWorkloadsFrom(status.Coverage.CoveredWorkloads),
+			UncoveredRegistries: status.Coverage.UncoveredRegistries,
+			UncoveredWorkloads:  convertWorkloadsFrom(status.Coverage.UncoveredWorkloads),
+			Truncated:           status.Coverage.Truncated,
+			AuditTime:           status.Coverage.AuditTime,
+		}
+	}
+	return nil
+}
+
+func setAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
+
+func convertWorkloadsTo(workloads []WorkloadImages) []msiacrpullv1.WorkloadImages {
+	var converted []msiacrpullv1.WorkloadImages
+	for _, workload := range workloads {
+		converted = append(converted, msiacrpullv1.WorkloadImages(workload))
+	}
+	return converted
+}
+
+func convertWorkloadsFrom(workloads []msiacrpullv1.WorkloadImages) []WorkloadImages {
+	var converted []WorkloadImages
+	for _, workload := range workloads {
+		converted = append(converted, WorkloadImages(workload))
+	}
+	return converted
+}
@@ -0,0 +1,139 @@
+/*
+   MIT License
+
+   Copyright (c) Microsoft Corporation.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = Describe("AcrPullBinding conversion", func() {
+	roundTrip := func(src *AcrPullBinding) *AcrPullBinding {
+		hub := &msiacrpullv1.AcrPullBinding{}
+		Expect(src.ConvertTo(hub)).To(Succeed())
+		converted := &AcrPullBinding{}
+		Expect(converted.ConvertFrom(hub)).To(Succeed())
+		return converted
+	}
+
+	It("Should convert the managed identity client ID", func() {
+		src := &AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: AcrPullBindingSpec{
+				AcrServer:               "test.azurecr.io",
+				ManagedIdentityClientID: "client-id",
+			},
+		}
+		hub := &msiacrpullv1.AcrPullBinding{}
+		Expect(src.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.Identity).To(Equal(&msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "client-id"}))
+		Expect(hub.Annotations).To(BeEmpty())
+		Expect(roundTrip(src)).To(Equal(src))
+	})
+
+	It("Should keep the resource ID that is ignored for the client ID", func() {
+		src := &AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: AcrPullBindingSpec{
+				ManagedIdentityClientID:   "client-id",
+				ManagedIdentityResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id",
+			},
+		}
+		hub := &msiacrpullv1.AcrPullBinding{}
+		Expect(src.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.Identity.Type).To(Equal(msiacrpullv1.IdentityTypeClientID))
+		Expect(hub.Annotations).To(HaveKeyWithValue(ignoredResourceIDAnnotation, src.Spec.ManagedIdentityResourceID))
+		Expect(roundTrip(src)).To(Equal(src))
+	})
+
+	It("Should keep identities that v1beta1 can not express in an annotation", func() {
+		hub := &msiacrpullv1.AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: msiacrpullv1.AcrPullBindingSpec{
+				Identity: &msiacrpullv1.Identity{
+					Type: msiacrpullv1.IdentityTypeWorkload,
+					Workload: &msiacrpullv1.WorkloadIdentity{
+						ClientID: "00000000-0000-0000-0000-000000000001",
+						TenantID: "00000000-0000-0000-0000-000000000002",
+					},
+				},
+			},
+		}
+		spoke := &AcrPullBinding{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.ManagedIdentityClientID).To(BeEmpty())
+		Expect(spoke.Spec.ManagedIdentityResourceID).To(BeEmpty())
+		Expect(spoke.Annotations).To(HaveKey(identityAnnotation))
+
+		converted := &msiacrpullv1.AcrPullBinding{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted).To(Equal(hub))
+	})
+
+	It("Should drop the identity annotation when a managed identity is set", func() {
+		src := &AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "default",
+				Annotations: map[string]string{identityAnnotation: `{"type":"Workload"}`},
+			},
+			Spec: AcrPullBindingSpec{ManagedIdentityResourceID: "resource-id"},
+		}
+		hub := &msiacrpullv1.AcrPullBinding{}
+		Expect(src.ConvertTo(hub)).To(Succeed())
+		Expect(hub.Spec.Identity).To(Equal(&msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "resource-id"}))
+		Expect(hub.Annotations).To(BeNil())
+	})
+
+	It("Should convert the status", func() {
+		now := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+		src := &AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       AcrPullBindingSpec{ManagedIdentityResourceID: "resource-id"},
+			Status: AcrPullBindingStatus{
+				LastTokenRefreshTime: &now,
+				TokenExpirationTime:  &now,
+				AcrServer:            "test.azurecr.io",
+				Identity:             "resource-id",
+				ServiceAccounts:      "default",
+				BoundServiceAccounts: []BoundServiceAccount{{Name: "default", PullSecretName: "acr-pull-test"}},
+				ExistingSecretName:   "registry-credentials",
+				MirroredNamespaces:   []string{"team-a"},
+				Coverage: &RegistryCoverage{
+					CoveredWorkloads:    []WorkloadImages{{Kind: "Deployment", Name: "app", Images: []string{"test.azurecr.io/app"}}},
+					UncoveredRegistries: []string{"docker.io"},
+					AuditTime:           &now,
+				},
+				Conditions: []metav1.Condition{{Type: "PullVerified", Status: metav1.ConditionTrue, Reason: "Verified"}},
+			},
+		}
+		Expect(roundTrip(src)).To(Equal(src))
+	})
+})
@@ -28,9 +28,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
 // AcrPullBindingSpec defines the desired state of AcrPullBinding
 type AcrPullBindingSpec struct {
 	// +kubebuilder:validation:MinLength=0
@@ -50,13 +47,90 @@ type AcrPullBindingSpec struct {
 	// of the namespace will be used.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// The format of the image pull secret. DockerConfigJSON, the default, writes a kubernetes.io/dockerconfigjson
+	// secret with a username and password. DockerConfigJSONIdentityToken writes the ACR refresh token as an
+	// identitytoken instead, for clients that exchange it for access tokens themselves; the kubelet does not
+	// support it. DockerCfg writes a legacy kubernetes.io/dockercfg secret.
+	// +optional
+	SecretFormat SecretFormat `json:"secretFormat,omitempty"`
+
+	// The name, labels and annotations of the image pull secret created by the controller. The controller adds
+	// its own labels and annotations on top, describing the identity, registry and token expiry.
+	// +optional
+	SecretTemplate *SecretTemplate `json:"secretTemplate,omitempty"`
+
+	// The name of an existing image pull secret in the namespace, for example a shared secret that also holds
+	// credentials of other registries. When set, no secret is created; the controller only maintains the entries
+	// of the ACR server and its host aliases in the existing secret and removes them when the binding is deleted.
+	// The format of the credential follows the type of the existing secret.
+	// +optional
+	ExistingSecretName string `json:"existingSecretName,omitempty"`
+
+	// Additional host names of the registry to write the credential for, for example geo-replica or
+	// data endpoint host names.
+	// +optional
+	HostAliases []string `json:"hostAliases,omitempty"`
+
+	// Copy the image pull secret into other namespaces. The copies are owned by the controller, refreshed together
+	// with the secret and deleted when their namespace no longer matches.
+	// +optional
+	Mirror *SecretMirror `json:"mirror,omitempty"`
+
+	// Verify the pull credential against the registry after it is minted. The outcome is recorded in the
+	// PullVerified condition. Verification is skipped when this is not specified.
+	// +optional
+	PullVerification *PullVerification `json:"pullVerification,omitempty"`
+}
+
+// SecretTemplate describes the metadata of a generated image pull secret.
+type SecretTemplate struct {
+	// The name of the secret. Defaults to <binding name>-msi-acrpull-secret.
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name,omitempty"`
+
+	// Labels added to the secret.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the secret.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SecretMirror selects the namespaces an image pull secret is copied into.
+type SecretMirror struct {
+	// Namespaces whose labels match the selector receive a copy of the secret. Namespaces can be selected by name
+	// with the kubernetes.io/metadata.name label. The namespace of the binding is never a target.
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+}
+
+// SecretFormat is the format of an image pull secret.
+// +kubebuilder:validation:Enum=DockerConfigJSON;DockerConfigJSONIdentityToken;DockerCfg
+type SecretFormat string
+
+const (
+	// SecretFormatDockerConfigJSON is a kubernetes.io/dockerconfigjson secret with a username and password.
+	SecretFormatDockerConfigJSON SecretFormat = "DockerConfigJSON"
+	// SecretFormatDockerConfigJSONIdentityToken is a kubernetes.io/dockerconfigjson secret with an identitytoken.
+	SecretFormatDockerConfigJSONIdentityToken SecretFormat = "DockerConfigJSONIdentityToken"
+	// SecretFormatDockerCfg is a legacy kubernetes.io/dockercfg secret.
+	SecretFormatDockerCfg SecretFormat = "DockerCfg"
+)
+
+// PullVerification configures checking a newly minted pull credential against the registry.
+type PullVerification struct {
+	// An image in the registry whose manifest is requested to check pull access, for example
+	// team/probe:latest or test.azurecr.io/team/probe@sha256:... When empty, only the registry
+	// /v2/ endpoint is called, which checks that the registry accepts the credential.
+	// +optional
+	ProbeImage string `json:"probeImage,omitempty"`
 }
 
 // AcrPullBindingStatus defines the observed state of AcrPullBinding
 type AcrPullBindingStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-
 	// Information when was the last time the ACR token was refreshed.
 	// +optional
 	LastTokenRefreshTime *metav1.Time `json:"lastTokenRefreshTime,omitempty"`
@@ -68,10 +142,104 @@ type AcrPullBindingStatus struct {
 	// Error message if there was an error updating the token.
 	// +optional
 	Error string `json:"error,omitempty"`
+
+	// The ACR server the credential is issued for, after applying the defaults of the controller.
+	// +optional
+	AcrServer string `json:"acrServer,omitempty"`
+
+	// A short form of the identity behind the credential: the name of a managed identity given by
+	// resource ID, or its client ID.
+	// +optional
+	Identity string `json:"identity,omitempty"`
+
+	// The comma separated names of the service accounts the binding targets and is bound to.
+	// +optional
+	ServiceAccounts string `json:"serviceAccounts,omitempty"`
+
+	// The service accounts the controller added an image pull secret reference to. They are unbound when
+	// the binding targets another service account or secret, and when the binding is deleted.
+	// +optional
+	// +listType=atomic
+	BoundServiceAccounts []BoundServiceAccount `json:"boundServiceAccounts,omitempty"`
+
+	// The name of the existing secret the controller last wrote the credential into. The entries of the
+	// binding are removed from it when spec.existingSecretName changes or is cleared.
+	// +optional
+	ExistingSecretName string `json:"existingSecretName,omitempty"`
+
+	// The namespaces the image pull secret is currently copied into.
+	// +optional
+	MirroredNamespaces []string `json:"mirroredNamespaces,omitempty"`
+
+	// Coverage reports which workloads of the namespace pull from the registry of the binding and which
+	// registries no binding of the namespace covers. It is only set when the controller audits registry coverage.
+	// +optional
+	Coverage *RegistryCoverage `json:"coverage,omitempty"`
+
+	// Conditions describe the state of the pull credential.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BoundServiceAccount is a service account that references the image pull secret of a binding.
+type BoundServiceAccount struct {
+	// Name of the service account.
+	Name string `json:"name"`
+
+	// The name of the image pull secret the service account references.
+	PullSecretName string `json:"pullSecretName"`
+}
+
+// RegistryCoverage is the result of matching the image references of the workloads of a namespace against
+// the registries of its AcrPullBindings.
+type RegistryCoverage struct {
+	// The workloads with images served by the registry of this binding.
+	// +optional
+	CoveredWorkloads []WorkloadImages `json:"coveredWorkloads,omitempty"`
+
+	// The registries referenced by workloads of the namespace that no AcrPullBinding of the namespace covers.
+	// +optional
+	UncoveredRegistries []string `json:"uncoveredRegistries,omitempty"`
+
+	// The workloads with images from registries that no AcrPullBinding of the namespace covers.
+	// +optional
+	UncoveredWorkloads []WorkloadImages `json:"uncoveredWorkloads,omitempty"`
+
+	// Truncated is set when there were more than 50 entries in one of the lists.
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+
+	// The time the workloads were last inspected.
+	// +optional
+	AuditTime *metav1.Time `json:"auditTime,omitempty"`
+}
+
+// WorkloadImages lists image references of a workload.
+type WorkloadImages struct {
+	// Kind of the workload, for example Deployment or Pod.
+	Kind string `json:"kind"`
+
+	// Name of the workload.
+	Name string `json:"name"`
+
+	// The image references of the workload.
+	// +optional
+	Images []string `json:"images,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=apb,categories=acr
+//+kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.status.acrServer`
+//+kubebuilder:printcolumn:name="Identity",type=string,JSONPath=`.status.identity`
+//+kubebuilder:printcolumn:name="Service Accounts",type=string,JSONPath=`.status.serviceAccounts`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`,priority=1
+//+kubebuilder:printcolumn:name="Token Expiry",type=string,JSONPath=`.status.tokenExpirationTime`
+//+kubebuilder:printcolumn:name="Last Refresh",type=date,JSONPath=`.status.lastTokenRefreshTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // AcrPullBinding is the Schema for the acrpullbindings API
 type AcrPullBinding struct {
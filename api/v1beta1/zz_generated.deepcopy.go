@@ -29,6 +29,7 @@
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -37,7 +38,7 @@ func (in *AcrPullBinding) DeepCopyInto(out *AcrPullBinding) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -94,6 +95,26 @@ func (in *AcrPullBindingList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AcrPullBindingSpec) DeepCopyInto(out *AcrPullBindingSpec) {
 	*out = *in
+	if in.SecretTemplate != nil {
+		in, out := &in.SecretTemplate, &out.SecretTemplate
+		*out = new(SecretTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		*out = new(SecretMirror)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PullVerification != nil {
+		in, out := &in.PullVerification, &out.PullVerification
+		*out = new(PullVerification)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AcrPullBindingSpec.
@@ -117,6 +138,28 @@ func (in *AcrPullBindingStatus) DeepCopyInto(out *AcrPullBindingStatus) {
 		in, out := &in.TokenExpirationTime, &out.TokenExpirationTime
 		*out = (*in).DeepCopy()
 	}
+	if in.BoundServiceAccounts != nil {
+		in, out := &in.BoundServiceAccounts, &out.BoundServiceAccounts
+		*out = make([]BoundServiceAccount, len(*in))
+		copy(*out, *in)
+	}
+	if in.MirroredNamespaces != nil {
+		in, out := &in.MirroredNamespaces, &out.MirroredNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Coverage != nil {
+		in, out := &in.Coverage, &out.Coverage
+		*out = new(RegistryCoverage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AcrPullBindingStatus.
@@ -128,3 +171,136 @@ func (in *AcrPullBindingStatus) DeepCopy() *AcrPullBindingStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BoundServiceAccount) DeepCopyInto(out *BoundServiceAccount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BoundServiceAccount.
+func (in *BoundServiceAccount) DeepCopy() *BoundServiceAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(BoundServiceAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullVerification) DeepCopyInto(out *PullVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullVerification.
+func (in *PullVerification) DeepCopy() *PullVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(PullVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCoverage) DeepCopyInto(out *RegistryCoverage) {
+	*out = *in
+	if in.CoveredWorkloads != nil {
+		in, out := &in.CoveredWorkloads, &out.CoveredWorkloads
+		*out = make([]WorkloadImages, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UncoveredRegistries != nil {
+		in, out := &in.UncoveredRegistries, &out.UncoveredRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UncoveredWorkloads != nil {
+		in, out := &in.UncoveredWorkloads, &out.UncoveredWorkloads
+		*out = make([]WorkloadImages, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AuditTime != nil {
+		in, out := &in.AuditTime, &out.AuditTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCoverage.
+func (in *RegistryCoverage) DeepCopy() *RegistryCoverage {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCoverage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretMirror) DeepCopyInto(out *SecretMirror) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretMirror.
+func (in *SecretMirror) DeepCopy() *SecretMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretTemplate) DeepCopyInto(out *SecretTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretTemplate.
+func (in *SecretTemplate) DeepCopy() *SecretTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadImages) DeepCopyInto(out *WorkloadImages) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadImages.
+func (in *WorkloadImages) DeepCopy() *WorkloadImages {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadImages)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Azure/msi-acrpull/internal/config"
+	"github.com/Azure/msi-acrpull/internal/diagnose"
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+// runDiagnose implements the diagnose subcommand. It walks the token path of
+// a binding, or of the identity and registry given as flags, without writing
+// any secret.
+func runDiagnose(args []string) int {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	var binding string
+	var acrServer string
+	var clientID string
+	var resourceID string
+	var probeImage string
+	var configFile string
+	fs.StringVar(&binding, "binding", "",
+		"The AcrPullBinding to diagnose, as namespace/name. Identity and registry flags override its spec.")
+	fs.StringVar(&acrServer, "acr-server", "", "The ACR server to diagnose.")
+	fs.StringVar(&clientID, "managed-identity-client-id", "", "The client ID of the managed identity.")
+	fs.StringVar(&resourceID, "managed-identity-resource-id", "", "The resource ID of the managed identity.")
+	fs.StringVar(&probeImage, "probe-image", "",
+		"An image in the registry to check pull access to, as the pull verification of a binding does.")
+	fs.StringVar(&configFile, "config", "",
+		"The path to a ControllerConfiguration file providing defaults, cloud and rate limit settings.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s diagnose [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load controller configuration: %v\n", err)
+		return 1
+	}
+
+	var spec msiacrpullv1.AcrPullBindingSpec
+	if binding != "" {
+		spec, err = getBindingSpec(binding)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to get AcrPullBinding %s: %v\n", binding, err)
+			return 1
+		}
+	}
+	if acrServer != "" {
+		spec.AcrServer = acrServer
+	}
+	if probeImage != "" {
+		spec.PullVerification = &msiacrpullv1.PullVerification{ProbeImage: probeImage}
+	}
+	switch {
+	case clientID != "":
+		spec.Identity = &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: clientID}
+	case resourceID != "":
+		spec.Identity = &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: resourceID}
+	}
+
+	// same defaults as the controller: the identity of the binding replaces the configured one
+	target := diagnose.Target{
+		ACRServer: spec.AcrServer,
+		TenantID:  cfg.Cloud.TenantID,
+	}
+	if spec.PullVerification != nil {
+		target.ProbeImage = spec.PullVerification.ProbeImage
+	}
+	if target.ACRServer == "" {
+		target.ACRServer = cfg.Defaults.ACRServer
+	}
+	switch {
+	case spec.Identity == nil:
+		target.ManagedIdentityClientID = cfg.Defaults.ManagedIdentityClientID
+		target.ManagedIdentityResourceID = cfg.Defaults.ManagedIdentityResourceID
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeClientID:
+		target.ManagedIdentityClientID = spec.Identity.ClientID
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeResourceID:
+		target.ManagedIdentityResourceID = path.Clean(spec.Identity.ResourceID)
+	default:
+		fmt.Fprintf(os.Stderr, "identity type %s can not be diagnosed, only managed identities are supported\n", spec.Identity.Type)
+		return 1
+	}
+	if target.ACRServer == "" {
+		fmt.Fprintln(os.Stderr, "no ACR server given, use --binding, --acr-server or a default in --config")
+		return 1
+	}
+	if target.ManagedIdentityClientID == "" && target.ManagedIdentityResourceID == "" {
+		fmt.Fprintln(os.Stderr, "no managed identity given, use --binding, --managed-identity-client-id, "+
+			"--managed-identity-resource-id or a default in --config")
+		return 1
+	}
+
+	d := diagnose.NewDiagnoser(authorizer.Options{
+		MetadataEndpoint: cfg.Cloud.MetadataEndpoint,
+		ARMResource:      cfg.Cloud.ARMResource,
+		CacheExpiration:  cfg.Refresh.ARMTokenCacheDuration.Duration,
+		RPS:              cfg.RateLimit.QPS,
+		Burst:            cfg.RateLimit.Burst,
+	}, os.Stdout)
+	if err := d.Run(context.Background(), target); err != nil {
+		fmt.Fprintf(os.Stderr, "diagnosis failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func getBindingSpec(binding string) (msiacrpullv1.AcrPullBindingSpec, error) {
+	namespace, name, found := strings.Cut(binding, "/")
+	if !found || namespace == "" || name == "" {
+		return msiacrpullv1.AcrPullBindingSpec{}, fmt.Errorf("expected namespace/name")
+	}
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return msiacrpullv1.AcrPullBindingSpec{}, err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return msiacrpullv1.AcrPullBindingSpec{}, err
+	}
+
+	var acrBinding msiacrpullv1.AcrPullBinding
+	if err := c.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: name}, &acrBinding); err != nil {
+		return msiacrpullv1.AcrPullBindingSpec{}, err
+	}
+	return acrBinding.Spec, nil
+}
@@ -1,31 +1,44 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	"github.com/Azure/msi-acrpull/internal/audit"
+	"github.com/Azure/msi-acrpull/internal/config"
 	"github.com/Azure/msi-acrpull/internal/controller"
+	"github.com/Azure/msi-acrpull/internal/health"
+	"github.com/Azure/msi-acrpull/internal/tracing"
+	podwebhook "github.com/Azure/msi-acrpull/internal/webhook"
 	"github.com/Azure/msi-acrpull/pkg/authorizer"
+	"github.com/Azure/msi-acrpull/pkg/redact"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
 	msiacrpullv1beta1 "github.com/Azure/msi-acrpull/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
-const (
-	defaultACRServerEnvKey                 = "ACR_SERVER"
-	defaultManagedIdentityResourceIDEnvKey = "MANAGED_IDENTITY_RESOURCE_ID"
-	defaultManagedIdentityClientIDEnvKey   = "MANAGED_IDENTITY_CLIENT_ID"
-)
-
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -33,62 +46,232 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(msiacrpullv1.AddToScheme(scheme))
 	utilruntime.Must(msiacrpullv1beta1.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diagnose" {
+		os.Exit(runDiagnose(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var configFile string
+	var watchNamespaces string
+	var namespaceSelector string
+	var shardCount int
+	var shardIndex int
+	var enablePodWebhook bool
+	var podWebhookStrict bool
+	var auditRegistryCoverage bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&configFile, "config", "",
+		"The path to a ControllerConfiguration file. Defaults, rate limits and refresh settings are reloaded when it changes.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma separated list of namespaces to watch. Overrides namespaces.include of the configuration file. "+
+			"All namespaces are watched when empty.")
+	flag.StringVar(&namespaceSelector, "namespace-selector", "",
+		"Label selector of the namespaces to watch, resolved at startup. Can not be combined with --watch-namespaces.")
+	flag.IntVar(&shardCount, "shard-count", 1,
+		"The number of controller instances splitting the watched namespaces between them.")
+	flag.IntVar(&shardIndex, "shard-index", 0,
+		"The shard of namespaces this instance reconciles, from 0 to shard-count - 1.")
+	flag.BoolVar(&enablePodWebhook, "enable-pod-webhook", false,
+		"Serve a mutating webhook that adds the pull secrets of AcrPullBindings to the imagePullSecrets of pods.")
+	flag.BoolVar(&podWebhookStrict, "pod-webhook-strict", false,
+		"Deny pods pulling from a registry whose AcrPullBindings are all not ready. Requires --enable-pod-webhook.")
+	flag.BoolVar(&auditRegistryCoverage, "audit-registry-coverage", false,
+		"Report in the status of AcrPullBindings which workloads of their namespace pull from their registry "+
+			"and which registries no binding covers.")
+	// JSON at info level; --zap-devel switches to the console encoder and --zap-log-level=debug
+	// enables the messages logged by every reconcile
 	opts := zap.Options{
-		Development: true,
+		Development: false,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
-	defaultACRServer := os.Getenv(defaultACRServerEnvKey)
-	defaultManagedIdentityResourceID := os.Getenv(defaultManagedIdentityResourceIDEnvKey)
-	defaultManagedIdentityClientID := os.Getenv(defaultManagedIdentityClientIDEnvKey)
-
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "aks.azure.com",
+
+	// registry and identity endpoints echo tokens back in error responses
+	ctrl.SetLogger(redact.Logger(zap.New(zap.UseFlagOptions(&opts))))
+
+	fileConfig, err := config.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load controller configuration")
+		os.Exit(1)
+	}
+
+	if shardCount < 1 || shardIndex < 0 || shardIndex >= shardCount {
+		setupLog.Error(fmt.Errorf("shard index %d is not within [0, %d)", shardIndex, shardCount), "invalid sharding flags")
+		os.Exit(1)
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	controllerConfig := fileConfig.DeepCopy()
+	if watchNamespaces != "" {
+		controllerConfig.Namespaces.Include = strings.Split(watchNamespaces, ",")
+	}
+	controllerConfig.Namespaces.Include, err = resolveNamespaces(restConfig, controllerConfig, namespaceSelector)
+	if err != nil {
+		setupLog.Error(err, "unable to determine the namespaces to watch")
+		os.Exit(1)
+	}
+	if len(controllerConfig.Namespaces.Include) > 0 {
+		setupLog.Info("restricting the controller to namespaces", "namespaces", controllerConfig.Namespaces.Include)
+		if controllerConfig.Mirroring.Enabled() {
+			setupLog.Error(fmt.Errorf("mirroring needs the cluster-wide role"), "mirroring can not be combined with a namespace restriction")
+			os.Exit(1)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), controllerConfig.Tracing, os.Stdout)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	if controllerConfig.Tracing.Exporter != configv1alpha1.TracingExporterNone {
+		setupLog.Info("exporting traces", "exporter", controllerConfig.Tracing.Exporter, "samplingRatio", *controllerConfig.Tracing.SamplingRatio)
+	}
+
+	auditSink, err := audit.NewSink(controllerConfig.Audit, os.Stdout)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the audit log")
+		os.Exit(1)
+	}
+	if auditSink != nil {
+		setupLog.Info("auditing issued credentials", "sink", controllerConfig.Audit.Sink)
+	}
+
+	leaderElection := controllerConfig.LeaderElection
+	leaderElectionID := leaderElection.ID
+	if shardCount > 1 {
+		// every shard elects its own leader
+		leaderElectionID = fmt.Sprintf("%s-shard-%d-of-%d", leaderElectionID, shardIndex, shardCount)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElection.Namespace,
+		LeaseDuration:           &leaderElection.LeaseDuration.Duration,
+		RenewDeadline:           &leaderElection.RenewDeadline.Duration,
+		RetryPeriod:             &leaderElection.RetryPeriod.Duration,
+		// hand the lease over on shutdown rather than pausing rotation for a lease duration; the
+		// process exits right after the manager stops
+		LeaderElectionReleaseOnCancel: true,
+		Cache: cache.Options{
+			DefaultNamespaces: config.CacheNamespaces(controllerConfig.Namespaces.Include),
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	apbReconciler := &controller.AcrPullBindingReconciler{
-		Client:                           mgr.GetClient(),
-		Log:                              ctrl.Log.WithName("controllers").WithName("AcrPullBinding"),
-		Scheme:                           mgr.GetScheme(),
-		Auth:                             authorizer.NewAuthorizer(),
-		DefaultACRServer:                 defaultACRServer,
-		DefaultManagedIdentityResourceID: defaultManagedIdentityResourceID,
-		DefaultManagedIdentityClientID:   defaultManagedIdentityClientID,
+	auth := authorizer.NewAuthorizerWithOptions(authorizer.Options{
+		MetadataEndpoint:        controllerConfig.Cloud.MetadataEndpoint,
+		ARMResource:             controllerConfig.Cloud.ARMResource,
+		ActiveDirectoryEndpoint: controllerConfig.Cloud.ActiveDirectoryEndpoint,
+		CacheExpiration:         controllerConfig.Refresh.ARMTokenCacheDuration.Duration,
+		RPS:                     controllerConfig.RateLimit.QPS,
+		Burst:                   controllerConfig.RateLimit.Burst,
+	})
+	apbReconciler := controller.NewAcrPullBindingReconciler(
+		mgr.GetClient(),
+		ctrl.Log.WithName("controllers").WithName("AcrPullBinding"),
+		mgr.GetScheme(),
+		auth,
+		controllerConfig,
+	)
+	apbReconciler.ShardCount = shardCount
+	apbReconciler.ShardIndex = shardIndex
+	apbReconciler.Audit = auditSink
+	apbReconciler.Recorder = mgr.GetEventRecorderFor("acrpullbinding-controller")
+	expiryWatchdog := controller.NewExpiryWatchdog(apbReconciler, ctrl.Log.WithName("expiry-watchdog"), controllerConfig.ExpiryWatchdog)
+	if controllerConfig.ExpiryWatchdog.ReconcileExpiring {
+		expiryEvents := make(chan event.GenericEvent, 1024)
+		expiryWatchdog.Enqueue = expiryEvents
+		apbReconciler.ExpiryEvents = expiryEvents
+	}
+	if auditRegistryCoverage {
+		// workloads are listed on every token refresh rather than watched
+		apbReconciler.CoverageReader = mgr.GetAPIReader()
 	}
 	if err = apbReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AcrPullBinding")
 		os.Exit(1)
 	}
+	// v1beta1 bindings are converted to v1 by the webhook server of the manager
+	if err = (&msiacrpullv1.AcrPullBinding{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "AcrPullBinding")
+		os.Exit(1)
+	}
+	if err := mgr.Add(controller.NewStorageVersionMigrator(
+		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		controllerConfig.Namespaces.Include,
+		ctrl.Log.WithName("storage-version"),
+	)); err != nil {
+		setupLog.Error(err, "unable to set up storage version migration")
+		os.Exit(1)
+	}
+	if err := mgr.Add(expiryWatchdog); err != nil {
+		setupLog.Error(err, "unable to set up the credential expiry watchdog")
+		os.Exit(1)
+	}
+	var podInjector *podwebhook.PodImagePullSecretInjector
+	if enablePodWebhook {
+		podInjector = podwebhook.NewPodImagePullSecretInjector(
+			mgr.GetClient(),
+			admission.NewDecoder(mgr.GetScheme()),
+			ctrl.Log.WithName("webhooks").WithName("Pod"),
+			podWebhookStrict,
+			controllerConfig,
+		)
+		mgr.GetWebhookServer().Register(podwebhook.PodWebhookPath, &webhook.Admission{Handler: podInjector})
+	}
 	//+kubebuilder:scaffold:builder
 
+	if configFile != "" {
+		watcher := config.NewWatcher(configFile, fileConfig, func(cfg *configv1alpha1.ControllerConfiguration) {
+			apbReconciler.ApplyConfiguration(cfg)
+			if podInjector != nil {
+				podInjector.ApplyConfiguration(cfg)
+			}
+			auth.SetRateLimit(cfg.RateLimit.QPS, cfg.RateLimit.Burst)
+		}, ctrl.Log.WithName("config"))
+		if err := mgr.Add(watcher); err != nil {
+			setupLog.Error(err, "unable to set up configuration watcher")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	// only the leader reconciles, standby replicas are never stuck
+	if err := mgr.AddHealthzCheck("reconcile-loop", health.Elected(mgr.Elected(),
+		apbReconciler.StuckReconcileCheck(controllerConfig.Health.StuckReconcileWindow.Duration))); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("cache-sync", health.CacheSync(mgr.GetCache(), time.Second)); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("metadata-endpoint",
+		health.MetadataEndpoint(auth, controllerConfig.Health.MetadataEndpointTimeout.Duration)); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
@@ -98,4 +281,30 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// flush the spans of the last reconciles
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+	if auditSink != nil {
+		if err := auditSink.Close(); err != nil {
+			setupLog.Error(err, "unable to close the audit log")
+		}
+	}
+}
+
+// resolveNamespaces looks up the namespaces to watch before the manager, and
+// with it the namespace restricted cache, is created.
+func resolveNamespaces(restConfig *rest.Config, cfg *configv1alpha1.ControllerConfiguration, selector string) ([]string, error) {
+	if selector == "" && len(cfg.Namespaces.Include) == 0 {
+		return nil, nil
+	}
+
+	reader, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	return config.ResolveNamespaces(context.Background(), reader, cfg.Namespaces.Include, selector, cfg.Namespaces.Exclude)
 }
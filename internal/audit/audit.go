@@ -0,0 +1,191 @@
+// Package audit records the registry credentials the controller issues, rotates and revokes, so
+// that it can be shown which identity minted which credential for which service account and when.
+// Records describe a credential by its claims and never contain the token itself.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// Action is what happened to a credential.
+type Action string
+
+const (
+	// ActionIssue is the first credential written for a binding.
+	ActionIssue Action = "Issue"
+	// ActionRotate is a credential replacing the previous one of a binding.
+	ActionRotate Action = "Rotate"
+	// ActionRevoke is the removal of the credential of a deleted binding.
+	ActionRevoke Action = "Revoke"
+)
+
+// Binding identifies the AcrPullBinding a credential was written for.
+type Binding struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// Record is a single audit record.
+type Record struct {
+	// Time is when the record was made.
+	Time time.Time `json:"time"`
+	// Action is what happened to the credential.
+	Action Action `json:"action"`
+	// CorrelationID is the ID of the reconcile that made the record, which is also logged as
+	// reconcileID and stamped on the events of the binding.
+	CorrelationID string `json:"correlationID,omitempty"`
+	// TokenID is the jti claim of the credential. It can be empty for a revoked credential whose ID
+	// was not recorded.
+	TokenID string `json:"jti,omitempty"`
+	// Identity is the client ID or resource ID of the managed identity the credential was minted for.
+	Identity string `json:"identity"`
+	// TenantID is the tenant the credential was issued for.
+	TenantID string `json:"tenantID,omitempty"`
+	// Registry is the login server the credential is for.
+	Registry string `json:"registry"`
+	// Scopes are the repository actions granted by the credential, in the type:name:actions format
+	// of registry scopes. Refresh tokens carry no scopes.
+	Scopes []string `json:"scopes,omitempty"`
+	// ExpiresAt is the expiration time of the credential.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Binding is the AcrPullBinding the credential was written for.
+	Binding Binding `json:"binding"`
+	// ServiceAccount is the service account referencing the pull secret.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Secret is the name of the pull secret holding the credential.
+	Secret string `json:"secret,omitempty"`
+}
+
+// SetClaims fills the token ID, tenant, scopes and expiry of the record from the claims of an ACR token.
+func (r *Record) SetClaims(claims *types.ACRClaims) {
+	r.TokenID = claims.ID
+	r.TenantID = claims.TenantID
+	r.Scopes = nil
+	for _, access := range claims.Access {
+		actions := append([]string(nil), access.Actions...)
+		sort.Strings(actions)
+		r.Scopes = append(r.Scopes, fmt.Sprintf("%s:%s:%s", access.Type, access.Name, strings.Join(actions, ",")))
+	}
+	r.ExpiresAt = nil
+	if claims.ExpiresAt != nil {
+		exp := claims.ExpiresAt.Time.UTC()
+		r.ExpiresAt = &exp
+	}
+}
+
+// Sink writes audit records.
+type Sink interface {
+	// Write writes a record. It is safe for concurrent use.
+	Write(ctx context.Context, record *Record) error
+	// Close flushes and releases the sink.
+	Close() error
+}
+
+// NewSink returns the sink cfg selects, or nil when auditing is disabled. Stdout records are
+// written to out.
+func NewSink(cfg configv1alpha1.AuditConfiguration, out io.Writer) (Sink, error) {
+	switch cfg.Sink {
+	case "", configv1alpha1.AuditSinkNone:
+		return nil, nil
+	case configv1alpha1.AuditSinkStdout:
+		return NewWriterSink(out), nil
+	case configv1alpha1.AuditSinkFile:
+		file, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		return &writerSink{out: file, closer: file}, nil
+	case configv1alpha1.AuditSinkWebhook:
+		return NewWebhookSink(cfg.WebhookURL, &http.Client{Timeout: cfg.WebhookTimeout.Duration}), nil
+	default:
+		return nil, fmt.Errorf("unsupported audit sink %q", cfg.Sink)
+	}
+}
+
+// writerSink writes records as JSON lines.
+type writerSink struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewWriterSink returns a sink writing records to out as JSON lines.
+func NewWriterSink(out io.Writer) Sink {
+	return &writerSink{out: out}
+}
+
+func (s *writerSink) Write(_ context.Context, record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.out.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+func (s *writerSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// webhookSink posts every record to a URL.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting every record as JSON to url with client.
+func NewWebhookSink(url string, client *http.Client) Sink {
+	return &webhookSink{url: url, client: client}
+}
+
+func (s *webhookSink) Write(ctx context.Context, record *Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create audit webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit record: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
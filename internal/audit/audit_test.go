@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+var _ = Describe("Audit Tests", func() {
+	exp := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newToken := func() types.AccessToken {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"jti":        "6b5c0f8e-2f6a-4c43-9d0e-0c7c3f3c1b55",
+			"tenant":     "1b4e67bf-39b2-4eb1-bec3-5099dd556b07",
+			"exp":        exp.Unix(),
+			"grant_type": "access_token",
+			"access": []map[string]interface{}{
+				{"type": "repository", "name": "my-app", "actions": []string{"pull", "metadata_read"}},
+			},
+		})
+		signed, err := token.SignedString([]byte("secret"))
+		Expect(err).ToNot(HaveOccurred())
+		return types.AccessToken(signed)
+	}
+
+	newRecord := func(token types.AccessToken) *Record {
+		record := &Record{
+			Time:           time.Date(2029, 12, 31, 0, 0, 0, 0, time.UTC),
+			Action:         ActionIssue,
+			Identity:       "client-id",
+			Registry:       "test.azurecr.io",
+			Binding:        Binding{Namespace: "default", Name: "test", UID: "uid"},
+			ServiceAccount: "default",
+			Secret:         "test-msi-acrpull-secret",
+		}
+		claims, err := token.GetACRClaims()
+		Expect(err).ToNot(HaveOccurred())
+		record.SetClaims(claims)
+		return record
+	}
+
+	It("Describes a credential by its claims", func() {
+		record := newRecord(newToken())
+		Expect(record.TokenID).To(Equal("6b5c0f8e-2f6a-4c43-9d0e-0c7c3f3c1b55"))
+		Expect(record.TenantID).To(Equal("1b4e67bf-39b2-4eb1-bec3-5099dd556b07"))
+		Expect(record.Scopes).To(ConsistOf("repository:my-app:metadata_read,pull"))
+		Expect(*record.ExpiresAt).To(Equal(exp))
+	})
+
+	It("Writes JSON lines without the token", func() {
+		token := newToken()
+		var out bytes.Buffer
+		sink := NewWriterSink(&out)
+		Expect(sink.Write(context.Background(), newRecord(token))).To(Succeed())
+		Expect(sink.Write(context.Background(), newRecord(token))).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+
+		Expect(out.String()).ToNot(ContainSubstring(string(token)))
+		scanner := bufio.NewScanner(&out)
+		lines := 0
+		for scanner.Scan() {
+			var decoded map[string]interface{}
+			Expect(json.Unmarshal(scanner.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded).To(HaveKeyWithValue("jti", "6b5c0f8e-2f6a-4c43-9d0e-0c7c3f3c1b55"))
+			Expect(decoded).To(HaveKeyWithValue("action", "Issue"))
+			Expect(decoded).To(HaveKeyWithValue("expiresAt", "2030-01-02T03:04:05Z"))
+			Expect(decoded).To(HaveKeyWithValue("binding", HaveKeyWithValue("uid", "uid")))
+			lines++
+		}
+		Expect(lines).To(Equal(2))
+	})
+
+	It("Appends to the audit file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "audit.log")
+		Expect(os.WriteFile(path, []byte("{}\n"), 0600)).To(Succeed())
+
+		sink, err := NewSink(configv1alpha1.AuditConfiguration{Sink: configv1alpha1.AuditSinkFile, Path: path}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.Write(context.Background(), newRecord(newToken()))).To(Succeed())
+		Expect(sink.Close()).To(Succeed())
+
+		content, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bytes.Split(bytes.TrimSpace(content), []byte("\n"))).To(HaveLen(2))
+	})
+
+	It("Posts records to the webhook", func() {
+		var received Record
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			body, err := io.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(body, &received)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		sink, err := NewSink(configv1alpha1.AuditConfiguration{
+			Sink:       configv1alpha1.AuditSinkWebhook,
+			WebhookURL: server.URL,
+		}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink.Write(context.Background(), newRecord(newToken()))).To(Succeed())
+		Expect(received.TokenID).To(Equal("6b5c0f8e-2f6a-4c43-9d0e-0c7c3f3c1b55"))
+		Expect(received.Binding.Name).To(Equal("test"))
+	})
+
+	It("Fails when the webhook rejects a record", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		sink := NewWebhookSink(server.URL, server.Client())
+		err := sink.Write(context.Background(), newRecord(newToken()))
+		Expect(err).To(MatchError(ContainSubstring("status 500")))
+	})
+
+	It("Returns no sink when auditing is disabled", func() {
+		sink, err := NewSink(configv1alpha1.AuditConfiguration{Sink: configv1alpha1.AuditSinkNone}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sink).To(BeNil())
+	})
+})
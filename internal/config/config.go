@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+const (
+	acrServerEnvKey                 = "ACR_SERVER"
+	managedIdentityResourceIDEnvKey = "MANAGED_IDENTITY_RESOURCE_ID"
+	managedIdentityClientIDEnvKey   = "MANAGED_IDENTITY_CLIENT_ID"
+	armResourceEnvKey               = "ARM_RESOURCE"
+)
+
+// Load reads the configuration file at path, then defaults and validates it.
+// When path is empty, the defaults are returned. In both cases the legacy
+// environment variables fill in any value the file leaves unset.
+func Load(path string) (*configv1alpha1.ControllerConfiguration, error) {
+	if path == "" {
+		return complete(&configv1alpha1.ControllerConfiguration{})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read controller configuration: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid controller configuration %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes, defaults and validates a configuration file. Unknown fields are rejected.
+func Parse(data []byte) (*configv1alpha1.ControllerConfiguration, error) {
+	cfg := &configv1alpha1.ControllerConfiguration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode: %w", err)
+	}
+
+	if cfg.APIVersion != configv1alpha1.GroupVersion.String() || cfg.Kind != configv1alpha1.ControllerConfigurationKind {
+		return nil, fmt.Errorf("unsupported configuration %s, Kind=%s: expected %s, Kind=%s",
+			cfg.APIVersion, cfg.Kind, configv1alpha1.GroupVersion.String(), configv1alpha1.ControllerConfigurationKind)
+	}
+
+	return complete(cfg)
+}
+
+func complete(cfg *configv1alpha1.ControllerConfiguration) (*configv1alpha1.ControllerConfiguration, error) {
+	applyEnvironment(cfg)
+	configv1alpha1.SetDefaults(cfg)
+	if err := Validate(cfg).ToAggregate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnvironment keeps deployments that configure the controller through
+// environment variables working.
+func applyEnvironment(cfg *configv1alpha1.ControllerConfiguration) {
+	setFromEnv(&cfg.Defaults.ACRServer, acrServerEnvKey)
+	setFromEnv(&cfg.Defaults.ManagedIdentityResourceID, managedIdentityResourceIDEnvKey)
+	setFromEnv(&cfg.Defaults.ManagedIdentityClientID, managedIdentityClientIDEnvKey)
+	setFromEnv(&cfg.Cloud.ARMResource, armResourceEnvKey)
+}
+
+func setFromEnv(field *string, key string) {
+	if *field == "" {
+		*field = os.Getenv(key)
+	}
+}
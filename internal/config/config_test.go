@@ -0,0 +1,312 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+const testConfig = `apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  acrServer: test.azurecr.io
+  managedIdentityClientID: a24051cb-67a7-4aa9-8abe-0765312b658a
+cloud:
+  name: AzureUSGovernmentCloud
+rateLimit:
+  qps: 10
+  burst: 20
+refresh:
+  tokenRefreshBuffer: 1h
+concurrency:
+  maxConcurrentReconciles: 4
+namespaces:
+  exclude:
+  - kube-system
+`
+
+var _ = Describe("Config Tests", func() {
+	Context("Parse", func() {
+		It("Decodes and defaults a valid configuration", func() {
+			cfg, err := Parse([]byte(testConfig))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cfg.Defaults.ACRServer).To(Equal("test.azurecr.io"))
+			Expect(cfg.Cloud.ARMResource).To(Equal("https://management.usgovcloudapi.net/"))
+			Expect(cfg.Cloud.MetadataEndpoint).To(Equal(configv1alpha1.DefaultMetadataEndpoint))
+			Expect(cfg.Cloud.ActiveDirectoryEndpoint).To(Equal("https://login.microsoftonline.us/"))
+			Expect(cfg.Cloud.TokenExchangeAudience).To(Equal("api://AzureADTokenExchangeUSGov"))
+			Expect(cfg.RateLimit.QPS).To(Equal(float64(10)))
+			Expect(cfg.RateLimit.Burst).To(Equal(20))
+			Expect(cfg.Refresh.TokenRefreshBuffer.Duration).To(Equal(time.Hour))
+			Expect(cfg.Refresh.ARMTokenCacheDuration.Duration).To(Equal(configv1alpha1.DefaultARMTokenCacheDuration))
+			Expect(cfg.Concurrency.MaxConcurrentReconciles).To(Equal(4))
+			Expect(cfg.Namespaces.Exclude).To(ConsistOf("kube-system"))
+			Expect(cfg.Tracing.Exporter).To(Equal(configv1alpha1.TracingExporterNone))
+			Expect(cfg.Tracing.SamplingRatio).To(HaveValue(Equal(configv1alpha1.DefaultTracingSamplingRatio)))
+			Expect(cfg.Audit.Sink).To(Equal(configv1alpha1.AuditSinkNone))
+			Expect(cfg.Audit.WebhookTimeout.Duration).To(Equal(configv1alpha1.DefaultAuditWebhookTimeout))
+			Expect(cfg.ExpiryWatchdog.Interval.Duration).To(Equal(configv1alpha1.DefaultExpiryWatchdogInterval))
+			Expect(cfg.ExpiryWatchdog.ExpiringThreshold.Duration).To(Equal(configv1alpha1.DefaultExpiringThreshold))
+			Expect(cfg.Health.MetadataEndpointTimeout.Duration).To(Equal(configv1alpha1.DefaultMetadataEndpointTimeout))
+			Expect(cfg.Health.StuckReconcileWindow.Duration).To(Equal(configv1alpha1.DefaultStuckReconcileWindow))
+			Expect(cfg.LeaderElection.ID).To(Equal("msi-acrpull.aks.azure.com"))
+			Expect(cfg.LeaderElection.Namespace).To(BeEmpty())
+			Expect(cfg.LeaderElection.LeaseDuration.Duration).To(Equal(configv1alpha1.DefaultLeaseDuration))
+			Expect(cfg.LeaderElection.RenewDeadline.Duration).To(Equal(configv1alpha1.DefaultRenewDeadline))
+			Expect(cfg.LeaderElection.RetryPeriod.Duration).To(Equal(configv1alpha1.DefaultRetryPeriod))
+		})
+
+		It("Keeps a sampling ratio of 0", func() {
+			cfg, err := Parse([]byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+tracing:
+  exporter: Stdout
+  samplingRatio: 0
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Tracing.SamplingRatio).To(HaveValue(BeZero()))
+		})
+
+		It("Rejects unknown fields", func() {
+			_, err := Parse([]byte(testConfig + "unknown: true\n"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown"))
+		})
+
+		It("Rejects an unexpected kind", func() {
+			_, err := Parse([]byte("apiVersion: v1\nkind: ConfigMap\n"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported configuration"))
+		})
+
+		It("Reports every invalid field", func() {
+			_, err := Parse([]byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+cloud:
+  name: AzureMoonCloud
+rateLimit:
+  qps: -1
+namespaces:
+  include:
+  - Not_A_Namespace
+mirroring:
+  sourceNamespaces:
+  - team-a
+  targetNamespaces:
+  - Team_B
+tracing:
+  exporter: Zipkin
+  samplingRatio: 2
+audit:
+  sink: File
+expiryWatchdog:
+  expiringThreshold: 2h
+health:
+  stuckReconcileWindow: -1m
+`))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cloud.name"))
+			Expect(err.Error()).To(ContainSubstring("rateLimit.qps"))
+			Expect(err.Error()).To(ContainSubstring("namespaces.include[0]"))
+			Expect(err.Error()).To(ContainSubstring("mirroring.targetNamespaces[0]"))
+			Expect(err.Error()).To(ContainSubstring("can not be combined with namespaces.include"))
+			Expect(err.Error()).To(ContainSubstring("tracing.exporter"))
+			Expect(err.Error()).To(ContainSubstring("tracing.samplingRatio"))
+			Expect(err.Error()).To(ContainSubstring("audit.path"))
+			Expect(err.Error()).To(ContainSubstring("expiryWatchdog.expiringThreshold"))
+			Expect(err.Error()).To(ContainSubstring("health.stuckReconcileWindow"))
+		})
+
+		It("Requires an absolute URL for the audit webhook", func() {
+			_, err := Parse([]byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+audit:
+  sink: Webhook
+  webhookURL: /audit
+`))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("audit.webhookURL"))
+		})
+
+		It("Checks the leader election lease and timing", func() {
+			cfg, err := Parse([]byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  id: msi-acrpull.team-a
+  namespace: team-a
+  leaseDuration: 60s
+  renewDeadline: 40s
+  retryPeriod: 5s
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.LeaderElection.ID).To(Equal("msi-acrpull.team-a"))
+			Expect(cfg.LeaderElection.Namespace).To(Equal("team-a"))
+			Expect(cfg.LeaderElection.LeaseDuration.Duration).To(Equal(time.Minute))
+
+			_, err = Parse([]byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+leaderElection:
+  id: Not_A_Lease
+  namespace: Team_A
+  leaseDuration: 10s
+  renewDeadline: 10s
+  retryPeriod: 9s
+`))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("leaderElection.id"))
+			Expect(err.Error()).To(ContainSubstring("leaderElection.namespace"))
+			Expect(err.Error()).To(ContainSubstring("leaderElection.leaseDuration"))
+			Expect(err.Error()).To(ContainSubstring("leaderElection.renewDeadline"))
+		})
+	})
+
+	Context("Load", func() {
+		It("Returns defaults and legacy environment variables without a file", func() {
+			os.Setenv(acrServerEnvKey, "env.azurecr.io")
+			defer os.Unsetenv(acrServerEnvKey)
+
+			cfg, err := Load("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Defaults.ACRServer).To(Equal("env.azurecr.io"))
+			Expect(cfg.Cloud.ARMResource).To(Equal("https://management.azure.com/"))
+			Expect(cfg.RateLimit.QPS).To(Equal(float64(configv1alpha1.DefaultQPS)))
+		})
+
+		It("Prefers the file over legacy environment variables", func() {
+			os.Setenv(acrServerEnvKey, "env.azurecr.io")
+			defer os.Unsetenv(acrServerEnvKey)
+
+			path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(path, []byte(testConfig), 0600)).To(Succeed())
+
+			cfg, err := Load(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.Defaults.ACRServer).To(Equal("test.azurecr.io"))
+		})
+	})
+
+	Context("Watcher", func() {
+		It("Applies only reloadable fields", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(path, []byte(testConfig), 0600)).To(Succeed())
+
+			current, err := Load(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			reloaded := make(chan *configv1alpha1.ControllerConfiguration, 1)
+			watcher := NewWatcher(path, current, func(cfg *configv1alpha1.ControllerConfiguration) {
+				reloaded <- cfg
+			}, ctrl.Log.WithName("config"))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				defer GinkgoRecover()
+				Expect(watcher.Start(ctx)).To(Succeed())
+			}()
+
+			updated := []byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+defaults:
+  acrServer: updated.azurecr.io
+concurrency:
+  maxConcurrentReconciles: 8
+`)
+			// keep writing until the watcher has been established and picked up the change
+			var cfg *configv1alpha1.ControllerConfiguration
+			Eventually(func() bool {
+				Expect(os.WriteFile(path, updated, 0600)).To(Succeed())
+				select {
+				case cfg = <-reloaded:
+					return true
+				case <-time.After(100 * time.Millisecond):
+					return false
+				}
+			}, 5*time.Second).Should(BeTrue())
+			Expect(cfg.Defaults.ACRServer).To(Equal("updated.azurecr.io"))
+			Expect(cfg.RateLimit.QPS).To(Equal(float64(configv1alpha1.DefaultQPS)))
+			Expect(cfg.Concurrency.MaxConcurrentReconciles).To(Equal(4))
+		})
+
+		It("Keeps the ARM token cache duration until a restart", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(path, []byte(testConfig), 0600)).To(Succeed())
+
+			current, err := Load(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			var reloaded *configv1alpha1.ControllerConfiguration
+			watcher := NewWatcher(path, current, func(cfg *configv1alpha1.ControllerConfiguration) {
+				reloaded = cfg
+			}, ctrl.Log.WithName("config"))
+
+			Expect(os.WriteFile(path, []byte(`apiVersion: config.msi-acrpull.microsoft.com/v1alpha1
+kind: ControllerConfiguration
+refresh:
+  tokenRefreshBuffer: 45m
+  armTokenCacheDuration: 1m
+`), 0600)).To(Succeed())
+			watcher.reload()
+
+			Expect(reloaded).ToNot(BeNil())
+			Expect(reloaded.Refresh.TokenRefreshBuffer.Duration).To(Equal(45 * time.Minute))
+			Expect(reloaded.Refresh.ARMTokenCacheDuration.Duration).To(Equal(configv1alpha1.DefaultARMTokenCacheDuration))
+		})
+	})
+})
+
+var _ = Describe("Namespace Tests", func() {
+	Context("ResolveNamespaces", func() {
+		var reader client.Reader
+
+		BeforeEach(func() {
+			reader = fake.NewClientBuilder().WithObjects(
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"acrpull": "enabled"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"acrpull": "enabled"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c"}},
+			).Build()
+		})
+
+		It("Watches all namespaces by default", func() {
+			namespaces, err := ResolveNamespaces(context.Background(), reader, nil, "", []string{"kube-system"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namespaces).To(BeEmpty())
+			Expect(CacheNamespaces(namespaces)).To(BeNil())
+		})
+
+		It("Removes excluded namespaces from the list", func() {
+			namespaces, err := ResolveNamespaces(context.Background(), reader, []string{"team-c", "team-a", "team-b"}, "", []string{"team-b"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namespaces).To(Equal([]string{"team-a", "team-c"}))
+			Expect(CacheNamespaces(namespaces)).To(HaveKey("team-a"))
+		})
+
+		It("Resolves the namespace selector", func() {
+			namespaces, err := ResolveNamespaces(context.Background(), reader, nil, "acrpull=enabled", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(namespaces).To(Equal([]string{"team-a", "team-b"}))
+		})
+
+		It("Rejects a list together with a selector", func() {
+			_, err := ResolveNamespaces(context.Background(), reader, []string{"team-a"}, "acrpull=enabled", nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Rejects a selector matching nothing", func() {
+			_, err := ResolveNamespaces(context.Background(), reader, nil, "acrpull=disabled", nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
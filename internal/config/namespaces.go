@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolveNamespaces returns the namespaces the controller watches. namespaces
+// and selector are mutually exclusive; namespaces matching selector are looked
+// up once, so namespaces labelled later are only picked up after a restart.
+// Excluded namespaces are removed from the result. An empty result means all
+// namespaces are watched.
+func ResolveNamespaces(ctx context.Context, reader client.Reader, namespaces []string, selector string, exclude []string) ([]string, error) {
+	if len(namespaces) > 0 && selector != "" {
+		return nil, fmt.Errorf("a namespace list and a namespace selector can not be used together")
+	}
+
+	if selector != "" {
+		parsed, err := labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector %q: %w", selector, err)
+		}
+
+		var namespaceList v1.NamespaceList
+		if err := reader.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: parsed}); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces matching %q: %w", selector, err)
+		}
+		if len(namespaceList.Items) == 0 {
+			return nil, fmt.Errorf("no namespaces match selector %q", selector)
+		}
+
+		for _, namespace := range namespaceList.Items {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+
+	var result []string
+	for _, namespace := range namespaces {
+		if !containsString(exclude, namespace) && !containsString(result, namespace) {
+			result = append(result, namespace)
+		}
+	}
+	if len(namespaces) > 0 && len(result) == 0 {
+		return nil, fmt.Errorf("every selected namespace is excluded")
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// CacheNamespaces returns the cache configuration restricting it to namespaces,
+// or nil to cache all namespaces.
+func CacheNamespaces(namespaces []string) map[string]cache.Config {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	result := make(map[string]cache.Config, len(namespaces))
+	for _, namespace := range namespaces {
+		result[namespace] = cache.Config{}
+	}
+	return result
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/leaderelection"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+// Validate checks a defaulted configuration and returns every problem found.
+func Validate(cfg *configv1alpha1.ControllerConfiguration) field.ErrorList {
+	var errs field.ErrorList
+
+	cloudPath := field.NewPath("cloud")
+	if _, ok := configv1alpha1.ARMResources[cfg.Cloud.Name]; !ok {
+		errs = append(errs, field.NotSupported(cloudPath.Child("name"), cfg.Cloud.Name, []string{
+			string(configv1alpha1.AzurePublicCloud),
+			string(configv1alpha1.AzureUSGovernmentCloud),
+			string(configv1alpha1.AzureChinaCloud),
+		}))
+	}
+	errs = append(errs, validateURL(cloudPath.Child("armResource"), cfg.Cloud.ARMResource)...)
+	errs = append(errs, validateURL(cloudPath.Child("metadataEndpoint"), cfg.Cloud.MetadataEndpoint)...)
+	errs = append(errs, validateURL(cloudPath.Child("activeDirectoryEndpoint"), cfg.Cloud.ActiveDirectoryEndpoint)...)
+
+	rateLimitPath := field.NewPath("rateLimit")
+	if cfg.RateLimit.QPS <= 0 {
+		errs = append(errs, field.Invalid(rateLimitPath.Child("qps"), cfg.RateLimit.QPS, "must be greater than zero"))
+	}
+	if cfg.RateLimit.Burst < 1 {
+		errs = append(errs, field.Invalid(rateLimitPath.Child("burst"), cfg.RateLimit.Burst, "must be at least 1"))
+	}
+
+	refreshPath := field.NewPath("refresh")
+	if cfg.Refresh.TokenRefreshBuffer.Duration < 0 {
+		errs = append(errs, field.Invalid(refreshPath.Child("tokenRefreshBuffer"), cfg.Refresh.TokenRefreshBuffer.String(), "must not be negative"))
+	}
+	if cfg.Refresh.ARMTokenCacheDuration.Duration < 0 {
+		errs = append(errs, field.Invalid(refreshPath.Child("armTokenCacheDuration"), cfg.Refresh.ARMTokenCacheDuration.String(), "must not be negative"))
+	}
+
+	if cfg.Concurrency.MaxConcurrentReconciles < 1 {
+		errs = append(errs, field.Invalid(field.NewPath("concurrency", "maxConcurrentReconciles"),
+			cfg.Concurrency.MaxConcurrentReconciles, "must be at least 1"))
+	}
+
+	namespacesPath := field.NewPath("namespaces")
+	errs = append(errs, validateNamespaceNames(namespacesPath.Child("include"), cfg.Namespaces.Include)...)
+	errs = append(errs, validateNamespaceNames(namespacesPath.Child("exclude"), cfg.Namespaces.Exclude)...)
+
+	mirroringPath := field.NewPath("mirroring")
+	errs = append(errs, validateNamespaceNames(mirroringPath.Child("sourceNamespaces"), cfg.Mirroring.SourceNamespaces)...)
+	errs = append(errs, validateNamespaceNames(mirroringPath.Child("targetNamespaces"), cfg.Mirroring.TargetNamespaces)...)
+	if cfg.Mirroring.Enabled() && len(cfg.Namespaces.Include) > 0 {
+		errs = append(errs, field.Invalid(mirroringPath, cfg.Mirroring.SourceNamespaces,
+			"mirroring needs the cluster-wide role and can not be combined with namespaces.include"))
+	}
+
+	tracingPath := field.NewPath("tracing")
+	switch cfg.Tracing.Exporter {
+	case configv1alpha1.TracingExporterNone, configv1alpha1.TracingExporterOTLP, configv1alpha1.TracingExporterStdout:
+	default:
+		errs = append(errs, field.NotSupported(tracingPath.Child("exporter"), cfg.Tracing.Exporter, []string{
+			string(configv1alpha1.TracingExporterNone),
+			string(configv1alpha1.TracingExporterOTLP),
+			string(configv1alpha1.TracingExporterStdout),
+		}))
+	}
+	if ratio := cfg.Tracing.SamplingRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		errs = append(errs, field.Invalid(tracingPath.Child("samplingRatio"), *ratio, "must be between 0 and 1"))
+	}
+
+	auditPath := field.NewPath("audit")
+	switch cfg.Audit.Sink {
+	case configv1alpha1.AuditSinkNone, configv1alpha1.AuditSinkStdout:
+	case configv1alpha1.AuditSinkFile:
+		if cfg.Audit.Path == "" {
+			errs = append(errs, field.Required(auditPath.Child("path"), "required when the sink is File"))
+		}
+	case configv1alpha1.AuditSinkWebhook:
+		errs = append(errs, validateURL(auditPath.Child("webhookURL"), cfg.Audit.WebhookURL)...)
+	default:
+		errs = append(errs, field.NotSupported(auditPath.Child("sink"), cfg.Audit.Sink, []string{
+			string(configv1alpha1.AuditSinkNone),
+			string(configv1alpha1.AuditSinkFile),
+			string(configv1alpha1.AuditSinkStdout),
+			string(configv1alpha1.AuditSinkWebhook),
+		}))
+	}
+	if cfg.Audit.WebhookTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(auditPath.Child("webhookTimeout"), cfg.Audit.WebhookTimeout.String(), "must not be negative"))
+	}
+
+	watchdogPath := field.NewPath("expiryWatchdog")
+	if cfg.ExpiryWatchdog.Interval.Duration < 0 {
+		errs = append(errs, field.Invalid(watchdogPath.Child("interval"), cfg.ExpiryWatchdog.Interval.String(), "must not be negative"))
+	}
+	if cfg.ExpiryWatchdog.ExpiringThreshold.Duration < 0 {
+		errs = append(errs, field.Invalid(watchdogPath.Child("expiringThreshold"), cfg.ExpiryWatchdog.ExpiringThreshold.String(), "must not be negative"))
+	} else if cfg.ExpiryWatchdog.ExpiringThreshold.Duration >= cfg.Refresh.TokenRefreshBuffer.Duration {
+		errs = append(errs, field.Invalid(watchdogPath.Child("expiringThreshold"), cfg.ExpiryWatchdog.ExpiringThreshold.String(),
+			"must be shorter than refresh.tokenRefreshBuffer"))
+	}
+
+	healthPath := field.NewPath("health")
+	if cfg.Health.MetadataEndpointTimeout.Duration < 0 {
+		errs = append(errs, field.Invalid(healthPath.Child("metadataEndpointTimeout"), cfg.Health.MetadataEndpointTimeout.String(), "must not be negative"))
+	}
+	if cfg.Health.StuckReconcileWindow.Duration < 0 {
+		errs = append(errs, field.Invalid(healthPath.Child("stuckReconcileWindow"), cfg.Health.StuckReconcileWindow.String(), "must not be negative"))
+	}
+
+	errs = append(errs, validateLeaderElection(field.NewPath("leaderElection"), cfg.LeaderElection)...)
+
+	return errs
+}
+
+// validateLeaderElection checks the lease name and namespace and the timing constraints client-go
+// enforces on leader election, so that a bad configuration is reported at startup with its field.
+func validateLeaderElection(path *field.Path, cfg configv1alpha1.LeaderElectionConfiguration) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(cfg.ID) {
+		errs = append(errs, field.Invalid(path.Child("id"), cfg.ID, msg))
+	}
+	if cfg.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(cfg.Namespace) {
+			errs = append(errs, field.Invalid(path.Child("namespace"), cfg.Namespace, msg))
+		}
+	}
+	if cfg.RetryPeriod.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("retryPeriod"), cfg.RetryPeriod.String(), "must be greater than zero"))
+	}
+	if cfg.LeaseDuration.Duration <= cfg.RenewDeadline.Duration {
+		errs = append(errs, field.Invalid(path.Child("leaseDuration"), cfg.LeaseDuration.String(), "must be longer than renewDeadline"))
+	}
+	if float64(cfg.RenewDeadline.Duration) <= leaderelection.JitterFactor*float64(cfg.RetryPeriod.Duration) {
+		errs = append(errs, field.Invalid(path.Child("renewDeadline"), cfg.RenewDeadline.String(),
+			"must be longer than 1.2 times retryPeriod"))
+	}
+	return errs
+}
+
+func validateURL(path *field.Path, value string) field.ErrorList {
+	u, err := url.Parse(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, value, "must be an absolute URL")}
+	}
+	return nil
+}
+
+func validateNamespaceNames(path *field.Path, namespaces []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, namespace := range namespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(path.Index(i), namespace, msg))
+		}
+	}
+	return errs
+}
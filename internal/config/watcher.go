@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+// Watcher reloads the configuration file when it changes on disk. Only the
+// defaults, rate limit and token refresh buffer are applied at runtime; changes
+// to any other field, including the ARM token cache duration the authorizer is
+// built with, are logged and take effect on the next restart.
+type Watcher struct {
+	// Path is the configuration file to watch.
+	Path string
+	// OnReload is called with the configuration to apply after every successful reload.
+	OnReload func(cfg *configv1alpha1.ControllerConfiguration)
+	Log      logr.Logger
+
+	current *configv1alpha1.ControllerConfiguration
+	latest  *configv1alpha1.ControllerConfiguration
+}
+
+// NewWatcher returns a watcher for the configuration file at path, which was
+// loaded as current during startup.
+func NewWatcher(path string, current *configv1alpha1.ControllerConfiguration,
+	onReload func(cfg *configv1alpha1.ControllerConfiguration), log logr.Logger) *Watcher {
+	return &Watcher{
+		Path:     path,
+		OnReload: onReload,
+		Log:      log,
+		current:  current.DeepCopy(),
+		latest:   current.DeepCopy(),
+	}
+}
+
+// NeedLeaderElection returns false so every replica keeps its configuration current.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watches the directory of the configuration file until ctx is done. The
+// directory is watched rather than the file so that ConfigMap updates, which
+// atomically swap a symlink, are picked up.
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create configuration watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return fmt.Errorf("failed to watch configuration directory: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.Log.Error(err, "Configuration watch error")
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.reload()
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	data, err := os.ReadFile(w.Path)
+	if err != nil {
+		w.Log.Error(err, "Failed to read controller configuration, keeping the current one")
+		return
+	}
+
+	updated, err := Parse(data)
+	if err != nil {
+		w.Log.Error(err, "Ignoring invalid controller configuration, keeping the current one")
+		return
+	}
+	if equality.Semantic.DeepEqual(updated, w.latest) {
+		return
+	}
+	w.latest = updated
+
+	next := w.current.DeepCopy()
+	next.Defaults = updated.Defaults
+	next.RateLimit = updated.RateLimit
+	next.Refresh.TokenRefreshBuffer = updated.Refresh.TokenRefreshBuffer
+
+	if !equality.Semantic.DeepEqual(next, updated) {
+		w.Log.Info("Controller configuration contains changes that require a restart to take effect")
+	}
+
+	w.current = next
+	w.Log.Info("Reloaded controller configuration")
+	w.OnReload(next.DeepCopy())
+}
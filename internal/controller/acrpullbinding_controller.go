@@ -3,32 +3,55 @@ package controller
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	msiacrpullv1beta1 "github.com/Azure/msi-acrpull/api/v1beta1"
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/internal/audit"
 	"github.com/Azure/msi-acrpull/pkg/authorizer"
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
 )
 
 const (
 	ownerKey                  = ".metadata.controller"
-	dockerConfigKey           = ".dockerconfigjson"
 	msiAcrPullFinalizerName   = "msi-acrpull.microsoft.com"
 	defaultServiceAccountName = "default"
 
 	tokenRefreshBuffer = time.Minute * 30
+	// pullVerificationRetryInterval is how soon a binding whose pull verification failed is retried,
+	// so that a fixed role assignment is picked up before the token is due for refresh.
+	pullVerificationRetryInterval = time.Minute * 5
 )
 
 // AcrPullBindingReconciler reconciles a AcrPullBinding object
@@ -40,24 +63,111 @@ type AcrPullBindingReconciler struct {
 	DefaultManagedIdentityResourceID string
 	DefaultManagedIdentityClientID   string
 	DefaultACRServer                 string
+	// TokenRefreshBuffer is how long before expiry a token is refreshed; tokenRefreshBuffer is used when zero.
+	TokenRefreshBuffer time.Duration
+	// MaxConcurrentReconciles is the number of bindings reconciled in parallel.
+	MaxConcurrentReconciles int
+	// IncludeNamespaces and ExcludeNamespaces restrict the namespaces that are reconciled.
+	IncludeNamespaces []string
+	ExcludeNamespaces []string
+	// ShardCount and ShardIndex split namespaces between controller instances. Each
+	// instance only reconciles the namespaces that hash to its ShardIndex.
+	ShardCount int
+	ShardIndex int
+	// MirrorSourceNamespaces and MirrorTargetNamespaces allow the bindings of the source namespaces to copy
+	// their pull secret into the target namespaces. Mirroring is disabled when either is empty.
+	MirrorSourceNamespaces []string
+	MirrorTargetNamespaces []string
+	// TenantID, when set, is the only tenant ACR tokens are accepted for, and the tenant of workload identities
+	// that name none.
+	TenantID string
+	// TokenExchangeAudience is the audience of the service account tokens exchanged for the tokens of
+	// workload identities.
+	TokenExchangeAudience string
+	// CoverageReader, when set, is used to list the workloads of a namespace and report the registry
+	// coverage of its bindings. It is usually an uncached reader, so that workloads are not watched.
+	CoverageReader client.Reader
+	// Clock is the time tokens are validated, refreshed and recorded at, the real clock when nil.
+	Clock clock.PassiveClock
+	// Audit, when set, receives a record of every credential issued, rotated or revoked.
+	Audit audit.Sink
+	// Recorder, when set, records events about bindings.
+	Recorder record.EventRecorder
+	// ExpiryEvents, when set, is watched for bindings to reconcile right away, such as the bindings the
+	// expiry watchdog finds with an expiring credential.
+	ExpiryEvents <-chan event.GenericEvent
+
+	// mu guards the fields that can be changed by ApplyConfiguration while reconciling.
+	mu sync.RWMutex
+	// lastReconcile is when the last reconcile succeeded, in Unix nanoseconds, for StuckReconcileCheck.
+	lastReconcile atomic.Int64
+}
+
+// NewAcrPullBindingReconciler returns a reconciler configured from cfg.
+func NewAcrPullBindingReconciler(c client.Client, log logr.Logger, scheme *runtime.Scheme, auth authorizer.Interface,
+	cfg *configv1alpha1.ControllerConfiguration) *AcrPullBindingReconciler {
+	r := &AcrPullBindingReconciler{
+		Client:                  newTracingClient(c),
+		Log:                     log,
+		Scheme:                  scheme,
+		Auth:                    auth,
+		MaxConcurrentReconciles: cfg.Concurrency.MaxConcurrentReconciles,
+		IncludeNamespaces:       cfg.Namespaces.Include,
+		ExcludeNamespaces:       cfg.Namespaces.Exclude,
+		MirrorSourceNamespaces:  cfg.Mirroring.SourceNamespaces,
+		MirrorTargetNamespaces:  cfg.Mirroring.TargetNamespaces,
+		TenantID:                cfg.Cloud.TenantID,
+		TokenExchangeAudience:   cfg.Cloud.TokenExchangeAudience,
+	}
+	r.ApplyConfiguration(cfg)
+	return r
+}
+
+// ApplyConfiguration updates the settings that can change while the controller is running.
+func (r *AcrPullBindingReconciler) ApplyConfiguration(cfg *configv1alpha1.ControllerConfiguration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.DefaultACRServer = cfg.Defaults.ACRServer
+	r.DefaultManagedIdentityResourceID = cfg.Defaults.ManagedIdentityResourceID
+	r.DefaultManagedIdentityClientID = cfg.Defaults.ManagedIdentityClientID
+	r.TokenRefreshBuffer = cfg.Refresh.TokenRefreshBuffer.Duration
 }
 
 //+kubebuilder:rbac:groups=msi-acrpull.microsoft.com,resources=acrpullbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=msi-acrpull.microsoft.com,resources=acrpullbindings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=msi-acrpull.microsoft.com,resources=acrpullbindings/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=*
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=serviceaccounts/token,verbs=create
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+func (r *AcrPullBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, reconcileID := withCorrelationID(ctx)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "AcrPullBindingReconciler.Reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("acrpullbinding.name", req.Name),
+		attribute.String("reconcile.id", reconcileID),
+	))
+	defer func() { endSpan(span, err) }()
+	defer func() {
+		if err == nil {
+			r.lastReconcile.Store(r.now().UnixNano())
+		}
+	}()
 
-func (r *AcrPullBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("acrpullbinding", req.NamespacedName)
+	log := r.Log.WithValues(logKeyBinding, req.Name, logKeyNamespace, req.Namespace, logKeyReconcileID, reconcileID)
+	return r.reconcile(ctx, req, log)
+}
 
-	var acrBinding msiacrpullv1beta1.AcrPullBinding
+func (r *AcrPullBindingReconciler) reconcile(ctx context.Context, req ctrl.Request, log logr.Logger) (ctrl.Result, error) {
+	var acrBinding msiacrpullv1.AcrPullBinding
 	if err := r.Get(ctx, req.NamespacedName, &acrBinding); err != nil {
 		if !apierrors.IsNotFound(err) {
-			log.Error(err, "unable to fetch acrPullBinding.")
+			log.Error(err, "Failed to get AcrPullBinding")
 			return ctrl.Result{}, err
 		}
-		log.Info("AcrPullBinding is not found. Ignore because this is expected to happen when it is being deleted.")
+		log.V(LogLevelDebug).Info("AcrPullBinding not found, it was deleted")
 		return ctrl.Result{}, nil
 	}
 
@@ -81,14 +191,13 @@ func (r *AcrPullBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 
 	msiClientID, msiResourceID, acrServer := specOrDefault(r, acrBinding.Spec)
-	var acrAccessToken types.AccessToken
-	var err error
+	trace.SpanFromContext(ctx).SetAttributes(authorizer.RegistryAttribute(acrServer), authorizer.IdentityAttribute(msiClientID+msiResourceID))
+	log = log.WithValues(logKeyRegistry, acrServer, logKeyIdentityHash, authorizer.IdentityHash(msiClientID+msiResourceID))
+	acrBinding.Status.AcrServer = acrServer
+	acrBinding.Status.Identity = identitySummary(msiClientID, msiResourceID)
+	acrBinding.Status.ServiceAccounts = serviceAccountsSummary(serviceAccountName, acrBinding.Status.BoundServiceAccounts)
 
-	if msiClientID != "" {
-		acrAccessToken, err = r.Auth.AcquireACRAccessTokenWithClientID(msiClientID, acrServer)
-	} else {
-		acrAccessToken, err = r.Auth.AcquireACRAccessTokenWithResourceID(msiResourceID, acrServer)
-	}
+	acrAccessToken, err := r.acquireACRAccessToken(ctx, &acrBinding, serviceAccountName, msiClientID, msiResourceID, acrServer)
 	if err != nil {
 		log.Error(err, "Failed to get ACR access token")
 		if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
@@ -98,64 +207,370 @@ func (r *AcrPullBindingReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, err
 	}
 
-	dockerConfig := authorizer.CreateACRDockerCfg(acrServer, acrAccessToken)
+	acrClaims, err := r.validateACRToken(acrAccessToken, acrServer)
+	if err != nil {
+		log.Error(err, "Rejected ACR access token")
+		if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
+			log.Error(err, "Failed to update error status")
+		}
 
-	var pullSecrets v1.SecretList
-	if err := r.List(ctx, &pullSecrets, client.InNamespace(req.Namespace), client.MatchingFields{ownerKey: req.Name}); err != nil {
-		log.Error(err, "unable to list child secrets")
 		return ctrl.Result{}, err
 	}
-	pullSecret := getPullSecret(&acrBinding, pullSecrets.Items)
+	tokenExp := acrClaims.ExpiresAt.Time
+	log = log.WithValues(logKeyTokenExpiry, tokenExp.UTC().Format(time.RFC3339))
+
+	identity := msiClientID
+	if identity == "" {
+		identity = msiResourceID
+	}
+
+	if acrBinding.Spec.ExistingSecretName != "" {
+		if err := r.updateExistingSecret(ctx, &acrBinding, acrServer, acrAccessToken, serviceAccountName, log); err != nil {
+			log.Error(err, "Failed to update existing pull secret", logKeySecret, acrBinding.Spec.ExistingSecretName)
+			if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
+				log.Error(err, "Failed to update error status")
+			}
+
+			return ctrl.Result{}, err
+		}
+	} else if err := r.updateOwnedPullSecret(ctx, &acrBinding, acrServer, identity, acrAccessToken, acrClaims, serviceAccountName, log); err != nil {
+		if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
+			log.Error(err, "Failed to update error status")
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// Remove the entries of the binding from the existing secret it wrote into before the last spec change
+	if previous := acrBinding.Status.ExistingSecretName; previous != "" && previous != acrBinding.Spec.ExistingSecretName {
+		if err := r.removeFromExistingSecret(ctx, &acrBinding, previous, log); err != nil {
+			log.Error(err, "Failed to remove registry entries from existing pull secret", logKeySecret, previous)
+			if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
+				log.Error(err, "Failed to update error status")
+			}
+
+			return ctrl.Result{}, err
+		}
+	}
+	acrBinding.Status.ExistingSecretName = acrBinding.Spec.ExistingSecretName
+	r.recordCredential(ctx, &acrBinding, identity, acrServer, acrClaims, serviceAccountName, log)
+
+	mirroredNamespaces, secretsMirrored, err := r.mirrorPullSecret(ctx, &acrBinding, acrServer, identity, acrAccessToken, acrClaims, log)
+	if err != nil {
+		log.Error(err, "Failed to mirror pull secret")
+		if err := r.setErrStatus(ctx, err, &acrBinding); err != nil {
+			log.Error(err, "Failed to update error status")
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	// Associate the image pull secret with the default service account of the namespace
+	serviceAccountBound, err := r.updateServiceAccount(ctx, &acrBinding, req, serviceAccountName, log)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Unbind the service accounts and secret names used before the last spec change
+	current := msiacrpullv1.BoundServiceAccount{Name: serviceAccountName, PullSecretName: PullSecretName(&acrBinding)}
+	if err := r.unbindServiceAccounts(ctx, &acrBinding, &current, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	acrBinding.Status.BoundServiceAccounts = nil
+	if serviceAccountBound.Status == metav1.ConditionTrue {
+		acrBinding.Status.BoundServiceAccounts = []msiacrpullv1.BoundServiceAccount{current}
+	}
+	acrBinding.Status.ServiceAccounts = serviceAccountsSummary(serviceAccountName, acrBinding.Status.BoundServiceAccounts)
+
+	pullVerified := r.verifyPullAccess(ctx, &acrBinding, acrAccessToken, acrServer, log)
+	setCondition(&acrBinding.Status.Conditions, msiacrpullv1.ConditionPullVerified, pullVerified)
+	setCondition(&acrBinding.Status.Conditions, msiacrpullv1.ConditionSecretsMirrored, secretsMirrored)
+	setCondition(&acrBinding.Status.Conditions, msiacrpullv1.ConditionServiceAccountBound, serviceAccountBound)
+	acrBinding.Status.MirroredNamespaces = mirroredNamespaces
+	if r.CoverageReader != nil {
+		coverage, err := r.registryCoverage(ctx, &acrBinding)
+		if err != nil {
+			// the audit is informational, the previous result is kept
+			log.Error(err, "Failed to audit registry coverage")
+		} else {
+			acrBinding.Status.Coverage = coverage
+		}
+	} else {
+		acrBinding.Status.Coverage = nil
+	}
+
+	if err := r.setSuccessStatus(ctx, &acrBinding, tokenExp); err != nil {
+		log.Error(err, "Failed to update acr binding status")
+		return ctrl.Result{}, err
+	}
+
+	requeueAfter := getTokenRefreshDuration(tokenExp, r.tokenRefreshBuffer(), r.now())
+	if pullVerified != nil && pullVerified.Status == metav1.ConditionFalse && requeueAfter > pullVerificationRetryInterval {
+		requeueAfter = pullVerificationRetryInterval
+	}
+	log.V(LogLevelDebug).Info("Reconciled AcrPullBinding", "requeueAfter", requeueAfter)
+
+	return ctrl.Result{
+		RequeueAfter: requeueAfter,
+	}, nil
+}
+
+// updateOwnedPullSecret creates or updates the pull secret owned by the binding. Owned secrets with
+// another name, left behind by a change of the secret template, are deleted.
+func (r *AcrPullBindingReconciler) updateOwnedPullSecret(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	acrServer, identity string, acrAccessToken types.AccessToken, acrClaims *types.ACRClaims, serviceAccountName string,
+	log logr.Logger) error {
+	secretType, secretData, err := buildPullSecretData(acrBinding.Spec, acrServer, acrAccessToken)
+	if err != nil {
+		log.Error(err, "Failed to build docker config")
+		return err
+	}
+
+	var pullSecrets v1.SecretList
+	if err := r.List(ctx, &pullSecrets, client.InNamespace(acrBinding.Namespace), client.MatchingFields{ownerKey: acrBinding.Name}); err != nil {
+		log.Error(err, "Failed to list pull secrets")
+		return err
+	}
+	pullSecret := getPullSecret(acrBinding, pullSecrets.Items)
+	if pullSecret == nil {
+		// a secret of the expected name that lost its owner reference is adopted rather than duplicated,
+		// as long as the controller wrote it and it has the type of the binding
+		if pullSecret, err = r.getUnownedPullSecret(ctx, acrBinding); err != nil {
+			log.Error(err, "Failed to get pull secret")
+			return err
+		}
+		if pullSecret != nil && !adoptable(pullSecret, acrBinding, secretType) {
+			log.Info("Not adopting a secret the controller did not write", logKeySecret, pullSecret.Name, "type", pullSecret.Type)
+			return &secretConflictError{name: pullSecret.Name, secretType: secretType}
+		}
+	}
+	if pullSecret != nil {
+		adopted, err := adoptPullSecret(pullSecret, acrBinding, r.Scheme)
+		if err != nil {
+			log.Error(err, "Failed to adopt pull secret")
+			return err
+		}
+		if adopted {
+			log.Info("Adopting pull secret", logKeySecret, pullSecret.Name)
+		}
+	}
+
+	if err := r.deleteStalePullSecrets(ctx, acrBinding, pullSecrets.Items, getOwnedPullSecretName(acrBinding),
+		serviceAccountName, log); err != nil {
+		return err
+	}
+
+	// The type of a secret can not be changed, so a secret of another format is replaced
+	if pullSecret != nil && pullSecret.Type != secretType {
+		log.Info("Deleting pull secret to change its type", logKeySecret, pullSecret.Name, "type", pullSecret.Type, "newType", secretType)
+		if err := r.Delete(ctx, pullSecret); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete pull secret")
+			return err
+		}
+		pullSecret = nil
+	}
 
 	// Create a new secret if one doesn't already exist
 	if pullSecret == nil {
-		log.Info("Creating new pull secret")
+		log.Info("Creating pull secret", logKeySecret, getOwnedPullSecretName(acrBinding))
 
-		pullSecret, err := newBasePullSecret(&acrBinding, dockerConfig, r.Scheme)
+		pullSecret, err := newBasePullSecret(acrBinding, secretType, secretData, r.Scheme)
 		if err != nil {
 			log.Error(err, "Failed to construct pull secret")
-			return ctrl.Result{}, err
+			return err
+		}
+		if err := setPullSecretMetadata(pullSecret, acrBinding, acrServer, identity, acrClaims, r.now()); err != nil {
+			log.Error(err, "Failed to set pull secret metadata")
+			return err
 		}
 
 		if err := r.Create(ctx, pullSecret); err != nil {
 			log.Error(err, "Failed to create pull secret in cluster")
-			return ctrl.Result{}, err
+			return err
 		}
 	} else {
-		log.Info("Updating existing pull secret")
+		log.V(LogLevelDebug).Info("Updating pull secret", logKeySecret, pullSecret.Name)
 
-		pullSecret := updatePullSecret(&pullSecrets.Items[0], dockerConfig)
+		pullSecret := updatePullSecret(pullSecret, secretData)
+		if err := setPullSecretMetadata(pullSecret, acrBinding, acrServer, identity, acrClaims, r.now()); err != nil {
+			log.Error(err, "Failed to set pull secret metadata")
+			return err
+		}
 		if err := r.Update(ctx, pullSecret); err != nil {
 			log.Error(err, "Failed to update pull secret")
-			return ctrl.Result{}, err
+			return err
 		}
 	}
 
-	// Associate the image pull secret with the default service account of the namespace
-	if err := r.updateServiceAccount(ctx, &acrBinding, req, serviceAccountName, log); err != nil {
-		return ctrl.Result{}, err
+	return nil
+}
+
+// deleteStalePullSecrets deletes the owned secrets other than keep, and their service account reference.
+func (r *AcrPullBindingReconciler) deleteStalePullSecrets(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	pullSecrets []v1.Secret, keep string, serviceAccountName string, log logr.Logger) error {
+	for idx := range pullSecrets {
+		pullSecret := &pullSecrets[idx]
+		if pullSecret.Name == keep {
+			continue
+		}
+
+		if err := r.removeServiceAccountRef(ctx, acrBinding.Namespace, serviceAccountName, pullSecret.Name, log); err != nil {
+			return err
+		}
+
+		log.Info("Deleting stale pull secret", logKeySecret, pullSecret.Name)
+		if err := r.Delete(ctx, pullSecret); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete stale pull secret", logKeySecret, pullSecret.Name)
+			return err
+		}
 	}
+	return nil
+}
 
-	if err := r.setSuccessStatus(ctx, &acrBinding, acrAccessToken); err != nil {
-		log.Error(err, "Failed to update acr binding status")
-		return ctrl.Result{}, err
+// getUnownedPullSecret returns the secret with the name of the pull secret of the binding, or nil if
+// there is none.
+func (r *AcrPullBindingReconciler) getUnownedPullSecret(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding) (*v1.Secret, error) {
+	var pullSecret v1.Secret
+	err := r.Get(ctx, k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: getOwnedPullSecretName(acrBinding)}, &pullSecret)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	return &pullSecret, nil
+}
 
-	return ctrl.Result{
-		RequeueAfter: getTokenRefreshDuration(acrAccessToken),
-	}, nil
+// adoptable reports whether a pull secret the owner index did not attribute to the binding can be adopted:
+// it must be controlled by the binding, or carry the managed-by label of the controller and have the type
+// the binding writes. Any other secret of the same name, for example one named by spec.secretTemplate.name
+// by mistake, belongs to someone else.
+func adoptable(pullSecret *v1.Secret, acrBinding *msiacrpullv1.AcrPullBinding, secretType v1.SecretType) bool {
+	if owner := metav1.GetControllerOf(pullSecret); owner != nil && owner.UID == acrBinding.UID {
+		return true
+	}
+	return pullSecret.Labels[msiacrpullv1.ManagedByLabel] == msiacrpullv1.ManagedByValue && pullSecret.Type == secretType
 }
 
-func specOrDefault(r *AcrPullBindingReconciler, spec msiacrpullv1beta1.AcrPullBindingSpec) (string, string, string) {
-	msiClientID := spec.ManagedIdentityClientID
-	msiResourceID := path.Clean(spec.ManagedIdentityResourceID)
-	acrServer := spec.AcrServer
-	if msiClientID == "" {
-		msiClientID = r.DefaultManagedIdentityClientID
+// secretConflictError is returned when a secret of the pull secret name exists that can not be adopted.
+type secretConflictError struct {
+	name       string
+	secretType v1.SecretType
+}
+
+func (e *secretConflictError) Error() string {
+	return fmt.Sprintf("secret %s already exists and is not a %s secret managed by msi-acrpull", e.name, e.secretType)
+}
+
+// adoptPullSecret makes the binding the controller of a pull secret without one, or of a pull secret
+// still controlled by a previous binding of the same name, as happens when both are restored from
+// a backup. It reports whether the owner references were changed.
+func adoptPullSecret(pullSecret *v1.Secret, acrBinding *msiacrpullv1.AcrPullBinding, scheme *runtime.Scheme) (bool, error) {
+	owner := metav1.GetControllerOf(pullSecret)
+	if owner != nil {
+		if owner.UID == acrBinding.UID {
+			return false, nil
+		}
+		if !isAcrPullBindingRef(owner) || owner.Name != acrBinding.Name {
+			return false, errors.Errorf("secret %s is controlled by %s %s", pullSecret.Name, owner.Kind, owner.Name)
+		}
+
+		var ownerReferences []metav1.OwnerReference
+		for _, ref := range pullSecret.OwnerReferences {
+			if ref.UID != owner.UID {
+				ownerReferences = append(ownerReferences, ref)
+			}
+		}
+		pullSecret.OwnerReferences = ownerReferences
+	}
+
+	if err := ctrl.SetControllerReference(acrBinding, pullSecret, scheme); err != nil {
+		return false, errors.Wrap(err, "failed to adopt pull secret")
+	}
+	return true, nil
+}
+
+// verifyPullAccess checks the new credential against the registry when the binding asks for it
+// and returns the resulting PullVerified condition, or nil when verification is disabled.
+func (r *AcrPullBindingReconciler) verifyPullAccess(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	acrAccessToken types.AccessToken, acrServer string, log logr.Logger) *metav1.Condition {
+	if acrBinding.Spec.PullVerification == nil {
+		return nil
+	}
+
+	condition := &metav1.Condition{
+		Type:               msiacrpullv1.ConditionPullVerified,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: acrBinding.Generation,
+		Reason:             msiacrpullv1.ReasonPullVerified,
+		Message:            fmt.Sprintf("Registry %s accepted the pull credential", acrServer),
+	}
+	probeImage := acrBinding.Spec.PullVerification.ProbeImage
+	if probeImage != "" {
+		condition.Message = fmt.Sprintf("Pull access to %s was verified", probeImage)
+	}
+
+	if err := r.Auth.VerifyPullAccess(ctx, acrAccessToken, acrServer, probeImage); err != nil {
+		log.Error(err, "Failed to verify pull access", "probeImage", probeImage)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = msiacrpullv1.ReasonRegistryAccessDenied
+		condition.Message = redact.String(err.Error())
+	}
+	return condition
+}
+
+// validateACRToken parses the claims of the token and rejects a token that was not issued for acrServer,
+// has no expiry, is not yet valid, or was issued for another tenant than the configured one. The claims
+// are returned so that the rest of the reconcile does not parse the token again.
+func (r *AcrPullBindingReconciler) validateACRToken(accessToken types.AccessToken, acrServer string) (*types.ACRClaims, error) {
+	claims, err := accessToken.GetACRClaims()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ACR access token")
+	}
+	if err := claims.Validate(acrServer, r.TenantID, r.now()); err != nil {
+		return nil, errors.Wrap(err, "invalid ACR access token")
+	}
+	return claims, nil
+}
+
+// now returns the current time of the clock of the reconciler.
+func (r *AcrPullBindingReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *AcrPullBindingReconciler) tokenRefreshBuffer() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.TokenRefreshBuffer == 0 {
+		return tokenRefreshBuffer
 	}
-	if msiResourceID == "." {
+	return r.TokenRefreshBuffer
+}
+
+func specOrDefault(r *AcrPullBindingReconciler, spec msiacrpullv1.AcrPullBindingSpec) (string, string, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var msiClientID, msiResourceID string
+	switch {
+	case spec.Identity == nil:
+		msiClientID = r.DefaultManagedIdentityClientID
 		msiResourceID = r.DefaultManagedIdentityResourceID
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeClientID:
+		msiClientID = spec.Identity.ClientID
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeResourceID:
+		msiResourceID = path.Clean(spec.Identity.ResourceID)
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeWorkload && spec.Identity.Workload != nil:
+		// applications are recorded by their client ID, like managed identities
+		msiClientID = spec.Identity.Workload.ClientID
+	case spec.Identity.Type == msiacrpullv1.IdentityTypeServicePrincipal && spec.Identity.ServicePrincipal != nil:
+		msiClientID = spec.Identity.ServicePrincipal.ClientID
 	}
+	acrServer := spec.AcrServer
 	if acrServer == "" {
 		acrServer = r.DefaultACRServer
 	}
@@ -164,107 +579,274 @@ func specOrDefault(r *AcrPullBindingReconciler, spec msiacrpullv1beta1.AcrPullBi
 
 func (r *AcrPullBindingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ctx := context.Background()
-	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1.Secret{}, ownerKey, func(rawObj client.Object) []string {
-		secret := rawObj.(*v1.Secret)
-		owner := metav1.GetControllerOf(secret)
-		if owner == nil {
-			return nil
-		}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1.Secret{}, ownerKey, pullSecretOwner); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &v1.Secret{}, mirrorSourceKey, mirrorSource); err != nil {
+		return err
+	}
 
-		if owner.APIVersion != msiacrpullv1beta1.GroupVersion.String() || owner.Kind != "AcrPullBinding" {
-			return nil
-		}
+	bindingPredicates := builder.WithPredicates(
+		predicate.GenerationChangedPredicate{}, // Needed to not enter reconcile loop on status update
+		namespaceFilter(r.IncludeNamespaces, r.ExcludeNamespaces),
+		shardFilter(r.ShardCount, r.ShardIndex),
+	)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&msiacrpullv1.AcrPullBinding{}, bindingPredicates).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Owns(&v1.Secret{}, bindingPredicates).
+		// bindings applied before their service account wait for it to be created
+		Watches(&v1.ServiceAccount{}, handler.EnqueueRequestsFromMapFunc(r.serviceAccountBindings),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}))
+	if r.mirroringEnabled() {
+		// mirrored secrets live in other namespaces; recreate them when they are deleted
+		b = b.Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mirrorSourceRequest),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return false },
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			})).
+			Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mirroringBindings),
+				builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	if r.ExpiryEvents != nil {
+		b = b.WatchesRawSource(&source.Channel{Source: r.ExpiryEvents}, &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(r)
+}
 
-		return []string{owner.Name}
-	}); err != nil {
-		return err
+// pullSecretOwner indexes secrets by the name of the AcrPullBinding controlling them.
+func pullSecretOwner(rawObj client.Object) []string {
+	secret := rawObj.(*v1.Secret)
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil {
+		return nil
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&msiacrpullv1beta1.AcrPullBinding{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}). // Needed to not enter reconcile loop on status update
-		Owns(&v1.Secret{}).
-		Complete(r)
+	if !isAcrPullBindingRef(owner) {
+		return nil
+	}
+
+	return []string{owner.Name}
+}
+
+// isAcrPullBindingRef reports whether an owner reference points to an AcrPullBinding of any version:
+// secrets written before v1 became the storage version are controlled through v1beta1.
+func isAcrPullBindingRef(ref *metav1.OwnerReference) bool {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == msiacrpullv1.GroupVersion.Group && ref.Kind == "AcrPullBinding"
+}
+
+// namespaceFilter only admits objects in included namespaces that are not excluded.
+// All namespaces are included when include is empty.
+func namespaceFilter(include, exclude []string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		if containsString(exclude, obj.GetNamespace()) {
+			return false
+		}
+		return len(include) == 0 || containsString(include, obj.GetNamespace())
+	})
 }
 
-func (r *AcrPullBindingReconciler) addFinalizer(ctx context.Context, acrBinding *msiacrpullv1beta1.AcrPullBinding, log logr.Logger) error {
+// shardFilter only admits objects in namespaces assigned to shard index.
+// Sharding is disabled when count is less than two.
+func shardFilter(count, index int) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return count < 2 || namespaceShard(obj.GetNamespace(), count) == index
+	})
+}
+
+func namespaceShard(namespace string, count int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(namespace))
+	return int(hash.Sum32() % uint32(count))
+}
+
+func (r *AcrPullBindingReconciler) addFinalizer(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding, log logr.Logger) error {
 	if !containsString(acrBinding.ObjectMeta.Finalizers, msiAcrPullFinalizerName) {
 		acrBinding.ObjectMeta.Finalizers = append(acrBinding.ObjectMeta.Finalizers, msiAcrPullFinalizerName)
 		if err := r.Update(ctx, acrBinding); err != nil {
-			log.Error(err, "Failed to append acr pull binding finalizer", "finalizerName", msiAcrPullFinalizerName)
+			log.Error(err, "Failed to add finalizer", "finalizer", msiAcrPullFinalizerName)
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *AcrPullBindingReconciler) removeFinalizer(ctx context.Context, acrBinding *msiacrpullv1beta1.AcrPullBinding,
+func (r *AcrPullBindingReconciler) removeFinalizer(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
 	req ctrl.Request, serviceAccountName string, log logr.Logger) error {
 	if containsString(acrBinding.ObjectMeta.Finalizers, msiAcrPullFinalizerName) {
-		// our finalizer is present, so need to clean up ImagePullSecret reference
-		var serviceAccount v1.ServiceAccount
-		saNamespacedName := k8stypes.NamespacedName{
-			Namespace: req.Namespace,
-			Name:      serviceAccountName,
-		}
-		if err := r.Get(ctx, saNamespacedName, &serviceAccount); err != nil {
-			if !apierrors.IsNotFound(err) {
-				log.Error(err, "Failed to get service account")
-				return err
-			}
-			log.Info("Service account is not found. Continue removing finalizer", "serviceAccountName", saNamespacedName.Name)
-		} else {
-			pullSecretName := getPullSecretName(acrBinding.Name)
-			serviceAccount.ImagePullSecrets = removeImagePullSecretRef(serviceAccount.ImagePullSecrets, pullSecretName)
-			if err := r.Update(ctx, &serviceAccount); err != nil {
-				log.Error(err, "Failed to remove image pull secret reference from default service account", "pullSecretName", pullSecretName)
+		revocation, err := r.revocationRecord(ctx, acrBinding, serviceAccountName)
+		if err != nil {
+			log.Error(err, "Failed to get pull secret")
+			return err
+		}
+
+		// the existing secret belongs to the user, so only the registry entries of the binding are
+		// removed, from the secret of the spec and from the one written before the last spec change
+		for _, secretName := range existingSecretNames(acrBinding) {
+			if err := r.removeFromExistingSecret(ctx, acrBinding, secretName, log); err != nil {
+				log.Error(err, "Failed to remove registry entries from existing pull secret", logKeySecret, secretName)
 				return err
 			}
 		}
 
+		// our finalizer is present, so need to clean up ImagePullSecret reference
+		if err := r.removeServiceAccountRef(ctx, req.Namespace, serviceAccountName, PullSecretName(acrBinding), log); err != nil {
+			return err
+		}
+
+		// service accounts the binding targeted before its last spec change
+		if err := r.unbindServiceAccounts(ctx, acrBinding, nil, log); err != nil {
+			return err
+		}
+
+		if err := r.deleteMirroredSecrets(ctx, acrBinding, log); err != nil {
+			log.Error(err, "Failed to delete mirrored pull secrets")
+			return err
+		}
+
 		// remove our finalizer from the list and update it.
 		acrBinding.ObjectMeta.Finalizers = removeString(acrBinding.ObjectMeta.Finalizers, msiAcrPullFinalizerName)
 		if err := r.Update(ctx, acrBinding); err != nil {
-			log.Error(err, "Failed to remove acr pull binding finalizer", "finalizerName", msiAcrPullFinalizerName)
+			log.Error(err, "Failed to remove finalizer", "finalizer", msiAcrPullFinalizerName)
 			return err
 		}
+		r.writeAuditRecord(ctx, revocation, log)
 	}
 	return nil
 }
 
-func (r *AcrPullBindingReconciler) updateServiceAccount(ctx context.Context, acrBinding *msiacrpullv1beta1.AcrPullBinding,
-	req ctrl.Request, serviceAccountName string, log logr.Logger) error {
+// updateServiceAccount adds the image pull secret to the service account and returns the
+// ServiceAccountBound condition. A service account that does not exist yet is not an error: the
+// binding is reconciled again when it is created.
+func (r *AcrPullBindingReconciler) updateServiceAccount(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	req ctrl.Request, serviceAccountName string, log logr.Logger) (*metav1.Condition, error) {
 	var serviceAccount v1.ServiceAccount
 	saNamespacedName := k8stypes.NamespacedName{
 		Namespace: req.Namespace,
 		Name:      serviceAccountName,
 	}
 	if err := r.Get(ctx, saNamespacedName, &serviceAccount); err != nil {
-		log.Error(err, "Failed to get service account")
-		return err
+		if apierrors.IsNotFound(err) {
+			log.Info("Service account does not exist yet, waiting for it to be created", logKeyServiceAccount, serviceAccountName)
+			return &metav1.Condition{
+				Type:               msiacrpullv1.ConditionServiceAccountBound,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: acrBinding.Generation,
+				Reason:             msiacrpullv1.ReasonServiceAccountNotFound,
+				Message:            fmt.Sprintf("Service account %s does not exist", serviceAccountName),
+			}, nil
+		}
+		log.Error(err, "Failed to get service account", logKeyServiceAccount, serviceAccountName)
+		return nil, err
 	}
-	pullSecretName := getPullSecretName(acrBinding.Name)
+	pullSecretName := PullSecretName(acrBinding)
 	if !imagePullSecretRefExist(serviceAccount.ImagePullSecrets, pullSecretName) {
-		log.Info("Updating default service account")
+		log.Info("Binding service account", logKeyServiceAccount, serviceAccountName, logKeySecret, pullSecretName)
 		appendImagePullSecretRef(&serviceAccount, pullSecretName)
 		if err := r.Update(ctx, &serviceAccount); err != nil {
-			log.Error(err, "Failed to append image pull secret reference to default service account", "pullSecretName", pullSecretName)
+			log.Error(err, "Failed to append image pull secret reference to service account", logKeyServiceAccount, serviceAccountName, logKeySecret, pullSecretName)
+			return nil, err
+		}
+	}
+	return &metav1.Condition{
+		Type:               msiacrpullv1.ConditionServiceAccountBound,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: acrBinding.Generation,
+		Reason:             msiacrpullv1.ReasonServiceAccountBound,
+		Message:            fmt.Sprintf("Service account %s references image pull secret %s", serviceAccountName, pullSecretName),
+	}, nil
+}
+
+// unbindServiceAccounts removes the image pull secret references recorded in the status of the
+// binding from their service accounts, except for keep.
+func (r *AcrPullBindingReconciler) unbindServiceAccounts(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	keep *msiacrpullv1.BoundServiceAccount, log logr.Logger) error {
+	for _, bound := range acrBinding.Status.BoundServiceAccounts {
+		if keep != nil && bound == *keep {
+			continue
+		}
+		log.Info("Unbinding service account", logKeyServiceAccount, bound.Name, logKeySecret, bound.PullSecretName)
+		if err := r.removeServiceAccountRef(ctx, acrBinding.Namespace, bound.Name, bound.PullSecretName, log); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *AcrPullBindingReconciler) setSuccessStatus(ctx context.Context, acrBinding *msiacrpullv1beta1.AcrPullBinding, accessToken types.AccessToken) error {
-	tokenExp, err := accessToken.GetTokenExp()
-	if err != nil {
+// removeServiceAccountRef removes the reference to pullSecretName from a service account, if it still exists.
+func (r *AcrPullBindingReconciler) removeServiceAccountRef(ctx context.Context, namespace, serviceAccountName, pullSecretName string,
+	log logr.Logger) error {
+	var serviceAccount v1.ServiceAccount
+	if err := r.Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &serviceAccount); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(LogLevelDebug).Info("Service account not found, nothing to unbind", logKeyServiceAccount, serviceAccountName)
+			return nil
+		}
+		log.Error(err, "Failed to get service account", logKeyServiceAccount, serviceAccountName)
 		return err
 	}
+	if !imagePullSecretRefExist(serviceAccount.ImagePullSecrets, pullSecretName) {
+		return nil
+	}
+	serviceAccount.ImagePullSecrets = removeImagePullSecretRef(serviceAccount.ImagePullSecrets, pullSecretName)
+	if err := r.Update(ctx, &serviceAccount); err != nil {
+		log.Error(err, "Failed to remove image pull secret reference from service account", logKeyServiceAccount, serviceAccountName, logKeySecret, pullSecretName)
+		return err
+	}
+	return nil
+}
+
+// serviceAccountBindings maps a service account to reconcile requests for the bindings of its
+// namespace that target it, so that a binding waiting for its service account binds it right away.
+func (r *AcrPullBindingReconciler) serviceAccountBindings(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !r.reconcilesNamespace(obj.GetNamespace()) {
+		return nil
+	}
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := r.List(ctx, &acrBindings, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list AcrPullBindings", logKeyNamespace, obj.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, acrBinding := range acrBindings.Items {
+		if getServiceAccountName(acrBinding.Spec.ServiceAccountName) != obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name},
+		})
+	}
+	return requests
+}
 
-	acrBinding.Status = msiacrpullv1beta1.AcrPullBindingStatus{
+// setSuccessStatus records the new token and clears the error. Conditions, bound service accounts,
+// mirrored namespaces and coverage set by the caller are kept.
+func (r *AcrPullBindingReconciler) setSuccessStatus(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	tokenExp time.Time) error {
+	acrBinding.Status = msiacrpullv1.AcrPullBindingStatus{
 		TokenExpirationTime:  &metav1.Time{Time: tokenExp},
-		LastTokenRefreshTime: &metav1.Time{Time: time.Now().UTC()},
+		LastTokenRefreshTime: &metav1.Time{Time: r.now().UTC()},
+		AcrServer:            acrBinding.Status.AcrServer,
+		Identity:             acrBinding.Status.Identity,
+		ServiceAccounts:      acrBinding.Status.ServiceAccounts,
+		BoundServiceAccounts: acrBinding.Status.BoundServiceAccounts,
+		ExistingSecretName:   acrBinding.Status.ExistingSecretName,
+		MirroredNamespaces:   acrBinding.Status.MirroredNamespaces,
+		Coverage:             acrBinding.Status.Coverage,
+		Conditions:           acrBinding.Status.Conditions,
 	}
+	meta.SetStatusCondition(&acrBinding.Status.Conditions, credentialValidCondition(credentialStateValid, tokenExp, acrBinding.Generation))
+	meta.SetStatusCondition(&acrBinding.Status.Conditions, readyCondition(acrBinding.Status.Conditions, acrBinding.Generation))
 
 	if err := r.Status().Update(ctx, acrBinding); err != nil {
 		return err
@@ -273,8 +855,33 @@ func (r *AcrPullBindingReconciler) setSuccessStatus(ctx context.Context, acrBind
 	return nil
 }
 
-func (r *AcrPullBindingReconciler) setErrStatus(ctx context.Context, err error, acrBinding *msiacrpullv1beta1.AcrPullBinding) error {
-	acrBinding.Status.Error = err.Error()
+// setCondition sets the condition of conditionType, or removes it when condition is nil.
+func setCondition(conditions *[]metav1.Condition, conditionType string, condition *metav1.Condition) {
+	if condition == nil {
+		meta.RemoveStatusCondition(conditions, conditionType)
+		return
+	}
+	meta.SetStatusCondition(conditions, *condition)
+}
+
+// setErrStatus records err in the status of the binding, with any token material redacted as the
+// status is readable by everyone allowed to get the binding.
+func (r *AcrPullBindingReconciler) setErrStatus(ctx context.Context, err error, acrBinding *msiacrpullv1.AcrPullBinding) error {
+	message := redact.String(err.Error())
+	reason := msiacrpullv1.ReasonReconcileFailed
+	var conflict *secretConflictError
+	if errors.As(err, &conflict) {
+		reason = msiacrpullv1.ReasonSecretConflict
+	}
+	acrBinding.Status.Error = message
+	meta.SetStatusCondition(&acrBinding.Status.Conditions, metav1.Condition{
+		Type:               msiacrpullv1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: acrBinding.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	r.warningEvent(ctx, acrBinding, reason, "%s", message)
 	if err := r.Status().Update(ctx, acrBinding); err != nil {
 		return err
 	}
@@ -282,11 +889,84 @@ func (r *AcrPullBindingReconciler) setErrStatus(ctx context.Context, err error,
 	return nil
 }
 
-func updatePullSecret(pullSecret *v1.Secret, dockerConfig string) *v1.Secret {
-	pullSecret.Data[dockerConfigKey] = []byte(dockerConfig)
+// readyCondition summarizes the other conditions of a binding whose credential was issued. It takes the
+// reason and message of the first condition that is not True.
+func readyCondition(conditions []metav1.Condition, generation int64) metav1.Condition {
+	ready := metav1.Condition{
+		Type:               msiacrpullv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             msiacrpullv1.ReasonCredentialIssued,
+		Message:            "The pull credential is issued",
+	}
+	for _, conditionType := range []string{
+		msiacrpullv1.ConditionServiceAccountBound,
+		msiacrpullv1.ConditionPullVerified,
+		msiacrpullv1.ConditionSecretsMirrored,
+	} {
+		condition := meta.FindStatusCondition(conditions, conditionType)
+		if condition != nil && condition.Status != metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+			break
+		}
+	}
+	return ready
+}
+
+// identitySummary returns the identity shown by kubectl: the client ID, or the last segment of the
+// resource ID, which is the name of the managed identity.
+func identitySummary(clientID, resourceID string) string {
+	if clientID != "" || resourceID == "" {
+		return clientID
+	}
+	return path.Base(resourceID)
+}
+
+// serviceAccountsSummary returns the comma separated names of the target service account and of the
+// service accounts the binding is still bound to.
+func serviceAccountsSummary(target string, bound []msiacrpullv1.BoundServiceAccount) string {
+	names := []string{target}
+	for _, serviceAccount := range bound {
+		if !containsString(names, serviceAccount.Name) {
+			names = append(names, serviceAccount.Name)
+		}
+	}
+	sort.Strings(names[1:])
+	return strings.Join(names, ",")
+}
+
+func updatePullSecret(pullSecret *v1.Secret, secretData map[string][]byte) *v1.Secret {
+	pullSecret.Data = secretData
 	return pullSecret
 }
 
+// buildPullSecretData returns the type and content of the pull secret in the format requested by spec.
+func buildPullSecretData(spec msiacrpullv1.AcrPullBindingSpec, acrServer string,
+	accessToken types.AccessToken) (v1.SecretType, map[string][]byte, error) {
+	builder := authorizer.NewDockerConfigBuilder()
+	builder.IdentityToken = spec.SecretFormat == msiacrpullv1.SecretFormatDockerConfigJSONIdentityToken
+	builder.Add(acrServer, accessToken, spec.HostAliases...)
+
+	switch spec.SecretFormat {
+	case msiacrpullv1.SecretFormatDockerCfg:
+		dockerCfg, err := builder.DockerCfg()
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to marshal docker config")
+		}
+		return v1.SecretTypeDockercfg, map[string][]byte{v1.DockerConfigKey: dockerCfg}, nil
+	case "", msiacrpullv1.SecretFormatDockerConfigJSON, msiacrpullv1.SecretFormatDockerConfigJSONIdentityToken:
+		dockerConfig, err := builder.DockerConfigJSON()
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to marshal docker config")
+		}
+		return v1.SecretTypeDockerConfigJson, map[string][]byte{v1.DockerConfigJsonKey: dockerConfig}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported secret format %q", spec.SecretFormat)
+	}
+}
+
 func appendImagePullSecretRef(serviceAccount *v1.ServiceAccount, secretName string) {
 	secretReference := &v1.LocalObjectReference{
 		Name: secretName,
@@ -348,12 +1028,28 @@ func getPullSecretName(acrBindingName string) string {
 	return fmt.Sprintf("%s-msi-acrpull-secret", acrBindingName)
 }
 
-func getPullSecret(acrBinding *msiacrpullv1beta1.AcrPullBinding, pullSecrets []v1.Secret) *v1.Secret {
+// getOwnedPullSecretName returns the name of the secret created for the binding.
+func getOwnedPullSecretName(acrBinding *msiacrpullv1.AcrPullBinding) string {
+	if acrBinding.Spec.SecretTemplate != nil && acrBinding.Spec.SecretTemplate.Name != "" {
+		return acrBinding.Spec.SecretTemplate.Name
+	}
+	return getPullSecretName(acrBinding.Name)
+}
+
+// PullSecretName returns the name of the secret the binding writes its credential into.
+func PullSecretName(acrBinding *msiacrpullv1.AcrPullBinding) string {
+	if acrBinding.Spec.ExistingSecretName != "" {
+		return acrBinding.Spec.ExistingSecretName
+	}
+	return getOwnedPullSecretName(acrBinding)
+}
+
+func getPullSecret(acrBinding *msiacrpullv1.AcrPullBinding, pullSecrets []v1.Secret) *v1.Secret {
 	if pullSecrets == nil {
 		return nil
 	}
 
-	pullSecretName := getPullSecretName(acrBinding.Name)
+	pullSecretName := getOwnedPullSecretName(acrBinding)
 
 	for idx, secret := range pullSecrets {
 		if secret.Name == pullSecretName {
@@ -364,20 +1060,18 @@ func getPullSecret(acrBinding *msiacrpullv1beta1.AcrPullBinding, pullSecrets []v
 	return nil
 }
 
-func newBasePullSecret(acrBinding *msiacrpullv1beta1.AcrPullBinding,
-	dockerConfig string, scheme *runtime.Scheme) (*v1.Secret, error) {
+func newBasePullSecret(acrBinding *msiacrpullv1.AcrPullBinding,
+	secretType v1.SecretType, secretData map[string][]byte, scheme *runtime.Scheme) (*v1.Secret, error) {
 
 	pullSecret := &v1.Secret{
-		Type: v1.SecretTypeDockerConfigJson,
+		Type: secretType,
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      map[string]string{},
 			Annotations: map[string]string{},
-			Name:        getPullSecretName(acrBinding.Name),
+			Name:        getOwnedPullSecretName(acrBinding),
 			Namespace:   acrBinding.Namespace,
 		},
-		Data: map[string][]byte{
-			dockerConfigKey: []byte(dockerConfig),
-		},
+		Data: secretData,
 	}
 
 	if err := ctrl.SetControllerReference(acrBinding, pullSecret, scheme); err != nil {
@@ -387,13 +1081,47 @@ func newBasePullSecret(acrBinding *msiacrpullv1beta1.AcrPullBinding,
 	return pullSecret, nil
 }
 
-func getTokenRefreshDuration(accessToken types.AccessToken) time.Duration {
-	exp, err := accessToken.GetTokenExp()
+// setPullSecretMetadata applies the secret template of the binding and stamps the identity, registry,
+// token ID and expiry and the refresh time now. The metadata of the controller takes precedence over the template.
+func setPullSecretMetadata(pullSecret *v1.Secret, acrBinding *msiacrpullv1.AcrPullBinding,
+	acrServer, identity string, claims *types.ACRClaims, now time.Time) error {
+	tokenExp, err := claims.Expiry()
 	if err != nil {
-		return 0
+		return err
+	}
+
+	if pullSecret.Labels == nil {
+		pullSecret.Labels = map[string]string{}
 	}
+	if pullSecret.Annotations == nil {
+		pullSecret.Annotations = map[string]string{}
+	}
+	if template := acrBinding.Spec.SecretTemplate; template != nil {
+		for key, value := range template.Labels {
+			pullSecret.Labels[key] = value
+		}
+		for key, value := range template.Annotations {
+			pullSecret.Annotations[key] = value
+		}
+	}
+
+	pullSecret.Labels[msiacrpullv1.ManagedByLabel] = msiacrpullv1.ManagedByValue
+	pullSecret.Annotations[msiacrpullv1.AcrServerAnnotation] = acrServer
+	pullSecret.Annotations[msiacrpullv1.ManagedIdentityAnnotation] = identity
+	pullSecret.Annotations[msiacrpullv1.TokenExpiryAnnotation] = tokenExp.UTC().Format(time.RFC3339)
+	if claims.ID != "" {
+		pullSecret.Annotations[msiacrpullv1.TokenIDAnnotation] = claims.ID
+	} else {
+		delete(pullSecret.Annotations, msiacrpullv1.TokenIDAnnotation)
+	}
+	pullSecret.Annotations[msiacrpullv1.LastRefreshAnnotation] = now.UTC().Format(time.RFC3339)
+	return nil
+}
 
-	refreshDuration := exp.Sub(time.Now().Add(tokenRefreshBuffer))
+// getTokenRefreshDuration returns how long after now a token expiring at exp is due for refresh,
+// refreshBuffer before it expires. A token that is already due is due immediately.
+func getTokenRefreshDuration(exp time.Time, refreshBuffer time.Duration, now time.Time) time.Duration {
+	refreshDuration := exp.Sub(now.Add(refreshBuffer))
 	if refreshDuration < 0 {
 		return 0
 	}
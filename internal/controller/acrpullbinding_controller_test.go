@@ -5,28 +5,62 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
 	"github.com/Azure/msi-acrpull/pkg/authorizer/mock_authorizer"
+	"github.com/go-logr/logr/funcr"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	_ "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	msiacrpullv1beta1 "github.com/Azure/msi-acrpull/api/v1beta1"
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/internal/audit"
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
 )
 
+// recordingAuditSink keeps the audit records written to it.
+type recordingAuditSink struct {
+	records []*audit.Record
+}
+
+func (s *recordingAuditSink) Write(_ context.Context, record *audit.Record) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingAuditSink) Close() error {
+	return nil
+}
+
 type errorFakeCtrlRuntimeClient struct {
 	client.Client
 }
@@ -41,64 +75,1411 @@ func (e *errorFakeCtrlRuntimeClient) Get(ctx context.Context, key client.ObjectK
 		errors.New("test error"))
 }
 
-var _ = msiacrpullv1beta1.AddToScheme(scheme.Scheme)
+var _ = msiacrpullv1.AddToScheme(scheme.Scheme)
+
+var _ = Describe("AcrPullBinding Controller Tests", func() {
+	Context("Reconcile", func() {
+		It("Should not return error when the acr pull binding is not found", func() {
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+			}
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+				},
+			}
+			_, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should use defaults when no parameters defined", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					Build(),
+				Log:                              ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme:                           scheme.Scheme,
+				Auth:                             fakeAuth,
+				DefaultManagedIdentityResourceID: "defaultResourceID",
+				DefaultACRServer:                 "DefaultACRServer",
+			}
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(),
+				gomock.Eq(reconciler.DefaultManagedIdentityResourceID),
+				gomock.Eq(reconciler.DefaultACRServer)).Times(1)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{},
+				},
+			}
+			reconciler.Create(context.TODO(), acrBinding)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			reconciler.Reconcile(context.Background(), req)
+			mockCtrl.Finish()
+		})
+
+		It("Should record the pull verification outcome", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:        "test.azurecr.io",
+					Identity:         &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					PullVerification: &msiacrpullv1.PullVerification{ProbeImage: "probe:1.0"},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+			gomock.InOrder(
+				fakeAuth.EXPECT().VerifyPullAccess(gomock.Any(), acrToken, "test.azurecr.io", "probe:1.0").
+					Return(errors.New("ACR manifest endpoint returned error status for probe:1.0: 401")),
+				fakeAuth.EXPECT().VerifyPullAccess(gomock.Any(), acrToken, "test.azurecr.io", "probe:1.0").Return(nil),
+			)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			result, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(pullVerificationRetryInterval))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			pullVerified := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionPullVerified)
+			Expect(pullVerified).NotTo(BeNil())
+			Expect(pullVerified.Status).To(Equal(metav1.ConditionFalse))
+			Expect(pullVerified.Reason).To(Equal(msiacrpullv1.ReasonRegistryAccessDenied))
+			Expect(pullVerified.Message).To(ContainSubstring("401"))
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(msiacrpullv1.ReasonRegistryAccessDenied))
+
+			result, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", pullVerificationRetryInterval))
+
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			pullVerified = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionPullVerified)
+			Expect(pullVerified.Status).To(Equal(metav1.ConditionTrue))
+			Expect(pullVerified.Reason).To(Equal(msiacrpullv1.ReasonPullVerified))
+			ready = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			Expect(ready.Reason).To(Equal(msiacrpullv1.ReasonCredentialIssued))
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, msiacrpullv1.ConditionCredentialValid)).To(BeTrue())
+			Expect(updated.Status.AcrServer).To(Equal("test.azurecr.io"))
+			Expect(updated.Status.Identity).To(Equal("testResourceID"))
+			Expect(updated.Status.ServiceAccounts).To(Equal(defaultServiceAccountName))
+			mockCtrl.Finish()
+		})
+
+		It("Should replace the pull secret when its format changes", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			secretName := k8stypes.NamespacedName{Namespace: "default", Name: getPullSecretName("test")}
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), secretName, &pullSecret)).To(Succeed())
+			Expect(pullSecret.Type).To(Equal(v1.SecretTypeDockerConfigJson))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			updated.Spec.SecretFormat = msiacrpullv1.SecretFormatDockerCfg
+			Expect(reconciler.Update(context.Background(), &updated)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Get(context.Background(), secretName, &pullSecret)).To(Succeed())
+			Expect(pullSecret.Type).To(Equal(v1.SecretTypeDockercfg))
+			Expect(pullSecret.Data).To(HaveKey(v1.DockerConfigKey))
+			mockCtrl.Finish()
+		})
+
+		It("Should exchange a service account token for the token of a workload identity", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity: &msiacrpullv1.Identity{
+						Type: msiacrpullv1.IdentityTypeWorkload,
+						Workload: &msiacrpullv1.WorkloadIdentity{
+							ClientID:           "00000000-0000-0000-0000-000000000001",
+							TenantID:           "00000000-0000-0000-0000-000000000002",
+							ServiceAccountName: "puller",
+						},
+					},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: defaultServiceAccountName, Namespace: "default"},
+			}
+			var tokenRequests []string
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					WithInterceptorFuncs(interceptor.Funcs{
+						SubResourceCreate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+							tokenRequest := subResource.(*authenticationv1.TokenRequest)
+							Expect(subResourceName).To(Equal("token"))
+							Expect(tokenRequest.Spec.Audiences).To(Equal([]string{"api://AzureADTokenExchangeUSGov"}))
+							tokenRequests = append(tokenRequests, obj.GetNamespace()+"/"+obj.GetName())
+							tokenRequest.Status.Token = "service-account-token"
+							return nil
+						},
+					}).
+					Build(),
+				Log:                   ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme:                scheme.Scheme,
+				Auth:                  fakeAuth,
+				TokenExchangeAudience: "api://AzureADTokenExchangeUSGov",
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientAssertion(gomock.Any(), "00000000-0000-0000-0000-000000000002",
+				"00000000-0000-0000-0000-000000000001", "service-account-token", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenRequests).To(Equal([]string{"default/puller"}))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.Identity).To(Equal("00000000-0000-0000-0000-000000000001"))
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "test-msi-acrpull-secret"}, &pullSecret)).To(Succeed())
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.ManagedIdentityAnnotation, "00000000-0000-0000-0000-000000000001"))
+			mockCtrl.Finish()
+		})
+
+		It("Should report a workload identity without a tenant", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity: &msiacrpullv1.Identity{
+						Type:     msiacrpullv1.IdentityTypeWorkload,
+						Workload: &msiacrpullv1.WorkloadIdentity{ClientID: "00000000-0000-0000-0000-000000000001"},
+					},
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).To(MatchError(ContainSubstring("workload identity has no tenant")))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.Error).To(ContainSubstring("workload identity has no tenant"))
+			mockCtrl.Finish()
+		})
+
+		It("Should acquire the token of a service principal with its client secret", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity: &msiacrpullv1.Identity{
+						Type: msiacrpullv1.IdentityTypeServicePrincipal,
+						ServicePrincipal: &msiacrpullv1.ServicePrincipalIdentity{
+							TenantID:        "00000000-0000-0000-0000-000000000002",
+							ClientID:        "00000000-0000-0000-0000-000000000003",
+							ClientSecretRef: msiacrpullv1.SecretKeySelector{Name: "sp-credentials"},
+						},
+					},
+				},
+			}
+			clientSecret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "sp-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"clientSecret": []byte("s3cret")},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: defaultServiceAccountName, Namespace: "default"},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, clientSecret, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientSecret(gomock.Any(), "00000000-0000-0000-0000-000000000002",
+				"00000000-0000-0000-0000-000000000003", "s3cret", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+
+			// the key of the client secret must exist
+			clientSecret.Data = map[string][]byte{"password": []byte("s3cret")}
+			Expect(reconciler.Update(context.Background(), clientSecret)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).To(MatchError(ContainSubstring("secret sp-credentials has no client secret in key clientSecret")))
+			mockCtrl.Finish()
+		})
+
+		It("Should not leak tokens into the status, logs or spans", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "testClientID"},
+				},
+			}
+			var logs strings.Builder
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding).
+					WithStatusSubresource(acrBinding).
+					Build(),
+				Log: redact.Logger(funcr.New(func(prefix, args string) {
+					logs.WriteString(prefix + " " + args + "\n")
+				}, funcr.Options{})),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			leaked, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientID(gomock.Any(), "testClientID", "test.azurecr.io").
+				Return(types.AccessToken(""), fmt.Errorf(`failed to read token exchange response: unexpected EOF. response: {"refresh_token":"%s"`, leaked))
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.Error).To(ContainSubstring(redact.Placeholder))
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+
+			var spanText strings.Builder
+			for _, span := range recorder.Ended() {
+				spanText.WriteString(span.Status().Description)
+				for _, event := range span.Events() {
+					for _, attr := range event.Attributes {
+						spanText.WriteString(attr.Value.Emit())
+					}
+				}
+			}
+			Expect(spanText.String()).To(ContainSubstring(redact.Placeholder))
+			Expect(logs.String()).To(ContainSubstring(redact.Placeholder))
+
+			signature := string(leaked)[strings.LastIndex(string(leaked), ".")+1:]
+			for _, text := range []string{updated.Status.Error, ready.Message, spanText.String(), logs.String()} {
+				Expect(text).NotTo(ContainSubstring(signature))
+			}
+			mockCtrl.Finish()
+		})
+
+		It("Should apply the secret template and stamp metadata", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "testClientID"},
+					SecretTemplate: &msiacrpullv1.SecretTemplate{
+						Name:        "team-pull-secret",
+						Labels:      map[string]string{"backup": "true", msiacrpullv1.ManagedByLabel: "someone-else"},
+						Annotations: map[string]string{"team": "a"},
+					},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			exp := time.Now().Add(3 * time.Hour).Truncate(time.Second)
+			acrToken, err := getTestToken(exp.Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientID(gomock.Any(), "testClientID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "team-pull-secret"},
+				&pullSecret)).To(Succeed())
+			Expect(pullSecret.Labels).To(Equal(map[string]string{
+				"backup":                    "true",
+				msiacrpullv1.ManagedByLabel: msiacrpullv1.ManagedByValue,
+			}))
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue("team", "a"))
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.AcrServerAnnotation, "test.azurecr.io"))
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.ManagedIdentityAnnotation, "testClientID"))
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.TokenExpiryAnnotation, exp.UTC().Format(time.RFC3339)))
+			Expect(pullSecret.Annotations).To(HaveKey(msiacrpullv1.LastRefreshAnnotation))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			updated.Spec.SecretTemplate = nil
+			Expect(reconciler.Update(context.Background(), &updated)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var pullSecrets v1.SecretList
+			Expect(reconciler.List(context.Background(), &pullSecrets)).To(Succeed())
+			Expect(pullSecrets.Items).To(HaveLen(1))
+			Expect(pullSecrets.Items[0].Name).To(Equal(getPullSecretName("test")))
+
+			var updatedServiceAccount v1.ServiceAccount
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: defaultServiceAccountName},
+				&updatedServiceAccount)).To(Succeed())
+			Expect(updatedServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: getPullSecretName("test")}}))
+			mockCtrl.Finish()
+		})
+
+		It("Should maintain only its own entries in an existing secret", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:          "test.azurecr.io",
+					Identity:           &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					ExistingSecretName: "shared-pull-secret",
+				},
+			}
+			sharedSecret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "shared-pull-secret",
+					Namespace: "default",
+				},
+				Type: v1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					v1.DockerConfigJsonKey: []byte(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}}}`),
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, sharedSecret, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(1)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			secretName := k8stypes.NamespacedName{Namespace: "default", Name: "shared-pull-secret"}
+			var secret v1.Secret
+			Expect(reconciler.Get(context.Background(), secretName, &secret)).To(Succeed())
+			Expect(secret.OwnerReferences).To(BeEmpty())
+			Expect(secret.Annotations).To(HaveKeyWithValue(managedHostsAnnotation, `{"test":["test.azurecr.io"]}`))
+			Expect(string(secret.Data[v1.DockerConfigJsonKey])).To(ContainSubstring(`"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}`))
+			Expect(string(secret.Data[v1.DockerConfigJsonKey])).To(ContainSubstring(`"test.azurecr.io":{`))
+
+			var updatedServiceAccount v1.ServiceAccount
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: defaultServiceAccountName},
+				&updatedServiceAccount)).To(Succeed())
+			Expect(updatedServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "shared-pull-secret"}}))
+
+			var pullSecrets v1.SecretList
+			Expect(reconciler.List(context.Background(), &pullSecrets)).To(Succeed())
+			Expect(pullSecrets.Items).To(HaveLen(1))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(reconciler.Delete(context.Background(), &updated)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Get(context.Background(), secretName, &secret)).To(Succeed())
+			Expect(secret.Annotations).NotTo(HaveKey(managedHostsAnnotation))
+			Expect(string(secret.Data[v1.DockerConfigJsonKey])).To(Equal(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}}}`))
+
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: defaultServiceAccountName},
+				&updatedServiceAccount)).To(Succeed())
+			Expect(updatedServiceAccount.ImagePullSecrets).To(BeEmpty())
+			mockCtrl.Finish()
+		})
+
+		It("Should clean up the previous existing secret when the binding targets another one", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:          "test.azurecr.io",
+					Identity:           &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					ExistingSecretName: "old-pull-secret",
+				},
+			}
+			newSharedSecret := func(name string) *v1.Secret {
+				return &v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+					Type:       v1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						v1.DockerConfigJsonKey: []byte(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}}}`),
+					},
+				}
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, newSharedSecret("old-pull-secret"), newSharedSecret("new-pull-secret"), serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.ExistingSecretName).To(Equal("old-pull-secret"))
+
+			updated.Spec.ExistingSecretName = "new-pull-secret"
+			Expect(reconciler.Update(context.Background(), &updated)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.ExistingSecretName).To(Equal("new-pull-secret"))
+
+			var secret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "old-pull-secret"}, &secret)).To(Succeed())
+			Expect(secret.Annotations).NotTo(HaveKey(managedHostsAnnotation))
+			Expect(string(secret.Data[v1.DockerConfigJsonKey])).To(Equal(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}}}`))
+
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "new-pull-secret"}, &secret)).To(Succeed())
+			Expect(secret.Annotations).To(HaveKeyWithValue(managedHostsAnnotation, `{"test":["test.azurecr.io"]}`))
+
+			var updatedServiceAccount v1.ServiceAccount
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: defaultServiceAccountName},
+				&updatedServiceAccount)).To(Succeed())
+			Expect(updatedServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "new-pull-secret"}}))
+			mockCtrl.Finish()
+		})
+
+		It("Should mirror the pull secret into the selected namespaces", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					Mirror: &msiacrpullv1.SecretMirror{
+						NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+					},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			namespaces := []client.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "a"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app1", Labels: map[string]string{"team": "a"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app2", Labels: map[string]string{"team": "a"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "b"}}},
+			}
+			conflicting := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-msi-acrpull-secret", Namespace: "app2"},
+				Type:       v1.SecretTypeDockerConfigJson,
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(append(namespaces, acrBinding, serviceAccount, conflicting)...).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:                    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme:                 scheme.Scheme,
+				Auth:                   fakeAuth,
+				MirrorSourceNamespaces: []string{"default"},
+				MirrorTargetNamespaces: []string{"app1", "app2", "other"},
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var mirrored v1.Secret
+			mirroredName := k8stypes.NamespacedName{Namespace: "app1", Name: "test-msi-acrpull-secret"}
+			Expect(reconciler.Get(context.Background(), mirroredName, &mirrored)).To(Succeed())
+			Expect(mirrored.OwnerReferences).To(BeEmpty())
+			Expect(mirrored.Labels).To(HaveKeyWithValue(msiacrpullv1.ManagedByLabel, msiacrpullv1.ManagedByValue))
+			Expect(mirrored.Annotations).To(HaveKeyWithValue(msiacrpullv1.MirrorSourceAnnotation, "default/test"))
+			Expect(string(mirrored.Data[v1.DockerConfigJsonKey])).To(ContainSubstring(string(acrToken)))
+
+			var secret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "app2", Name: "test-msi-acrpull-secret"}, &secret)).To(Succeed())
+			Expect(secret.Data).To(BeEmpty())
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.MirroredNamespaces).To(Equal([]string{"app1"}))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionSecretsMirrored)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonMirrorConflict))
+			Expect(condition.Message).To(ContainSubstring("app2"))
+
+			updated.Spec.Mirror.NamespaceSelector = metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+			Expect(reconciler.Update(context.Background(), &updated)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = reconciler.Get(context.Background(), mirroredName, &mirrored)
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "other", Name: "test-msi-acrpull-secret"}, &mirrored)).To(Succeed())
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.MirroredNamespaces).To(Equal([]string{"other"}))
+			condition = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionSecretsMirrored)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+
+			Expect(reconciler.Delete(context.Background(), &updated)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var copies v1.SecretList
+			Expect(reconciler.List(context.Background(), &copies, client.MatchingFields{mirrorSourceKey: "default/test"})).To(Succeed())
+			Expect(copies.Items).To(BeEmpty())
+			mockCtrl.Finish()
+		})
+
+		It("Should only mirror the pull secret between the namespaces allowed by the configuration", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					Mirror: &msiacrpullv1.SecretMirror{
+						NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+					},
+				},
+			}
+			namespaces := []client.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app1", Labels: map[string]string{"team": "a"}}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"team": "a"}}},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(append(namespaces, acrBinding)...).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var copies v1.SecretList
+			Expect(reconciler.List(context.Background(), &copies, client.MatchingFields{mirrorSourceKey: "default/test"})).To(Succeed())
+			Expect(copies.Items).To(BeEmpty())
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			condition := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionSecretsMirrored)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonMirrorNotAllowed))
+			Expect(condition.Message).To(ContainSubstring("from namespace default"))
+
+			reconciler.MirrorSourceNamespaces = []string{"default"}
+			reconciler.MirrorTargetNamespaces = []string{"app1"}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.List(context.Background(), &copies, client.MatchingFields{mirrorSourceKey: "default/test"})).To(Succeed())
+			Expect(copies.Items).To(HaveLen(1))
+			Expect(copies.Items[0].Namespace).To(Equal("app1"))
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.MirroredNamespaces).To(Equal([]string{"app1"}))
+			condition = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionSecretsMirrored)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonMirrorNotAllowed))
+			Expect(condition.Message).To(ContainSubstring("into namespaces: kube-system"))
+			mockCtrl.Finish()
+		})
+
+		It("Should unbind the service accounts it bound before a spec change and on deletion", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:          "test.azurecr.io",
+					Identity:           &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					ServiceAccountName: "new",
+				},
+				Status: msiacrpullv1.AcrPullBindingStatus{
+					BoundServiceAccounts: []msiacrpullv1.BoundServiceAccount{
+						{Name: "old", PullSecretName: "test-msi-acrpull-secret"},
+						{Name: "gone", PullSecretName: "test-msi-acrpull-secret"},
+					},
+				},
+			}
+			oldServiceAccount := &v1.ServiceAccount{
+				ObjectMeta:       metav1.ObjectMeta{Name: "old", Namespace: "default"},
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "other"}, {Name: "test-msi-acrpull-secret"}},
+			}
+			newServiceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, oldServiceAccount, newServiceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(oldServiceAccount), oldServiceAccount)).To(Succeed())
+			Expect(oldServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "other"}}))
+			Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(newServiceAccount), newServiceAccount)).To(Succeed())
+			Expect(newServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "test-msi-acrpull-secret"}}))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.BoundServiceAccounts).To(Equal([]msiacrpullv1.BoundServiceAccount{
+				{Name: "new", PullSecretName: "test-msi-acrpull-secret"},
+			}))
+
+			// the service account is changed and the binding deleted before the change is reconciled
+			updated.Spec.ServiceAccountName = "old"
+			Expect(reconciler.Update(context.Background(), &updated)).To(Succeed())
+			Expect(reconciler.Delete(context.Background(), &updated)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(newServiceAccount), newServiceAccount)).To(Succeed())
+			Expect(newServiceAccount.ImagePullSecrets).To(BeEmpty())
+			mockCtrl.Finish()
+		})
+
+		It("Should audit the credentials it issues, rotates and revokes", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					UID:        "0c6f7a4e-binding-uid",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "testClientID"},
+				},
+			}
+			sink := &recordingAuditSink{}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+				Audit:  sink,
+			}
+
+			exp := time.Now().Add(3 * time.Hour).Truncate(time.Second)
+			acrToken, err := getTestToken(exp.Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientID(gomock.Any(), "testClientID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "test-msi-acrpull-secret"}, &pullSecret)).To(Succeed())
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.TokenIDAnnotation, "bb8d6d3d-c7b0-4f96-a390-8738f730e8c6"))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(reconciler.Delete(context.Background(), &updated)).To(Succeed())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sink.records).To(HaveLen(3))
+			for idx, action := range []audit.Action{audit.ActionIssue, audit.ActionRotate, audit.ActionRevoke} {
+				record := sink.records[idx]
+				Expect(record.Action).To(Equal(action))
+				Expect(record.TokenID).To(Equal("bb8d6d3d-c7b0-4f96-a390-8738f730e8c6"))
+				Expect(record.Identity).To(Equal("testClientID"))
+				Expect(record.Registry).To(Equal("test.azurecr.io"))
+				Expect(record.ExpiresAt.Equal(exp)).To(BeTrue())
+				Expect(record.Binding).To(Equal(audit.Binding{Namespace: "default", Name: "test", UID: "0c6f7a4e-binding-uid"}))
+				Expect(record.ServiceAccount).To(Equal(defaultServiceAccountName))
+				Expect(record.Secret).To(Equal("test-msi-acrpull-secret"))
+			}
+			mockCtrl.Finish()
+		})
+
+		It("Should correlate the log lines, events and audit records of a reconcile", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "testClientID"},
+				},
+			}
+			var logs []string
+			sink := &recordingAuditSink{}
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log: funcr.New(func(prefix, args string) {
+					logs = append(logs, args)
+				}, funcr.Options{}),
+				Scheme:   scheme.Scheme,
+				Auth:     fakeAuth,
+				Audit:    sink,
+				Recorder: recorder,
+			}
+
+			exp := time.Now().Add(3 * time.Hour)
+			acrToken, err := getTestToken(exp.Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithClientID(gomock.Any(), "testClientID", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sink.records).To(HaveLen(1))
+			id := sink.records[0].CorrelationID
+			Expect(id).NotTo(BeEmpty())
+
+			Expect(recorder.Events).To(Receive(And(
+				HavePrefix("Normal CredentialIssued Issued a pull credential for test.azurecr.io in secret test-msi-acrpull-secret"),
+				ContainSubstring(msiacrpullv1.CorrelationIDAnnotation+":"+id),
+			)))
+
+			Expect(logs).To(ContainElement(And(
+				ContainSubstring(`"msg"="Issued pull credential"`),
+				ContainSubstring(`"binding"="test"`),
+				ContainSubstring(`"namespace"="default"`),
+				ContainSubstring(`"reconcileID"="`+id+`"`),
+				ContainSubstring(`"registry"="test.azurecr.io"`),
+				ContainSubstring(`"identityHash"="`+authorizer.IdentityHash("testClientID")+`"`),
+				ContainSubstring(`"tokenExpiry"="`+exp.UTC().Format(time.RFC3339)+`"`),
+			)))
+			// routine messages are only logged at debug verbosity
+			Expect(logs).NotTo(ContainElement(ContainSubstring("Reconciled AcrPullBinding")))
+			mockCtrl.Finish()
+		})
+
+		It("Should adopt a pull secret that lost its owner reference", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
 
-var _ = Describe("AcrPullBinding Controller Tests", func() {
-	Context("Reconcile", func() {
-		It("Should not return error when the acr pull binding is not found", func() {
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					UID:        "restored",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			orphan := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-msi-acrpull-secret",
+					Namespace: "default",
+					Labels:    map[string]string{msiacrpullv1.ManagedByLabel: msiacrpullv1.ManagedByValue},
+				},
+				Type: v1.SecretTypeDockerConfigJson,
+			}
 			reconciler := &AcrPullBindingReconciler{
 				Client: fake.NewClientBuilder().
 					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount, orphan).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
 					Build(),
 				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
 				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
 			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil)
+
 			req := ctrl.Request{
 				NamespacedName: k8stypes.NamespacedName{
 					Namespace: "default",
+					Name:      "test",
 				},
 			}
-			_, err := reconciler.Reconcile(context.Background(), req)
-			Expect(err).To(BeNil())
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var pullSecrets v1.SecretList
+			Expect(reconciler.List(context.Background(), &pullSecrets)).To(Succeed())
+			Expect(pullSecrets.Items).To(HaveLen(1))
+			owner := metav1.GetControllerOf(&pullSecrets.Items[0])
+			Expect(owner).NotTo(BeNil())
+			Expect(owner.UID).To(Equal(k8stypes.UID("restored")))
+			Expect(pullSecrets.Items[0].Data).To(HaveKey(v1.DockerConfigJsonKey))
+			mockCtrl.Finish()
 		})
 
-		It("Should use defaults when no parameters defined", func() {
+		It("Should not adopt an unrelated secret of the pull secret name", func() {
 			mockCtrl := gomock.NewController(GinkgoT())
 			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
 
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:      "test.azurecr.io",
+					Identity:       &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					SecretTemplate: &msiacrpullv1.SecretTemplate{Name: "app-tls"},
+				},
+			}
+			unrelated := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-tls", Namespace: "default"},
+				Type:       v1.SecretTypeTLS,
+				Data: map[string][]byte{
+					v1.TLSCertKey:       []byte("cert"),
+					v1.TLSPrivateKeyKey: []byte("key"),
+				},
+			}
 			reconciler := &AcrPullBindingReconciler{
 				Client: fake.NewClientBuilder().
 					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, unrelated).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
 					Build(),
-				Log:                              ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
-				Scheme:                           scheme.Scheme,
-				Auth:                             fakeAuth,
-				DefaultManagedIdentityResourceID: "defaultResourceID",
-				DefaultACRServer:                 "DefaultACRServer",
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
 			}
-			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(
-				gomock.Eq(reconciler.DefaultManagedIdentityResourceID),
-				gomock.Eq(reconciler.DefaultACRServer)).Times(1)
 
-			acrBinding := &msiacrpullv1beta1.AcrPullBinding{
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+
+			var secret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "app-tls"}, &secret)).To(Succeed())
+			Expect(secret.Type).To(Equal(v1.SecretTypeTLS))
+			Expect(secret.OwnerReferences).To(BeEmpty())
+			Expect(secret.Data).To(Equal(unrelated.Data))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(msiacrpullv1.ReasonSecretConflict))
+			Expect(ready.Message).To(ContainSubstring("secret app-tls already exists"))
+			mockCtrl.Finish()
+		})
+
+		It("Should refresh an unlabelled pull secret controlled through v1beta1", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:       "test",
 					Namespace:  "default",
-					Finalizers: []string{},
+					UID:        "upgraded",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
 				},
 			}
-			reconciler.Create(context.TODO(), acrBinding)
+			controlled := true
+			// written by a release that stored bindings as v1beta1 and did not label its secrets
+			pullSecret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-msi-acrpull-secret",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "msi-acrpull.microsoft.com/v1beta1",
+						Kind:       "AcrPullBinding",
+						Name:       "test",
+						UID:        "upgraded",
+						Controller: &controlled,
+					}},
+				},
+				Type: v1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{v1.DockerConfigJsonKey: []byte("{}")},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, pullSecret, &v1.ServiceAccount{
+						ObjectMeta: metav1.ObjectMeta{Name: defaultServiceAccountName, Namespace: "default"},
+					}).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+			Expect(pullSecretOwner(pullSecret)).To(Equal([]string{"test"}))
+			Expect(adoptable(pullSecret, acrBinding, v1.SecretTypeDockerConfigJson)).To(BeTrue())
 
-			req := ctrl.Request{
-				NamespacedName: k8stypes.NamespacedName{
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var secret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "test-msi-acrpull-secret"}, &secret)).To(Succeed())
+			Expect(secret.Labels).To(HaveKeyWithValue(msiacrpullv1.ManagedByLabel, msiacrpullv1.ManagedByValue))
+			Expect(secret.Data[v1.DockerConfigJsonKey]).NotTo(Equal([]byte("{}")))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+			mockCtrl.Finish()
+		})
+
+		It("Should delete a stale pull secret controlled through v1beta1", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					UID:        "upgraded",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:      "test.azurecr.io",
+					Identity:       &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					SecretTemplate: &msiacrpullv1.SecretTemplate{Name: "renamed"},
+				},
+			}
+			controlled := true
+			stale := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-msi-acrpull-secret",
 					Namespace: "default",
-					Name:      "test",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "msi-acrpull.microsoft.com/v1beta1",
+						Kind:       "AcrPullBinding",
+						Name:       "test",
+						UID:        "upgraded",
+						Controller: &controlled,
+					}},
 				},
+				Type: v1.SecretTypeDockerConfigJson,
 			}
-			reconciler.Reconcile(context.Background(), req)
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta:       metav1.ObjectMeta{Name: defaultServiceAccountName, Namespace: "default"},
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "test-msi-acrpull-secret"}},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, stale, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var pullSecrets v1.SecretList
+			Expect(reconciler.List(context.Background(), &pullSecrets)).To(Succeed())
+			Expect(pullSecrets.Items).To(HaveLen(1))
+			Expect(pullSecrets.Items[0].Name).To(Equal("renamed"))
+
+			var updatedServiceAccount v1.ServiceAccount
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: defaultServiceAccountName}, &updatedServiceAccount)).To(Succeed())
+			Expect(updatedServiceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "renamed"}}))
 			mockCtrl.Finish()
 		})
 
+		It("Should replace the owner reference of a restored pull secret", func() {
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "restored"},
+			}
+			controlled := true
+			pullSecret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-msi-acrpull-secret",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: msiacrpullv1.GroupVersion.String(),
+						Kind:       "AcrPullBinding",
+						Name:       "test",
+						UID:        "original",
+						Controller: &controlled,
+					}},
+				},
+			}
+			adopted, err := adoptPullSecret(pullSecret, acrBinding, scheme.Scheme)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adopted).To(BeTrue())
+			Expect(pullSecret.OwnerReferences).To(HaveLen(1))
+			Expect(pullSecret.OwnerReferences[0].UID).To(Equal(k8stypes.UID("restored")))
+
+			pullSecret.OwnerReferences[0].Kind = "Deployment"
+			_, err = adoptPullSecret(pullSecret, &msiacrpullv1.AcrPullBinding{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "other"}}, scheme.Scheme)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("controlled by Deployment"))
+		})
+
+		It("Should return error when the existing secret has an unsupported type", func() {
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "opaque"},
+				Type:       v1.SecretTypeOpaque,
+			}
+			err := mergeExistingSecret(secret, "test", authorizer.NewDockerConfigBuilder(), nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported type"))
+		})
+
 		It("Should return error when getting acr pull binding returns error other than NotFound", func() {
 			reconciler := &AcrPullBindingReconciler{
 				Client: &errorFakeCtrlRuntimeClient{fake.NewClientBuilder().
@@ -119,23 +1500,292 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 		})
 	})
 
-	Context("getTokenRefreshDuration", func() {
-		It("Should return 0 for negative durations", func() {
-			token, err := getTestToken(time.Now().Add(-time.Hour).Unix())
+	Context("registryCoverage", func() {
+		It("Should report the workloads and registries covered by the bindings", func() {
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:   "test.azurecr.io",
+					HostAliases: []string{"test.westeurope.data.azurecr.io"},
+				},
+			}
+			other := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+					InitContainers: []v1.Container{{Name: "init", Image: "test.westeurope.data.azurecr.io/init:1.0"}},
+					Containers:     []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}, {Name: "proxy", Image: "nginx"}},
+				}}},
+			}
+			controlled := true
+			ownedPod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app-1234",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-12", UID: "uid", Controller: &controlled},
+					},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}}},
+			}
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+				Spec: v1.PodSpec{Containers: []v1.Container{
+					{Name: "debug", Image: "default.azurecr.io/debug:1.0"},
+					{Name: "tools", Image: "old.azurecr.io/tools:1.0"},
+				}},
+			}
+			c := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(acrBinding, other, deployment, ownedPod, pod).
+				Build()
+			reconciler := &AcrPullBindingReconciler{
+				Client:           c,
+				CoverageReader:   c,
+				Log:              ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme:           scheme.Scheme,
+				DefaultACRServer: "default.azurecr.io",
+			}
+
+			coverage, err := reconciler.registryCoverage(context.Background(), acrBinding)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(coverage.CoveredWorkloads).To(Equal([]msiacrpullv1.WorkloadImages{{
+				Kind:   "Deployment",
+				Name:   "app",
+				Images: []string{"test.westeurope.data.azurecr.io/init:1.0", "test.azurecr.io/app:1.0"},
+			}}))
+			Expect(coverage.UncoveredRegistries).To(Equal([]string{"docker.io", "old.azurecr.io"}))
+			Expect(coverage.UncoveredWorkloads).To(Equal([]msiacrpullv1.WorkloadImages{
+				{Kind: "Deployment", Name: "app", Images: []string{"nginx"}},
+				{Kind: "Pod", Name: "debug", Images: []string{"old.azurecr.io/tools:1.0"}},
+			}))
+			Expect(coverage.Truncated).To(BeFalse())
+			Expect(coverage.AuditTime).NotTo(BeNil())
+		})
+
+		It("Should return the registry host of an image", func() {
+			Expect(ImageRegistry("test.azurecr.io/app:1.0")).To(Equal("test.azurecr.io"))
+			Expect(ImageRegistry("Test.azurecr.io/team/app@sha256:abc")).To(Equal("test.azurecr.io"))
+			Expect(ImageRegistry("localhost:5000/app")).To(Equal("localhost:5000"))
+			Expect(ImageRegistry("localhost/app")).To(Equal("localhost"))
+			Expect(ImageRegistry("library/nginx")).To(Equal(dockerHubRegistry))
+			Expect(ImageRegistry("nginx")).To(Equal(dockerHubRegistry))
+			Expect(ImageRegistry("")).To(BeEmpty())
+		})
+	})
+
+	Context("buildPullSecretData", func() {
+		It("Should build the secret in the requested format", func() {
+			spec := msiacrpullv1.AcrPullBindingSpec{
+				HostAliases: []string{"test.westeurope.data.azurecr.io"},
+			}
+			secretType, data, err := buildPullSecretData(spec, "test.azurecr.io", "acr-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretType).To(Equal(v1.SecretTypeDockerConfigJson))
+			Expect(string(data[v1.DockerConfigJsonKey])).To(ContainSubstring(`"test.westeurope.data.azurecr.io":{`))
+			Expect(string(data[v1.DockerConfigJsonKey])).To(ContainSubstring(`"password":"acr-token"`))
+
+			spec.SecretFormat = msiacrpullv1.SecretFormatDockerConfigJSONIdentityToken
+			secretType, data, err = buildPullSecretData(spec, "test.azurecr.io", "acr-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretType).To(Equal(v1.SecretTypeDockerConfigJson))
+			Expect(string(data[v1.DockerConfigJsonKey])).To(ContainSubstring(`"identitytoken":"acr-token"`))
+
+			spec.SecretFormat = msiacrpullv1.SecretFormatDockerCfg
+			secretType, data, err = buildPullSecretData(spec, "test.azurecr.io", "acr-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretType).To(Equal(v1.SecretTypeDockercfg))
+			Expect(data).To(HaveKey(v1.DockerConfigKey))
+			Expect(string(data[v1.DockerConfigKey])).NotTo(ContainSubstring("auths"))
+
+			spec.SecretFormat = "Unknown"
+			_, _, err = buildPullSecretData(spec, "test.azurecr.io", "acr-token")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("validateACRToken", func() {
+		It("Should reject a token for another registry or tenant", func() {
+			reconciler := &AcrPullBindingReconciler{}
+			acrToken, err := getTestToken(time.Now().Add(time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := reconciler.validateACRToken(acrToken, "test.azurecr.io")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Audience).To(ConsistOf("test.azurecr.io"))
+
+			_, err = reconciler.validateACRToken(acrToken, "other.azurecr.io")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not match other.azurecr.io"))
+
+			reconciler.TenantID = "1b4e67bf-39b2-4eb1-bec3-5099dd556b07"
+			_, err = reconciler.validateACRToken(acrToken, "test.azurecr.io")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should reject a token that is not valid yet beyond the clock skew", func() {
+			now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			fakeClock := clocktesting.NewFakePassiveClock(now)
+			reconciler := &AcrPullBindingReconciler{Clock: fakeClock}
+			acrToken, err := getTestTokenWithNotBefore(now.Add(3*time.Hour).Unix(), now.Add(types.DefaultClockSkew+time.Minute).Unix())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = reconciler.validateACRToken(acrToken, "test.azurecr.io")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not valid before"))
+
+			fakeClock.SetTime(now.Add(2 * time.Minute))
+			_, err = reconciler.validateACRToken(acrToken, "test.azurecr.io")
 			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("getTokenRefreshDuration", func() {
+		now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
 
-			refreshDuration := getTokenRefreshDuration(token)
+		It("Should return 0 for negative durations", func() {
+			refreshDuration := getTokenRefreshDuration(now.Add(-time.Hour), tokenRefreshBuffer, now)
 			Expect(int(refreshDuration)).To(Equal(0))
 		})
 
 		It("Should return positive duration when exp is outside refresh buffer", func() {
-			exp := time.Now().Add(tokenRefreshBuffer + time.Hour).Unix()
+			refreshDuration := getTokenRefreshDuration(now.Add(tokenRefreshBuffer+time.Hour), tokenRefreshBuffer, now)
+			Expect(refreshDuration).To(Equal(time.Hour))
+		})
+
+		It("Should return 0 when exp is inside the refresh buffer", func() {
+			Expect(getTokenRefreshDuration(now.Add(tokenRefreshBuffer-time.Minute), tokenRefreshBuffer, now)).To(BeZero())
+		})
+
+		It("Should schedule the refresh and record the refresh time with the clock of the reconciler", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+			fakeClock := clocktesting.NewFakePassiveClock(now)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: "test.azurecr.io",
+					Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+				},
+			}
+			serviceAccount := &v1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultServiceAccountName,
+					Namespace: "default",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, serviceAccount).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+				Clock:  fakeClock,
+			}
+
+			acrToken, err := getTestTokenWithNotBefore(now.Add(3*time.Hour).Unix(), now.Add(-time.Minute).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "test"}}
+			result, err := reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(3*time.Hour - tokenRefreshBuffer))
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.LastTokenRefreshTime.Time.Equal(now)).To(BeTrue())
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: getPullSecretName("test")}, &pullSecret)).To(Succeed())
+			Expect(pullSecret.Annotations).To(HaveKeyWithValue(msiacrpullv1.LastRefreshAnnotation, now.Format(time.RFC3339)))
+
+			// the same token reconciled after its refresh was due is refreshed immediately
+			fakeClock.SetTime(now.Add(3*time.Hour - tokenRefreshBuffer + time.Minute))
+			result, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+		})
+
+		It("Should wait for a service account that does not exist yet", func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			fakeAuth := mock_authorizer.NewMockInterface(mockCtrl)
+
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "default",
+					Finalizers: []string{msiAcrPullFinalizerName},
+				},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer:          "test.azurecr.io",
+					Identity:           &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "testResourceID"},
+					ServiceAccountName: "app",
+				},
+			}
+			reconciler := &AcrPullBindingReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(acrBinding, &msiacrpullv1.AcrPullBinding{
+						ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+					}).
+					WithStatusSubresource(acrBinding).
+					WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+					Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+				Scheme: scheme.Scheme,
+				Auth:   fakeAuth,
+			}
+
+			acrToken, err := getTestToken(time.Now().Add(3 * time.Hour).Unix())
+			Expect(err).ToNot(HaveOccurred())
+			fakeAuth.EXPECT().AcquireACRAccessTokenWithResourceID(gomock.Any(), "testResourceID", "test.azurecr.io").Return(acrToken, nil).Times(2)
+
+			req := ctrl.Request{
+				NamespacedName: k8stypes.NamespacedName{
+					Namespace: "default",
+					Name:      "test",
+				},
+			}
+			_, err = reconciler.Reconcile(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+
+			var updated msiacrpullv1.AcrPullBinding
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			Expect(updated.Status.Error).To(BeEmpty())
+			condition := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionServiceAccountBound)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonServiceAccountNotFound))
+
+			var pullSecret v1.Secret
+			Expect(reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "test-msi-acrpull-secret"}, &pullSecret)).To(Succeed())
 
-			token, err := getTestToken(exp)
+			serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+			Expect(reconciler.Create(context.Background(), serviceAccount)).To(Succeed())
+			Expect(reconciler.serviceAccountBindings(context.Background(), serviceAccount)).To(Equal([]reconcile.Request{req}))
+
+			_, err = reconciler.Reconcile(context.Background(), req)
 			Expect(err).ToNot(HaveOccurred())
 
-			refreshDuration := getTokenRefreshDuration(token)
-			Expect(refreshDuration > 0).To(BeTrue())
+			Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(serviceAccount), serviceAccount)).To(Succeed())
+			Expect(serviceAccount.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "test-msi-acrpull-secret"}}))
+			Expect(reconciler.Get(context.Background(), req.NamespacedName, &updated)).To(Succeed())
+			condition = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionServiceAccountBound)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			mockCtrl.Finish()
 		})
 	})
 
@@ -153,7 +1803,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 
 	Context("addFinalizer", func() {
 		It("Should add finalizer to acr pull binding", func() {
-			acrBinding := &msiacrpullv1beta1.AcrPullBinding{
+			acrBinding := &msiacrpullv1.AcrPullBinding{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:       "test",
 					Namespace:  "default",
@@ -164,6 +1814,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 				Client: fake.NewClientBuilder().
 					WithScheme(scheme.Scheme).
 					WithRuntimeObjects(acrBinding).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
 					Build(),
 				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
 				Scheme: scheme.Scheme,
@@ -180,7 +1831,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 	Context("removeFinalizer", func() {
 		It("Should remove finalizer from acr pull binding", func() {
 			serviceAccountName := "sa1"
-			acrBinding := &msiacrpullv1beta1.AcrPullBinding{
+			acrBinding := &msiacrpullv1.AcrPullBinding{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "test",
 					Namespace: "default",
@@ -199,6 +1850,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 				Client: fake.NewClientBuilder().
 					WithScheme(scheme.Scheme).
 					WithRuntimeObjects(acrBinding, serviceAccount).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
 					Build(),
 				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
 				Scheme: scheme.Scheme,
@@ -217,7 +1869,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 
 		It("Should remove finalizer from acr pull binding when service account doesn't exist", func() {
 			serviceAccountName := "sa1"
-			acrBinding := &msiacrpullv1beta1.AcrPullBinding{
+			acrBinding := &msiacrpullv1.AcrPullBinding{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "test",
 					Namespace: "default",
@@ -230,6 +1882,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 				Client: fake.NewClientBuilder().
 					WithScheme(scheme.Scheme).
 					WithRuntimeObjects(acrBinding).
+					WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
 					Build(),
 				Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
 				Scheme: scheme.Scheme,
@@ -260,7 +1913,7 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 
 			for _, testCase := range testCases {
 				serviceAccountName := testCase.serviceAccountName
-				acrBinding := &msiacrpullv1beta1.AcrPullBinding{
+				acrBinding := &msiacrpullv1.AcrPullBinding{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "test",
 						Namespace: "default",
@@ -290,8 +1943,10 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 						Namespace: "default",
 					},
 				}
-				err := reconciler.updateServiceAccount(ctx, acrBinding, req, serviceAccountName, log)
+				condition, err := reconciler.updateServiceAccount(ctx, acrBinding, req, serviceAccountName, log)
 				Expect(err).To(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonServiceAccountBound))
 
 				saNamespacedName := k8stypes.NamespacedName{
 					Name:      serviceAccountName,
@@ -365,16 +2020,88 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 		})
 	})
 
+	Context("status summary", func() {
+		It("Should shorten resource IDs to the name of the managed identity", func() {
+			Expect(identitySummary("", "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/my-acr-puller")).
+				To(Equal("my-acr-puller"))
+			Expect(identitySummary("client-id", "/subscriptions/sub")).To(Equal("client-id"))
+			Expect(identitySummary("", "")).To(BeEmpty())
+		})
+
+		It("Should list the target service account first", func() {
+			bound := []msiacrpullv1.BoundServiceAccount{{Name: "old-b"}, {Name: "target"}, {Name: "old-a"}}
+			Expect(serviceAccountsSummary("target", bound)).To(Equal("target,old-a,old-b"))
+			Expect(serviceAccountsSummary("target", nil)).To(Equal("target"))
+		})
+	})
+
 	Context("specOrDefaultTest", func() {
 		It("should deduplicate double slash", func() {
 			reconciler := &AcrPullBindingReconciler{}
-			spec := msiacrpullv1beta1.AcrPullBindingSpec{
-				ManagedIdentityResourceID: "/resourcegroup//doubleslash/singleslash/",
+			spec := msiacrpullv1.AcrPullBindingSpec{
+				Identity: &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeResourceID, ResourceID: "/resourcegroup//doubleslash/singleslash/"},
 			}
 			_, msiResourceId, _ := specOrDefault(reconciler, spec)
 			Expect(msiResourceId).To(Equal("/resourcegroup/doubleslash/singleslash"))
 		})
 	})
+	Context("ApplyConfiguration", func() {
+		It("Should update defaults and refresh buffer", func() {
+			reconciler := &AcrPullBindingReconciler{}
+			reconciler.ApplyConfiguration(&configv1alpha1.ControllerConfiguration{
+				Defaults: configv1alpha1.DefaultsConfiguration{
+					ACRServer:                 "configured.azurecr.io",
+					ManagedIdentityResourceID: "configuredResourceID",
+				},
+				Refresh: configv1alpha1.RefreshConfiguration{
+					TokenRefreshBuffer: metav1.Duration{Duration: time.Hour},
+				},
+			})
+
+			_, msiResourceID, acrServer := specOrDefault(reconciler, msiacrpullv1.AcrPullBindingSpec{})
+			Expect(msiResourceID).To(Equal("configuredResourceID"))
+			Expect(acrServer).To(Equal("configured.azurecr.io"))
+			Expect(reconciler.tokenRefreshBuffer()).To(Equal(time.Hour))
+		})
+	})
+
+	Context("namespaceFilter", func() {
+		It("Should filter objects by namespace", func() {
+			inNamespace := func(namespace string) client.Object {
+				return &msiacrpullv1.AcrPullBinding{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace}}
+			}
+
+			all := namespaceFilter(nil, []string{"kube-system"})
+			Expect(all.Generic(event.GenericEvent{Object: inNamespace("default")})).To(BeTrue())
+			Expect(all.Generic(event.GenericEvent{Object: inNamespace("kube-system")})).To(BeFalse())
+
+			some := namespaceFilter([]string{"team-a", "team-b"}, []string{"team-b"})
+			Expect(some.Generic(event.GenericEvent{Object: inNamespace("team-a")})).To(BeTrue())
+			Expect(some.Generic(event.GenericEvent{Object: inNamespace("team-b")})).To(BeFalse())
+			Expect(some.Generic(event.GenericEvent{Object: inNamespace("default")})).To(BeFalse())
+		})
+	})
+
+	Context("shardFilter", func() {
+		It("Should assign every namespace to exactly one shard", func() {
+			const count = 3
+			shards := []predicate.Predicate{shardFilter(count, 0), shardFilter(count, 1), shardFilter(count, 2)}
+			for _, namespace := range []string{"default", "team-a", "team-b", "team-c", "kube-system"} {
+				obj := &msiacrpullv1.AcrPullBinding{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace}}
+				admitted := 0
+				for _, shard := range shards {
+					if shard.Generic(event.GenericEvent{Object: obj}) {
+						admitted++
+					}
+				}
+				Expect(admitted).To(Equal(1), namespace)
+			}
+
+			unsharded := shardFilter(1, 0)
+			Expect(unsharded.Generic(event.GenericEvent{Object: &msiacrpullv1.AcrPullBinding{}})).To(BeTrue())
+		})
+	})
+
 	Context("getServiceAccountName", func() {
 		It("Should get service account name", func() {
 			Expect(getServiceAccountName("")).To(Equal(defaultServiceAccountName))
@@ -384,6 +2111,10 @@ var _ = Describe("AcrPullBinding Controller Tests", func() {
 })
 
 func getTestToken(exp int64) (types.AccessToken, error) {
+	return getTestTokenWithNotBefore(exp, time.Now().AddDate(0, 0, -1).Unix())
+}
+
+func getTestTokenWithNotBefore(exp, nbf int64) (types.AccessToken, error) {
 	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -398,7 +2129,7 @@ func getTestToken(exp int64) (types.AccessToken, error) {
 		},
 		"jti": "bb8d6d3d-c7b0-4f96-a390-8738f730e8c6",
 		"iss": "Azure Container Registry",
-		"nbf": time.Now().AddDate(0, 0, -1).Unix(),
+		"nbf": nbf,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
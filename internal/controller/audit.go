@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/internal/audit"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// recordCredential logs, records an event and writes an audit record for the credential just written
+// to the pull secret of the binding. The first credential of a binding is issued, later ones rotate it.
+func (r *AcrPullBindingReconciler) recordCredential(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	identity, acrServer string, claims *types.ACRClaims, serviceAccountName string, log logr.Logger) {
+	action := audit.ActionIssue
+	if acrBinding.Status.TokenExpirationTime != nil {
+		action = audit.ActionRotate
+	}
+	if action == audit.ActionIssue {
+		log.Info("Issued pull credential")
+		r.event(ctx, acrBinding, v1.EventTypeNormal, eventReasonCredentialIssued,
+			"Issued a pull credential for %s in secret %s", acrServer, PullSecretName(acrBinding))
+	} else {
+		log.V(LogLevelDebug).Info("Rotated pull credential")
+		r.event(ctx, acrBinding, v1.EventTypeNormal, eventReasonCredentialRotated,
+			"Rotated the pull credential for %s in secret %s", acrServer, PullSecretName(acrBinding))
+	}
+
+	if r.Audit == nil {
+		return
+	}
+	record := r.newAuditRecord(ctx, acrBinding, action, identity, acrServer, serviceAccountName)
+	record.SetClaims(claims)
+	r.writeAuditRecord(ctx, record, log)
+}
+
+// revocationRecord returns the record of the revocation of the credential of a binding being deleted,
+// or nil when the binding never had one. The token ID is read from the pull secret, which is deleted
+// along with the binding.
+func (r *AcrPullBindingReconciler) revocationRecord(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	serviceAccountName string) (*audit.Record, error) {
+	if r.Audit == nil || acrBinding.Status.TokenExpirationTime == nil {
+		return nil, nil
+	}
+
+	msiClientID, msiResourceID, acrServer := specOrDefault(r, acrBinding.Spec)
+	if acrBinding.Status.AcrServer != "" {
+		acrServer = acrBinding.Status.AcrServer
+	}
+	identity := msiClientID
+	if identity == "" {
+		identity = msiResourceID
+	}
+	record := r.newAuditRecord(ctx, acrBinding, audit.ActionRevoke, identity, acrServer, serviceAccountName)
+	exp := acrBinding.Status.TokenExpirationTime.UTC()
+	record.ExpiresAt = &exp
+
+	var pullSecret v1.Secret
+	err := r.Get(ctx, k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: record.Secret}, &pullSecret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	record.TokenID = pullSecret.Annotations[msiacrpullv1.TokenIDAnnotation]
+	return record, nil
+}
+
+func (r *AcrPullBindingReconciler) newAuditRecord(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	action audit.Action, identity, acrServer, serviceAccountName string) *audit.Record {
+	return &audit.Record{
+		Time:          r.now().UTC(),
+		Action:        action,
+		CorrelationID: correlationID(ctx),
+		Identity:      identity,
+		Registry:      acrServer,
+		Binding: audit.Binding{
+			Namespace: acrBinding.Namespace,
+			Name:      acrBinding.Name,
+			UID:       string(acrBinding.UID),
+		},
+		ServiceAccount: serviceAccountName,
+		Secret:         PullSecretName(acrBinding),
+	}
+}
+
+// writeAuditRecord writes record, if any, to the audit sink. A failure is logged rather than
+// returned: the credential is already in place, and retrying the reconcile would issue another one.
+func (r *AcrPullBindingReconciler) writeAuditRecord(ctx context.Context, record *audit.Record, log logr.Logger) {
+	if r.Audit == nil || record == nil {
+		return
+	}
+	if err := r.Audit.Write(ctx, record); err != nil {
+		log.Error(err, "Failed to write audit record", "action", record.Action, "jti", record.TokenID)
+	}
+}
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+// dockerHubRegistry is the registry of images that do not name one.
+const dockerHubRegistry = "docker.io"
+
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=list
+//+kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=list
+
+// ImageRegistry returns the registry host of an image reference. As in docker, the first path
+// component is only a host when it contains a dot or a port, or is localhost.
+func ImageRegistry(image string) string {
+	if image == "" {
+		return ""
+	}
+	host, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return dockerHubRegistry
+	}
+	return strings.ToLower(host)
+}
+
+// BindsRegistry reports whether the credential of acrBinding is valid for registry. Bindings
+// without an ACR server use defaultACRServer.
+func BindsRegistry(acrBinding *msiacrpullv1.AcrPullBinding, defaultACRServer, registry string) bool {
+	acrServer := acrBinding.Spec.AcrServer
+	if acrServer == "" {
+		acrServer = defaultACRServer
+	}
+	if strings.EqualFold(acrServer, registry) {
+		return true
+	}
+	for _, alias := range acrBinding.Spec.HostAliases {
+		if strings.EqualFold(alias, registry) {
+			return true
+		}
+	}
+	return false
+}
+
+// PodSpecImages returns the image references of all containers of spec.
+func PodSpecImages(spec *v1.PodSpec) []string {
+	var images []string
+	for _, container := range spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+	for _, container := range spec.EphemeralContainers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// listWorkloads returns the images of the workloads of namespace. Pods and jobs created by another
+// workload are left out, as their images are reported for their owner.
+func (r *AcrPullBindingReconciler) listWorkloads(ctx context.Context, namespace string) ([]msiacrpullv1.WorkloadImages, error) {
+	var workloads []msiacrpullv1.WorkloadImages
+	add := func(kind string, meta metav1.Object, spec *v1.PodSpec) {
+		if metav1.GetControllerOf(meta) != nil {
+			return
+		}
+		workloads = append(workloads, msiacrpullv1.WorkloadImages{Kind: kind, Name: meta.GetName(), Images: PodSpecImages(spec)})
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := r.CoverageReader.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range deployments.Items {
+		add("Deployment", &deployments.Items[idx], &deployments.Items[idx].Spec.Template.Spec)
+	}
+	var statefulSets appsv1.StatefulSetList
+	if err := r.CoverageReader.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range statefulSets.Items {
+		add("StatefulSet", &statefulSets.Items[idx], &statefulSets.Items[idx].Spec.Template.Spec)
+	}
+	var daemonSets appsv1.DaemonSetList
+	if err := r.CoverageReader.List(ctx, &daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range daemonSets.Items {
+		add("DaemonSet", &daemonSets.Items[idx], &daemonSets.Items[idx].Spec.Template.Spec)
+	}
+	var cronJobs batchv1.CronJobList
+	if err := r.CoverageReader.List(ctx, &cronJobs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range cronJobs.Items {
+		add("CronJob", &cronJobs.Items[idx], &cronJobs.Items[idx].Spec.JobTemplate.Spec.Template.Spec)
+	}
+	var jobs batchv1.JobList
+	if err := r.CoverageReader.List(ctx, &jobs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range jobs.Items {
+		add("Job", &jobs.Items[idx], &jobs.Items[idx].Spec.Template.Spec)
+	}
+	var pods v1.PodList
+	if err := r.CoverageReader.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for idx := range pods.Items {
+		add("Pod", &pods.Items[idx], &pods.Items[idx].Spec)
+	}
+	return workloads, nil
+}
+
+// registryCoverage matches the images of the workloads in the namespace of acrBinding against the
+// registries of acrBinding and of the other bindings of the namespace.
+func (r *AcrPullBindingReconciler) registryCoverage(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding) (*msiacrpullv1.RegistryCoverage, error) {
+	workloads, err := r.listWorkloads(ctx, acrBinding.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := r.List(ctx, &acrBindings, client.InNamespace(acrBinding.Namespace)); err != nil {
+		return nil, err
+	}
+	_, _, defaultACRServer := specOrDefault(r, msiacrpullv1.AcrPullBindingSpec{})
+
+	coverage := &msiacrpullv1.RegistryCoverage{AuditTime: &metav1.Time{Time: r.now().UTC()}}
+	uncoveredRegistries := map[string]bool{}
+	for _, workload := range workloads {
+		var covered, uncovered []string
+		for _, image := range workload.Images {
+			registry := ImageRegistry(image)
+			if registry == "" {
+				continue
+			}
+			if BindsRegistry(acrBinding, defaultACRServer, registry) {
+				covered = append(covered, image)
+				continue
+			}
+			if !anyBindsRegistry(acrBindings.Items, defaultACRServer, registry) {
+				uncovered = append(uncovered, image)
+				uncoveredRegistries[registry] = true
+			}
+		}
+		if len(covered) > 0 {
+			coverage.CoveredWorkloads = append(coverage.CoveredWorkloads,
+				msiacrpullv1.WorkloadImages{Kind: workload.Kind, Name: workload.Name, Images: covered})
+		}
+		if len(uncovered) > 0 {
+			coverage.UncoveredWorkloads = append(coverage.UncoveredWorkloads,
+				msiacrpullv1.WorkloadImages{Kind: workload.Kind, Name: workload.Name, Images: uncovered})
+		}
+	}
+	for registry := range uncoveredRegistries {
+		coverage.UncoveredRegistries = append(coverage.UncoveredRegistries, registry)
+	}
+	sort.Strings(coverage.UncoveredRegistries)
+	sortWorkloads(coverage.CoveredWorkloads)
+	sortWorkloads(coverage.UncoveredWorkloads)
+
+	limit := msiacrpullv1.MaxCoverageEntries
+	if len(coverage.CoveredWorkloads) > limit || len(coverage.UncoveredWorkloads) > limit || len(coverage.UncoveredRegistries) > limit {
+		coverage.Truncated = true
+		coverage.CoveredWorkloads = truncate(coverage.CoveredWorkloads, limit)
+		coverage.UncoveredWorkloads = truncate(coverage.UncoveredWorkloads, limit)
+		coverage.UncoveredRegistries = truncate(coverage.UncoveredRegistries, limit)
+	}
+	return coverage, nil
+}
+
+func anyBindsRegistry(acrBindings []msiacrpullv1.AcrPullBinding, defaultACRServer, registry string) bool {
+	for idx := range acrBindings {
+		if acrBindings[idx].DeletionTimestamp.IsZero() && BindsRegistry(&acrBindings[idx], defaultACRServer, registry) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortWorkloads(workloads []msiacrpullv1.WorkloadImages) {
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+}
+
+func truncate[T any](items []T, limit int) []T {
+	if len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
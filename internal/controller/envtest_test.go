@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+	fakeauthorizer "github.com/Azure/msi-acrpull/pkg/authorizer/fake"
+)
+
+const envtestACR = "envtest.azurecr.io"
+
+// These specs run the manager against the API server started by the suite and the fake instance
+// metadata service and registry, so finalizers, garbage collection relevant owner references, the
+// status subresource and field indexes behave as in a cluster.
+var _ = Describe("AcrPullBinding Controller Envtest", Ordered, Label("envtest"), func() {
+	var (
+		server   *fakeauthorizer.Server
+		identity fakeauthorizer.Identity
+		cancel   context.CancelFunc
+	)
+
+	BeforeAll(func() {
+		if testEnv == nil {
+			Skip("KUBEBUILDER_ASSETS is not set, skipping the envtest specs")
+		}
+
+		server = fakeauthorizer.NewServer()
+		identity = server.AddIdentity("envtest-mi")
+		server.AddRegistry(envtestACR)
+		server.AssignRole(identity.ClientID, envtestACR, fakeauthorizer.RoleAcrPull)
+
+		controllerConfig := &configv1alpha1.ControllerConfiguration{}
+		configv1alpha1.SetDefaults(controllerConfig)
+		controllerConfig.Cloud.MetadataEndpoint = server.MetadataEndpoint()
+		// credentials are rotated every few seconds
+		server.RefreshTokenLifetime = controllerConfig.Refresh.TokenRefreshBuffer.Duration + 3*time.Second
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:  scheme.Scheme,
+			Metrics: metricsserver.Options{BindAddress: "0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		auth := authorizer.NewAuthorizerWithOptions(authorizer.Options{
+			MetadataEndpoint: controllerConfig.Cloud.MetadataEndpoint,
+			ARMResource:      controllerConfig.Cloud.ARMResource,
+			RPS:              100,
+			Burst:            100,
+			HTTPClient:       server.Client(),
+		})
+		reconciler := NewAcrPullBindingReconciler(mgr.GetClient(), ctrl.Log.WithName("envtest"), mgr.GetScheme(), auth, controllerConfig)
+		Expect(reconciler.SetupWithManager(mgr)).To(Succeed())
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterAll(func() {
+		if cancel != nil {
+			cancel()
+		}
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	// newNamespace creates a namespace with a default service account, which envtest does not create.
+	newNamespace := func() string {
+		namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "envtest-"}}
+		Expect(k8sClient.Create(context.Background(), namespace)).To(Succeed())
+		serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: defaultServiceAccountName, Namespace: namespace.Name}}
+		Expect(k8sClient.Create(context.Background(), serviceAccount)).To(Succeed())
+		return namespace.Name
+	}
+
+	newBinding := func(namespace string) *msiacrpullv1.AcrPullBinding {
+		acrBinding := &msiacrpullv1.AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+			Spec: msiacrpullv1.AcrPullBindingSpec{
+				AcrServer: envtestACR,
+				Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: identity.ClientID},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), acrBinding)).To(Succeed())
+		return acrBinding
+	}
+
+	pullSecretRefs := func(namespace, serviceAccountName string) func() []v1.LocalObjectReference {
+		return func() []v1.LocalObjectReference {
+			var serviceAccount v1.ServiceAccount
+			if err := k8sClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: serviceAccountName}, &serviceAccount); err != nil {
+				return nil
+			}
+			return serviceAccount.ImagePullSecrets
+		}
+	}
+
+	getPullSecret := func(namespace string) func() (*v1.Secret, error) {
+		return func() (*v1.Secret, error) {
+			var secret v1.Secret
+			err := k8sClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: getPullSecretName("test")}, &secret)
+			return &secret, err
+		}
+	}
+
+	It("Should create the pull secret and bind it to the service account", func() {
+		namespace := newNamespace()
+		acrBinding := newBinding(namespace)
+
+		Eventually(getPullSecret(namespace)).Should(WithTransform(func(secret *v1.Secret) bool {
+			return metav1.IsControlledBy(secret, acrBinding)
+		}, BeTrue()))
+		Eventually(pullSecretRefs(namespace, defaultServiceAccountName)).Should(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+
+		Eventually(func(g Gomega) {
+			var updated msiacrpullv1.AcrPullBinding
+			g.Expect(k8sClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: "test"}, &updated)).To(Succeed())
+			g.Expect(updated.Finalizers).To(ContainElement(msiAcrPullFinalizerName))
+			g.Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, msiacrpullv1.ConditionReady)).To(BeTrue())
+			g.Expect(updated.Status.AcrServer).To(Equal(envtestACR))
+			g.Expect(updated.Status.BoundServiceAccounts).To(ConsistOf(msiacrpullv1.BoundServiceAccount{
+				Name: defaultServiceAccountName, PullSecretName: getPullSecretName("test"),
+			}))
+		}).Should(Succeed())
+	})
+
+	It("Should rotate the credential before it expires", func() {
+		namespace := newNamespace()
+		newBinding(namespace)
+
+		var first *v1.Secret
+		Eventually(func() error {
+			var err error
+			first, err = getPullSecret(namespace)()
+			return err
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			secret, err := getPullSecret(namespace)()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(secret.Data).NotTo(Equal(first.Data))
+			g.Expect(secret.Annotations[msiacrpullv1.TokenExpiryAnnotation]).NotTo(Equal(first.Annotations[msiacrpullv1.TokenExpiryAnnotation]))
+		}, 15*time.Second).Should(Succeed())
+	})
+
+	It("Should move the pull secret to another service account", func() {
+		namespace := newNamespace()
+		newBinding(namespace)
+		Eventually(pullSecretRefs(namespace, defaultServiceAccountName)).Should(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+
+		other := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: namespace}}
+		Expect(k8sClient.Create(context.Background(), other)).To(Succeed())
+		Eventually(func() error {
+			var acrBinding msiacrpullv1.AcrPullBinding
+			if err := k8sClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: "test"}, &acrBinding); err != nil {
+				return err
+			}
+			acrBinding.Spec.ServiceAccountName = "other"
+			return k8sClient.Update(context.Background(), &acrBinding)
+		}).Should(Succeed())
+
+		Eventually(pullSecretRefs(namespace, "other")).Should(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+		Eventually(pullSecretRefs(namespace, defaultServiceAccountName)).ShouldNot(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+	})
+
+	It("Should recreate a deleted pull secret", func() {
+		namespace := newNamespace()
+		newBinding(namespace)
+
+		var deleted *v1.Secret
+		Eventually(func() error {
+			var err error
+			deleted, err = getPullSecret(namespace)()
+			return err
+		}).Should(Succeed())
+		Expect(k8sClient.Delete(context.Background(), deleted)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			secret, err := getPullSecret(namespace)()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(secret.UID).NotTo(Equal(deleted.UID))
+		}).Should(Succeed())
+	})
+
+	It("Should reject identities whose member does not match their type", func() {
+		namespace := newNamespace()
+		for _, invalid := range []msiacrpullv1.Identity{
+			{Type: "Other", ClientID: identity.ClientID},
+			{Type: msiacrpullv1.IdentityTypeWorkload, ClientID: identity.ClientID},
+			{Type: msiacrpullv1.IdentityTypeServicePrincipal, Workload: &msiacrpullv1.WorkloadIdentity{ClientID: identity.ClientID}},
+		} {
+			acrBinding := &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "test-", Namespace: namespace},
+				Spec: msiacrpullv1.AcrPullBindingSpec{
+					AcrServer: envtestACR,
+					Identity:  invalid.DeepCopy(),
+				},
+			}
+			err := k8sClient.Create(context.Background(), acrBinding)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error for %s: %v", invalid.Type, err)
+		}
+	})
+
+	It("Should unbind the service account and remove the finalizer on deletion", func() {
+		namespace := newNamespace()
+		acrBinding := newBinding(namespace)
+		Eventually(pullSecretRefs(namespace, defaultServiceAccountName)).Should(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+
+		Expect(k8sClient.Delete(context.Background(), acrBinding)).To(Succeed())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(context.Background(), k8stypes.NamespacedName{Namespace: namespace, Name: "test"}, &msiacrpullv1.AcrPullBinding{})
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+		Eventually(pullSecretRefs(namespace, defaultServiceAccountName)).ShouldNot(ContainElement(v1.LocalObjectReference{Name: getPullSecretName("test")}))
+	})
+})
@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// managedHostsAnnotation records, per binding, the registry hosts whose entries the controller
+// maintains in an existing secret, so that only those entries are replaced or removed.
+const managedHostsAnnotation = "msi-acrpull.microsoft.com/managed-hosts"
+
+// updateExistingSecret writes the credential of the binding into the user-owned secret named by
+// spec.existingSecretName, leaving the entries of other registries untouched. Secrets previously
+// created for the binding are deleted, as the existing secret replaces them.
+func (r *AcrPullBindingReconciler) updateExistingSecret(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	acrServer string, acrAccessToken types.AccessToken, serviceAccountName string, log logr.Logger) error {
+	var pullSecrets v1.SecretList
+	if err := r.List(ctx, &pullSecrets, client.InNamespace(acrBinding.Namespace), client.MatchingFields{ownerKey: acrBinding.Name}); err != nil {
+		return err
+	}
+	if err := r.deleteStalePullSecrets(ctx, acrBinding, pullSecrets.Items, "", serviceAccountName, log); err != nil {
+		return err
+	}
+
+	var secret v1.Secret
+	secretName := k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Spec.ExistingSecretName}
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		return errors.Wrapf(err, "failed to get existing secret %s", secretName.Name)
+	}
+
+	builder := authorizer.NewDockerConfigBuilder()
+	builder.IdentityToken = acrBinding.Spec.SecretFormat == msiacrpullv1.SecretFormatDockerConfigJSONIdentityToken
+	hosts := registryHosts(acrServer, acrBinding.Spec.HostAliases)
+	builder.Add(acrServer, acrAccessToken, acrBinding.Spec.HostAliases...)
+
+	if err := mergeExistingSecret(&secret, acrBinding.Name, builder, hosts); err != nil {
+		return err
+	}
+
+	log.V(LogLevelDebug).Info("Updating existing pull secret", logKeySecret, secret.Name)
+	return r.Update(ctx, &secret)
+}
+
+// removeFromExistingSecret removes the entries of the binding from the user-owned secret named name.
+func (r *AcrPullBindingReconciler) removeFromExistingSecret(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	name string, log logr.Logger) error {
+	var secret v1.Secret
+	secretName := k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: name}
+	if err := r.Get(ctx, secretName, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(LogLevelDebug).Info("Existing pull secret not found, nothing to remove", logKeySecret, secretName.Name)
+			return nil
+		}
+		return err
+	}
+
+	if err := mergeExistingSecret(&secret, acrBinding.Name, authorizer.NewDockerConfigBuilder(), nil); err != nil {
+		return err
+	}
+
+	log.Info("Removing registry entries from existing pull secret", logKeySecret, secret.Name)
+	return r.Update(ctx, &secret)
+}
+
+// existingSecretNames returns the distinct names of the existing secrets the binding may hold entries in:
+// the one of its spec and the one it last wrote into.
+func existingSecretNames(acrBinding *msiacrpullv1.AcrPullBinding) []string {
+	var names []string
+	for _, name := range []string{acrBinding.Spec.ExistingSecretName, acrBinding.Status.ExistingSecretName} {
+		if name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// mergeExistingSecret replaces the entries previously written for bindingName in secret with the
+// entries of builder, and records hosts as the hosts now managed for bindingName.
+func mergeExistingSecret(secret *v1.Secret, bindingName string, builder *authorizer.DockerConfigBuilder, hosts []string) error {
+	managedHosts := map[string][]string{}
+	if value := secret.Annotations[managedHostsAnnotation]; value != "" {
+		if err := json.Unmarshal([]byte(value), &managedHosts); err != nil {
+			return errors.Wrapf(err, "failed to parse annotation %s", managedHostsAnnotation)
+		}
+	}
+	previousHosts := managedHosts[bindingName]
+
+	var dataKey string
+	var merged []byte
+	var err error
+	switch secret.Type {
+	case v1.SecretTypeDockerConfigJson:
+		dataKey = v1.DockerConfigJsonKey
+		merged, err = builder.MergeDockerConfigJSON(secret.Data[dataKey], previousHosts)
+	case v1.SecretTypeDockercfg:
+		dataKey = v1.DockerConfigKey
+		merged, err = builder.MergeDockerCfg(secret.Data[dataKey], previousHosts)
+	default:
+		return fmt.Errorf("existing secret %s has unsupported type %s", secret.Name, secret.Type)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to update existing secret %s", secret.Name)
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[dataKey] = merged
+
+	if len(hosts) > 0 {
+		managedHosts[bindingName] = hosts
+	} else {
+		delete(managedHosts, bindingName)
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	if len(managedHosts) == 0 {
+		delete(secret.Annotations, managedHostsAnnotation)
+		return nil
+	}
+	annotation, err := json.Marshal(managedHosts)
+	if err != nil {
+		return err
+	}
+	secret.Annotations[managedHostsAnnotation] = string(annotation)
+	return nil
+}
+
+// registryHosts returns the sorted, distinct hosts a credential is written for.
+func registryHosts(acrServer string, hostAliases []string) []string {
+	var hosts []string
+	for _, host := range append([]string{acrServer}, hostAliases...) {
+		if host != "" && !containsString(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
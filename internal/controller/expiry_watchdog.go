@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+)
+
+// ExpiryWatchdog reads the expiry of the credentials in the pull secrets of the bindings independently
+// of their reconciles, and flags the bindings whose credential is about to expire or has expired. A
+// binding whose refresh keeps failing, because IMDS is down or the registry throttles, otherwise looks
+// healthy until pods fail to pull.
+type ExpiryWatchdog struct {
+	// Reconciler provides the client, clock, event recorder and namespace filters of the bindings.
+	Reconciler *AcrPullBindingReconciler
+	Log        logr.Logger
+	// Interval is how often the pull secrets are scanned.
+	Interval time.Duration
+	// ExpiringThreshold is how long before its expiry a credential is reported as expiring.
+	ExpiringThreshold time.Duration
+	// Enqueue, when set, receives the bindings with an expiring or expired credential on every scan, so
+	// that they are reconciled right away rather than after the backoff of their failed reconciles.
+	Enqueue chan<- event.GenericEvent
+}
+
+// NewExpiryWatchdog returns a watchdog of the bindings of r configured from cfg. Bindings are only
+// enqueued once Enqueue is set.
+func NewExpiryWatchdog(r *AcrPullBindingReconciler, log logr.Logger, cfg configv1alpha1.ExpiryWatchdogConfiguration) *ExpiryWatchdog {
+	return &ExpiryWatchdog{
+		Reconciler:        r,
+		Log:               log,
+		Interval:          cfg.Interval.Duration,
+		ExpiringThreshold: cfg.ExpiringThreshold.Duration,
+	}
+}
+
+// NeedLeaderElection returns true so that only the replica reconciling the bindings updates their status.
+func (w *ExpiryWatchdog) NeedLeaderElection() bool {
+	return true
+}
+
+// Start scans the pull secrets every interval until ctx is done.
+func (w *ExpiryWatchdog) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := w.Scan(ctx); err != nil {
+			w.Log.Error(err, "Failed to scan pull secrets for expiring credentials")
+		}
+	}, w.Interval)
+	return nil
+}
+
+// Scan reads the earliest expiry of the credentials written for every binding, updates the
+// CredentialValid condition of the bindings whose state changed and the credential metrics, and
+// enqueues the bindings with an expiring or expired credential. Bindings that could not be checked are
+// logged and skipped.
+func (w *ExpiryWatchdog) Scan(ctx context.Context) error {
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := w.Reconciler.List(ctx, &acrBindings); err != nil {
+		return errors.Wrap(err, "failed to list AcrPullBindings")
+	}
+
+	now := w.Reconciler.now()
+	counts := map[string]float64{
+		credentialStateValid:    0,
+		credentialStateExpiring: 0,
+		credentialStateExpired:  0,
+	}
+	var earliest time.Time
+	for i := range acrBindings.Items {
+		acrBinding := &acrBindings.Items[i]
+		if !acrBinding.DeletionTimestamp.IsZero() || !w.Reconciler.reconcilesNamespace(acrBinding.Namespace) {
+			continue
+		}
+		log := w.Log.WithValues(logKeyBinding, acrBinding.Name, logKeyNamespace, acrBinding.Namespace)
+
+		expiry, found, err := w.credentialExpiry(ctx, acrBinding)
+		if err != nil {
+			log.Error(err, "Failed to read the expiry of the pull credential")
+			continue
+		}
+		if !found {
+			continue
+		}
+		state := w.credentialState(expiry, now)
+		counts[state]++
+		if earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+
+		if err := w.flag(ctx, acrBinding, state, expiry, log); err != nil {
+			log.Error(err, "Failed to update the CredentialValid condition")
+		}
+		if state != credentialStateValid && w.Enqueue != nil {
+			select {
+			case w.Enqueue <- event.GenericEvent{Object: acrBinding}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	for state, count := range counts {
+		bindingCredentials.WithLabelValues(state).Set(count)
+	}
+	if earliest.IsZero() {
+		earliestCredentialExpiry.Set(0)
+	} else {
+		earliestCredentialExpiry.Set(float64(earliest.Unix()))
+	}
+	return nil
+}
+
+// credentialState returns the state of a credential expiring at expiry.
+func (w *ExpiryWatchdog) credentialState(expiry, now time.Time) string {
+	switch {
+	case !now.Before(expiry):
+		return credentialStateExpired
+	case expiry.Sub(now) <= w.ExpiringThreshold:
+		return credentialStateExpiring
+	default:
+		return credentialStateValid
+	}
+}
+
+// credentialExpiry returns the earliest expiry of the credentials written for the binding: in its own
+// pull secret or its entries of the existing secret, and in its mirrored secrets. It returns false when
+// no credential was written yet.
+func (w *ExpiryWatchdog) credentialExpiry(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding) (time.Time, bool, error) {
+	var secrets []v1.Secret
+	var hosts []string
+	if acrBinding.Spec.ExistingSecretName != "" {
+		var secret v1.Secret
+		secretName := k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Spec.ExistingSecretName}
+		err := w.Reconciler.Get(ctx, secretName, &secret)
+		switch {
+		case err == nil:
+			secrets = append(secrets, secret)
+		case !apierrors.IsNotFound(err):
+			return time.Time{}, false, err
+		}
+		// the existing secret can hold the credentials of other registries
+		_, _, acrServer := specOrDefault(w.Reconciler, acrBinding.Spec)
+		hosts = registryHosts(acrServer, acrBinding.Spec.HostAliases)
+	} else {
+		var pullSecrets v1.SecretList
+		if err := w.Reconciler.List(ctx, &pullSecrets, client.InNamespace(acrBinding.Namespace),
+			client.MatchingFields{ownerKey: acrBinding.Name}); err != nil {
+			return time.Time{}, false, err
+		}
+		if pullSecret := getPullSecret(acrBinding, pullSecrets.Items); pullSecret != nil {
+			secrets = append(secrets, *pullSecret)
+		}
+	}
+
+	var copies v1.SecretList
+	if err := w.Reconciler.List(ctx, &copies,
+		client.MatchingFields{mirrorSourceKey: fmt.Sprintf("%s/%s", acrBinding.Namespace, acrBinding.Name)}); err != nil {
+		return time.Time{}, false, err
+	}
+	secrets = append(secrets, copies.Items...)
+
+	var earliest time.Time
+	for i := range secrets {
+		expiry, found, err := secretCredentialExpiry(&secrets[i], hosts)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if found && (earliest.IsZero() || expiry.Before(earliest)) {
+			earliest = expiry
+		}
+	}
+	return earliest, !earliest.IsZero(), nil
+}
+
+// secretCredentialExpiry decodes the docker config of a pull secret and returns the earliest expiry of
+// the credentials of hosts, or of every host when hosts is empty.
+func secretCredentialExpiry(secret *v1.Secret, hosts []string) (time.Time, bool, error) {
+	var entries authorizer.DockerConfigEntries
+	var err error
+	if data, ok := secret.Data[v1.DockerConfigJsonKey]; ok {
+		entries, err = authorizer.ParseDockerConfigJSON(data)
+	} else if data, ok := secret.Data[v1.DockerConfigKey]; ok {
+		entries, err = authorizer.ParseDockerCfg(data)
+	}
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(err, "failed to decode pull secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	var earliest time.Time
+	for host, entry := range entries {
+		if len(hosts) > 0 && !containsString(hosts, host) {
+			continue
+		}
+		token := entry.Token()
+		if token == "" {
+			continue
+		}
+		expiry, err := token.GetTokenExp()
+		if err != nil {
+			return time.Time{}, false, errors.Wrapf(err, "failed to read the expiry of the credential for %s in pull secret %s/%s",
+				host, secret.Namespace, secret.Name)
+		}
+		if earliest.IsZero() || expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	return earliest, !earliest.IsZero(), nil
+}
+
+// flag sets the CredentialValid condition of the binding for state, and records an event when the
+// credential starts expiring or has expired. An expired credential also makes the binding not Ready. The
+// status is only written when the state changed, reconciles reset the condition when they refresh the
+// credential.
+func (w *ExpiryWatchdog) flag(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding, state string, expiry time.Time,
+	log logr.Logger) error {
+	condition := credentialValidCondition(state, expiry, acrBinding.Generation)
+	current := meta.FindStatusCondition(acrBinding.Status.Conditions, msiacrpullv1.ConditionCredentialValid)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason {
+		return nil
+	}
+
+	meta.SetStatusCondition(&acrBinding.Status.Conditions, condition)
+	ready := meta.FindStatusCondition(acrBinding.Status.Conditions, msiacrpullv1.ConditionReady)
+	switch {
+	case state == credentialStateExpired:
+		meta.SetStatusCondition(&acrBinding.Status.Conditions, metav1.Condition{
+			Type:               msiacrpullv1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: acrBinding.Generation,
+			Reason:             msiacrpullv1.ReasonCredentialExpired,
+			Message:            condition.Message,
+		})
+	case ready != nil && ready.Reason == msiacrpullv1.ReasonCredentialExpired:
+		// the credential was refreshed but the status of the reconcile was not written
+		meta.SetStatusCondition(&acrBinding.Status.Conditions, readyCondition(acrBinding.Status.Conditions, acrBinding.Generation))
+	}
+	if err := w.Reconciler.Status().Update(ctx, acrBinding); err != nil {
+		return err
+	}
+
+	expiryTime := expiry.UTC().Format(time.RFC3339)
+	switch state {
+	case credentialStateExpiring:
+		log.Info("Pull credential is expiring", logKeyTokenExpiry, expiryTime)
+		w.Reconciler.warningEvent(ctx, acrBinding, eventReasonCredentialExpiring, "%s", condition.Message)
+	case credentialStateExpired:
+		log.Info("Pull credential expired", logKeyTokenExpiry, expiryTime)
+		w.Reconciler.warningEvent(ctx, acrBinding, eventReasonCredentialExpired, "%s", condition.Message)
+	}
+	return nil
+}
+
+// credentialValidCondition returns the CredentialValid condition of a credential expiring at expiry.
+func credentialValidCondition(state string, expiry time.Time, generation int64) metav1.Condition {
+	expiryTime := expiry.UTC().Format(time.RFC3339)
+	condition := metav1.Condition{
+		Type:               msiacrpullv1.ConditionCredentialValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             msiacrpullv1.ReasonCredentialValid,
+		Message:            fmt.Sprintf("The pull credential expires at %s", expiryTime),
+	}
+	switch state {
+	case credentialStateExpiring:
+		condition.Reason = msiacrpullv1.ReasonCredentialExpiring
+		condition.Message = fmt.Sprintf("The pull credential expires at %s and has not been refreshed", expiryTime)
+	case credentialStateExpired:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = msiacrpullv1.ReasonCredentialExpired
+		condition.Message = fmt.Sprintf("The pull credential expired at %s and has not been refreshed", expiryTime)
+	}
+	return condition
+}
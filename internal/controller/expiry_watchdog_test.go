@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = Describe("ExpiryWatchdog", func() {
+	const acrServer = "test.azurecr.io"
+	now := time.Now().Truncate(time.Second)
+
+	// newBinding returns a binding with an owned pull secret holding a credential expiring at exp.
+	newBinding := func(name string, exp time.Time) (*msiacrpullv1.AcrPullBinding, *v1.Secret) {
+		acrBinding := &msiacrpullv1.AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: k8stypes.UID(name)},
+			Spec: msiacrpullv1.AcrPullBindingSpec{
+				AcrServer: acrServer,
+				Identity:  &msiacrpullv1.Identity{Type: msiacrpullv1.IdentityTypeClientID, ClientID: "clientID"},
+			},
+			Status: msiacrpullv1.AcrPullBindingStatus{TokenExpirationTime: &metav1.Time{Time: exp}},
+		}
+		acrToken, err := getTestToken(exp.Unix())
+		Expect(err).ToNot(HaveOccurred())
+		secretType, secretData, err := buildPullSecretData(acrBinding.Spec, acrServer, acrToken)
+		Expect(err).ToNot(HaveOccurred())
+		pullSecret, err := newBasePullSecret(acrBinding, secretType, secretData, scheme.Scheme)
+		Expect(err).ToNot(HaveOccurred())
+		acrClaims, err := acrToken.GetACRClaims()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(setPullSecretMetadata(pullSecret, acrBinding, acrServer, "clientID", acrClaims, now)).To(Succeed())
+		return acrBinding, pullSecret
+	}
+
+	newWatchdog := func(objs ...client.Object) (*ExpiryWatchdog, *record.FakeRecorder) {
+		var statusObjs []client.Object
+		for _, obj := range objs {
+			if _, ok := obj.(*msiacrpullv1.AcrPullBinding); ok {
+				statusObjs = append(statusObjs, obj)
+			}
+		}
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &AcrPullBindingReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(objs...).
+				WithStatusSubresource(statusObjs...).
+				WithIndex(&v1.Secret{}, ownerKey, pullSecretOwner).
+				WithIndex(&v1.Secret{}, mirrorSourceKey, mirrorSource).
+				Build(),
+			Log:      ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+			Scheme:   scheme.Scheme,
+			Clock:    clocktesting.NewFakePassiveClock(now),
+			Recorder: recorder,
+		}
+		watchdog := NewExpiryWatchdog(reconciler, ctrl.Log.WithName("expiry-watchdog"), configv1alpha1.ExpiryWatchdogConfiguration{
+			Interval:          metav1.Duration{Duration: time.Minute},
+			ExpiringThreshold: metav1.Duration{Duration: 10 * time.Minute},
+		})
+		return watchdog, recorder
+	}
+
+	getBinding := func(watchdog *ExpiryWatchdog, name string) *msiacrpullv1.AcrPullBinding {
+		var acrBinding msiacrpullv1.AcrPullBinding
+		Expect(watchdog.Reconciler.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: name}, &acrBinding)).To(Succeed())
+		return &acrBinding
+	}
+
+	It("Should flag the bindings whose credential is expiring and enqueue them", func() {
+		valid, validSecret := newBinding("valid", now.Add(time.Hour))
+		expiring, expiringSecret := newBinding("expiring", now.Add(5*time.Minute))
+		watchdog, recorder := newWatchdog(valid, validSecret, expiring, expiringSecret)
+		enqueued := make(chan event.GenericEvent, 10)
+		watchdog.Enqueue = enqueued
+
+		Expect(watchdog.Scan(context.Background())).To(Succeed())
+
+		condition := meta.FindStatusCondition(getBinding(watchdog, "valid").Status.Conditions, msiacrpullv1.ConditionCredentialValid)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonCredentialValid))
+
+		updated := getBinding(watchdog, "expiring")
+		condition = meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionCredentialValid)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonCredentialExpiring))
+		Expect(meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)).To(BeNil())
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(HavePrefix("Warning CredentialExpiring"))
+		Expect(enqueued).To(HaveLen(1))
+		Expect((<-enqueued).Object.GetName()).To(Equal("expiring"))
+
+		Expect(testutil.ToFloat64(bindingCredentials.WithLabelValues(credentialStateValid))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(bindingCredentials.WithLabelValues(credentialStateExpiring))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(bindingCredentials.WithLabelValues(credentialStateExpired))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(earliestCredentialExpiry)).To(Equal(float64(now.Add(5 * time.Minute).Unix())))
+	})
+
+	It("Should see pull secrets controlled through v1beta1", func() {
+		acrBinding, pullSecret := newBinding("upgraded", now.Add(5*time.Minute))
+		pullSecret.OwnerReferences[0].APIVersion = "msi-acrpull.microsoft.com/v1beta1"
+		delete(pullSecret.Labels, msiacrpullv1.ManagedByLabel)
+		watchdog, recorder := newWatchdog(acrBinding, pullSecret)
+
+		Expect(watchdog.Scan(context.Background())).To(Succeed())
+
+		condition := meta.FindStatusCondition(getBinding(watchdog, "upgraded").Status.Conditions, msiacrpullv1.ConditionCredentialValid)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonCredentialExpiring))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(HavePrefix("Warning CredentialExpiring"))
+	})
+
+	It("Should mark the binding not ready when a mirrored credential expired, once", func() {
+		acrBinding, pullSecret := newBinding("test", now.Add(time.Hour))
+		_, mirrored := newBinding("old", now.Add(-time.Minute))
+		mirrored.Namespace = "mirror"
+		mirrored.OwnerReferences = nil
+		mirrored.Annotations[msiacrpullv1.MirrorSourceAnnotation] = "default/test"
+		watchdog, recorder := newWatchdog(acrBinding, pullSecret, mirrored)
+
+		Expect(watchdog.Scan(context.Background())).To(Succeed())
+		Expect(watchdog.Scan(context.Background())).To(Succeed())
+
+		updated := getBinding(watchdog, "test")
+		condition := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionCredentialValid)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(msiacrpullv1.ReasonCredentialExpired))
+		ready := meta.FindStatusCondition(updated.Status.Conditions, msiacrpullv1.ConditionReady)
+		Expect(ready).ToNot(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(msiacrpullv1.ReasonCredentialExpired))
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(HavePrefix("Warning CredentialExpired"))
+		Expect(testutil.ToFloat64(bindingCredentials.WithLabelValues(credentialStateExpired))).To(Equal(1.0))
+	})
+})
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+// StuckReconcileCheck returns a liveness check that fails when no reconcile succeeded within window while
+// bindings are due for refresh, as happens when the workers hang or every reconcile fails, for instance
+// because the leader lost its credentials. A reconcile that returns an error does not count. The window
+// starts with the first check, so the check must only run on the leader.
+func (r *AcrPullBindingReconciler) StuckReconcileCheck(window time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		now := r.now()
+		r.lastReconcile.CompareAndSwap(0, now.UnixNano())
+		last := time.Unix(0, r.lastReconcile.Load())
+		if now.Sub(last) <= window {
+			return nil
+		}
+
+		overdue, err := r.overdueBindings(req.Context(), now)
+		if err != nil {
+			return err
+		}
+		if overdue == 0 {
+			return nil
+		}
+		return fmt.Errorf("no reconcile succeeded since %s while %d AcrPullBindings are due for refresh",
+			last.UTC().Format(time.RFC3339), overdue)
+	}
+}
+
+// overdueBindings counts the bindings reconciled by this instance whose credential was due for refresh at now.
+func (r *AcrPullBindingReconciler) overdueBindings(ctx context.Context, now time.Time) (int, error) {
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := r.List(ctx, &acrBindings); err != nil {
+		return 0, errors.Wrap(err, "failed to list AcrPullBindings")
+	}
+
+	refreshBuffer := r.tokenRefreshBuffer()
+	overdue := 0
+	for _, acrBinding := range acrBindings.Items {
+		if !acrBinding.DeletionTimestamp.IsZero() || !r.reconcilesNamespace(acrBinding.Namespace) {
+			continue
+		}
+		if exp := acrBinding.Status.TokenExpirationTime; exp != nil && !now.Before(exp.Add(-refreshBuffer)) {
+			overdue++
+		}
+	}
+	return overdue, nil
+}
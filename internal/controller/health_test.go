@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = Describe("StuckReconcileCheck", func() {
+	It("Should fail when no reconcile succeeded in the window while bindings are due", func() {
+		now := time.Now()
+		fakeClock := clocktesting.NewFakePassiveClock(now)
+		acrBinding := &msiacrpullv1.AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Status: msiacrpullv1.AcrPullBindingStatus{
+				TokenExpirationTime: &metav1.Time{Time: now.Add(2 * time.Hour)},
+			},
+		}
+		reconciler := &AcrPullBindingReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(acrBinding).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if key.Name == "broken" {
+							return errors.New("test error")
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build(),
+			Log:    ctrl.Log.WithName("controllers").WithName("acrpullbinding-controller"),
+			Scheme: scheme.Scheme,
+			Clock:  fakeClock,
+		}
+		check := reconciler.StuckReconcileCheck(30 * time.Minute)
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+		// the window starts with the first check
+		Expect(check(req)).To(Succeed())
+
+		// the binding is not due before 30 minutes ahead of its expiry
+		fakeClock.SetTime(now.Add(time.Minute * 60))
+		Expect(check(req)).To(Succeed())
+
+		fakeClock.SetTime(now.Add(time.Minute * 95))
+		Expect(check(req)).To(MatchError(ContainSubstring("while 1 AcrPullBindings are due for refresh")))
+
+		// a failed reconcile is no progress
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "broken"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(check(req)).To(MatchError(ContainSubstring("while 1 AcrPullBindings are due for refresh")))
+
+		// a successful reconcile restarts the window
+		_, err = reconciler.Reconcile(context.Background(), ctrl.Request{
+			NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "missing"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check(req)).To(Succeed())
+	})
+})
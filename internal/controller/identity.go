@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+const (
+	// defaultClientSecretKey is the key of the client secret of a service principal when the binding names none.
+	defaultClientSecretKey = "clientSecret"
+	// serviceAccountTokenExpirationSeconds is the lifetime of the service account tokens exchanged for the tokens
+	// of workload identities, the shortest the API server issues.
+	serviceAccountTokenExpirationSeconds = 600
+)
+
+// acquireACRAccessToken acquires an ACR access token for acrServer with the identity of the binding. clientID
+// and resourceID are the managed identity returned by specOrDefault, used when the binding sets no application.
+func (r *AcrPullBindingReconciler) acquireACRAccessToken(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	serviceAccountName, clientID, resourceID, acrServer string) (types.AccessToken, error) {
+	identity := acrBinding.Spec.Identity
+	switch {
+	case identity != nil && identity.Type == msiacrpullv1.IdentityTypeWorkload && identity.Workload != nil:
+		tenantID := identity.Workload.TenantID
+		if tenantID == "" {
+			tenantID = r.TenantID
+		}
+		if tenantID == "" {
+			return "", errors.New("workload identity has no tenant, set identity.workload.tenantID or cloud.tenantID in the controller configuration")
+		}
+		if identity.Workload.ServiceAccountName != "" {
+			serviceAccountName = identity.Workload.ServiceAccountName
+		}
+		assertion, err := r.serviceAccountToken(ctx, acrBinding.Namespace, serviceAccountName)
+		if err != nil {
+			return "", err
+		}
+		return r.Auth.AcquireACRAccessTokenWithClientAssertion(ctx, tenantID, identity.Workload.ClientID, assertion, acrServer)
+	case identity != nil && identity.Type == msiacrpullv1.IdentityTypeServicePrincipal && identity.ServicePrincipal != nil:
+		clientSecret, err := r.clientSecret(ctx, acrBinding.Namespace, identity.ServicePrincipal.ClientSecretRef)
+		if err != nil {
+			return "", err
+		}
+		return r.Auth.AcquireACRAccessTokenWithClientSecret(ctx, identity.ServicePrincipal.TenantID,
+			identity.ServicePrincipal.ClientID, clientSecret, acrServer)
+	case clientID != "":
+		return r.Auth.AcquireACRAccessTokenWithClientID(ctx, clientID, acrServer)
+	default:
+		return r.Auth.AcquireACRAccessTokenWithResourceID(ctx, resourceID, acrServer)
+	}
+}
+
+// serviceAccountToken requests a short lived token of a service account for the audience federated
+// credentials accept.
+func (r *AcrPullBindingReconciler) serviceAccountToken(ctx context.Context, namespace, name string) (string, error) {
+	audience := r.TokenExchangeAudience
+	if audience == "" {
+		audience = configv1alpha1.TokenExchangeAudiences[configv1alpha1.AzurePublicCloud]
+	}
+	expirationSeconds := int64(serviceAccountTokenExpirationSeconds)
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{audience},
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if err := r.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return "", errors.Wrapf(err, "failed to request a token of service account %s", name)
+	}
+	if tokenRequest.Status.Token == "" {
+		return "", errors.Errorf("no token returned for service account %s", name)
+	}
+	return tokenRequest.Status.Token, nil
+}
+
+// clientSecret returns the client secret of a service principal from a secret in namespace.
+func (r *AcrPullBindingReconciler) clientSecret(ctx context.Context, namespace string, ref msiacrpullv1.SecretKeySelector) (string, error) {
+	var secret v1.Secret
+	if err := r.Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+		return "", errors.Wrapf(err, "failed to get client secret %s", ref.Name)
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultClientSecretKey
+	}
+	value := secret.Data[key]
+	if len(value) == 0 {
+		return "", errors.Errorf("secret %s has no client secret in key %s", ref.Name, key)
+	}
+	return string(value), nil
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+// Keys of the values logged by the controller. Log pipelines query them, so they are not renamed.
+const (
+	logKeyBinding         = "binding"
+	logKeyNamespace       = "namespace"
+	logKeyReconcileID     = "reconcileID"
+	logKeyRegistry        = "registry"
+	logKeyIdentityHash    = "identityHash"
+	logKeyTokenExpiry     = "tokenExpiry"
+	logKeySecret          = "secret"
+	logKeyServiceAccount  = "serviceAccount"
+	logKeyTargetNamespace = "targetNamespace"
+)
+
+// Reasons of the events of a binding.
+const (
+	eventReasonCredentialIssued   = msiacrpullv1.ReasonCredentialIssued
+	eventReasonCredentialRotated  = "CredentialRotated"
+	eventReasonCredentialExpiring = "CredentialExpiring"
+	eventReasonCredentialExpired  = "CredentialExpired"
+)
+
+// LogLevelDebug is the verbosity of the messages logged by every reconcile, such as the rotation of
+// a credential, and by every admission of the pod webhook. They are enabled with --zap-log-level=debug.
+const LogLevelDebug = 1
+
+type correlationIDKey struct{}
+
+// withCorrelationID returns ctx carrying the correlation ID of a reconcile: the reconcile ID of
+// controller-runtime, or a new ID when the reconciler is not called by a controller.
+func withCorrelationID(ctx context.Context) (context.Context, string) {
+	id := string(controller.ReconcileIDFromContext(ctx))
+	if id == "" {
+		id = string(uuid.NewUUID())
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id), id
+}
+
+// correlationID returns the correlation ID of the reconcile running with ctx, if any.
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// event records an event about obj stamped with the correlation ID of the reconcile, so that it can be
+// matched with the log lines and audit records of the same reconcile.
+func (r *AcrPullBindingReconciler) event(ctx context.Context, obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	var annotations map[string]string
+	if id := correlationID(ctx); id != "" {
+		annotations = map[string]string{msiacrpullv1.CorrelationIDAnnotation: id}
+	}
+	r.Recorder.AnnotatedEventf(obj, annotations, eventType, reason, messageFmt, args...)
+}
+
+// warningEvent records a warning event about obj, see event.
+func (r *AcrPullBindingReconciler) warningEvent(ctx context.Context, obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.event(ctx, obj, v1.EventTypeWarning, reason, messageFmt, args...)
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// States of a credential, as reported by the expiry watchdog.
+const (
+	credentialStateValid    = "valid"
+	credentialStateExpiring = "expiring"
+	credentialStateExpired  = "expired"
+)
+
+var (
+	// bindingCredentials counts the bindings by the state of their credential at the last scan of the
+	// expiry watchdog.
+	bindingCredentials = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "msi_acrpull_binding_credentials",
+		Help: "Number of AcrPullBindings by the state of the credential in their pull secrets.",
+	}, []string{"state"})
+
+	// earliestCredentialExpiry is the expiry of the credential closest to expiring at the last scan of
+	// the expiry watchdog, so that an alert can fire before it is reached.
+	earliestCredentialExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "msi_acrpull_credential_earliest_expiry_timestamp_seconds",
+		Help: "Unix time at which the credential closest to expiring expires, 0 when there is none.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(bindingCredentials, earliestCredentialExpiry)
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// mirrorSourceKey indexes mirrored secrets by the binding they are copied from. Owner references
+// can not cross namespaces, so the copies are tied to the binding by label and annotation instead.
+const mirrorSourceKey = ".metadata.mirrorSource"
+
+// mirrorSource indexes secrets by the namespace/name of the AcrPullBinding they are mirrored from.
+func mirrorSource(rawObj client.Object) []string {
+	secret := rawObj.(*v1.Secret)
+	if secret.Labels[msiacrpullv1.ManagedByLabel] != msiacrpullv1.ManagedByValue {
+		return nil
+	}
+	if source := secret.Annotations[msiacrpullv1.MirrorSourceAnnotation]; source != "" {
+		return []string{source}
+	}
+	return nil
+}
+
+// mirrorSourceRequest maps a mirrored secret to a reconcile request for its binding.
+func (r *AcrPullBindingReconciler) mirrorSourceRequest(_ context.Context, obj client.Object) []reconcile.Request {
+	sources := mirrorSource(obj)
+	if len(sources) == 0 {
+		return nil
+	}
+	namespace, name, found := strings.Cut(sources[0], "/")
+	if !found || !r.reconcilesNamespace(namespace) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Namespace: namespace, Name: name}}}
+}
+
+// mirroringBindings maps a namespace to reconcile requests for every binding mirroring its secret,
+// so that copies follow namespaces starting or stopping to match a selector.
+func (r *AcrPullBindingReconciler) mirroringBindings(ctx context.Context, _ client.Object) []reconcile.Request {
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := r.List(ctx, &acrBindings); err != nil {
+		r.Log.Error(err, "Failed to list AcrPullBindings")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, acrBinding := range acrBindings.Items {
+		if acrBinding.Spec.Mirror == nil || !r.reconcilesNamespace(acrBinding.Namespace) ||
+			!containsString(r.MirrorSourceNamespaces, acrBinding.Namespace) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name},
+		})
+	}
+	return requests
+}
+
+// mirroringEnabled reports whether the configuration allows any binding to mirror its pull secret.
+func (r *AcrPullBindingReconciler) mirroringEnabled() bool {
+	return len(r.MirrorSourceNamespaces) > 0 && len(r.MirrorTargetNamespaces) > 0
+}
+
+// reconcilesNamespace reports whether bindings in namespace are reconciled by this instance.
+func (r *AcrPullBindingReconciler) reconcilesNamespace(namespace string) bool {
+	if containsString(r.ExcludeNamespaces, namespace) {
+		return false
+	}
+	if len(r.IncludeNamespaces) > 0 && !containsString(r.IncludeNamespaces, namespace) {
+		return false
+	}
+	return r.ShardCount < 2 || namespaceShard(namespace, r.ShardCount) == r.ShardIndex
+}
+
+// mirrorPullSecret copies the credential into the namespaces selected by the mirror spec of the binding
+// that the configuration allows as targets, and deletes the copies in namespaces that are no longer
+// selected or allowed. It returns the namespaces holding a copy and the SecretsMirrored condition, which
+// is nil when the binding does not mirror its secret.
+func (r *AcrPullBindingReconciler) mirrorPullSecret(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	acrServer, identity string, accessToken types.AccessToken, claims *types.ACRClaims, log logr.Logger) ([]string, *metav1.Condition, error) {
+	source := fmt.Sprintf("%s/%s", acrBinding.Namespace, acrBinding.Name)
+	var copies v1.SecretList
+	if err := r.List(ctx, &copies, client.MatchingFields{mirrorSourceKey: source}); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list mirrored secrets")
+	}
+
+	allowed := r.mirroringEnabled() && containsString(r.MirrorSourceNamespaces, acrBinding.Namespace)
+	targets := map[string]bool{}
+	var notAllowed []string
+	if acrBinding.Spec.Mirror != nil && allowed {
+		selector, err := metav1.LabelSelectorAsSelector(&acrBinding.Spec.Mirror.NamespaceSelector)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid mirror namespace selector")
+		}
+		var namespaces v1.NamespaceList
+		if err := r.List(ctx, &namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to list mirror target namespaces")
+		}
+		for _, namespace := range namespaces.Items {
+			if namespace.Name == acrBinding.Namespace || !namespace.DeletionTimestamp.IsZero() {
+				continue
+			}
+			if !containsString(r.MirrorTargetNamespaces, namespace.Name) {
+				notAllowed = append(notAllowed, namespace.Name)
+				continue
+			}
+			targets[namespace.Name] = true
+		}
+	}
+
+	secretName := getOwnedPullSecretName(acrBinding)
+	for idx := range copies.Items {
+		mirrored := &copies.Items[idx]
+		if targets[mirrored.Namespace] && mirrored.Name == secretName {
+			continue
+		}
+		log.Info("Deleting mirrored pull secret", logKeyTargetNamespace, mirrored.Namespace, logKeySecret, mirrored.Name)
+		if err := r.Delete(ctx, mirrored); err != nil && !apierrors.IsNotFound(err) {
+			return nil, nil, errors.Wrap(err, "failed to delete mirrored secret")
+		}
+	}
+	if acrBinding.Spec.Mirror == nil {
+		return nil, nil, nil
+	}
+	if !allowed {
+		log.Info("Not mirroring pull secret from a namespace that is not allowed as a mirror source")
+		return nil, &metav1.Condition{
+			Type:               msiacrpullv1.ConditionSecretsMirrored,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: acrBinding.Generation,
+			Reason:             msiacrpullv1.ReasonMirrorNotAllowed,
+			Message:            fmt.Sprintf("The controller does not allow mirroring pull secrets from namespace %s", acrBinding.Namespace),
+		}, nil
+	}
+
+	secretType, secretData, err := buildPullSecretData(acrBinding.Spec, acrServer, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mirroredNamespaces, conflicts []string
+	for namespace := range targets {
+		var mirrored v1.Secret
+		err := r.Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: secretName}, &mirrored)
+		switch {
+		case apierrors.IsNotFound(err):
+			mirrored = v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: namespace,
+				},
+				Type: secretType,
+				Data: secretData,
+			}
+			if err := setMirroredSecretMetadata(&mirrored, acrBinding, source, acrServer, identity, claims, r.now()); err != nil {
+				return nil, nil, err
+			}
+			log.Info("Creating mirrored pull secret", logKeyTargetNamespace, namespace, logKeySecret, secretName)
+			if err := r.Create(ctx, &mirrored); err != nil {
+				return nil, nil, errors.Wrap(err, "failed to create mirrored secret")
+			}
+		case err != nil:
+			return nil, nil, errors.Wrap(err, "failed to get mirrored secret")
+		case mirrored.Annotations[msiacrpullv1.MirrorSourceAnnotation] != source:
+			log.Info("Not mirroring pull secret over a secret of another owner", logKeyTargetNamespace, namespace, logKeySecret, secretName)
+			conflicts = append(conflicts, namespace)
+			continue
+		case mirrored.Type != secretType:
+			// the type of a secret can not be changed, the copy is recreated on the next reconcile
+			log.Info("Deleting mirrored pull secret to change its type", logKeyTargetNamespace, namespace, logKeySecret, secretName)
+			if err := r.Delete(ctx, &mirrored); err != nil && !apierrors.IsNotFound(err) {
+				return nil, nil, errors.Wrap(err, "failed to delete mirrored secret")
+			}
+			continue
+		default:
+			mirrored.Data = secretData
+			if err := setMirroredSecretMetadata(&mirrored, acrBinding, source, acrServer, identity, claims, r.now()); err != nil {
+				return nil, nil, err
+			}
+			if err := r.Update(ctx, &mirrored); err != nil {
+				return nil, nil, errors.Wrap(err, "failed to update mirrored secret")
+			}
+		}
+		mirroredNamespaces = append(mirroredNamespaces, namespace)
+	}
+	sort.Strings(mirroredNamespaces)
+	sort.Strings(conflicts)
+	sort.Strings(notAllowed)
+
+	condition := &metav1.Condition{
+		Type:               msiacrpullv1.ConditionSecretsMirrored,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: acrBinding.Generation,
+		Reason:             msiacrpullv1.ReasonSecretsMirrored,
+		Message:            fmt.Sprintf("Pull secret is mirrored into %d namespaces", len(mirroredNamespaces)),
+	}
+	if len(conflicts) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = msiacrpullv1.ReasonMirrorConflict
+		condition.Message = fmt.Sprintf("Secret %s already exists and is not a mirror of this binding in namespaces: %s",
+			secretName, strings.Join(conflicts, ", "))
+	}
+	if len(notAllowed) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = msiacrpullv1.ReasonMirrorNotAllowed
+		condition.Message = fmt.Sprintf("The controller does not allow mirroring pull secrets into namespaces: %s",
+			strings.Join(notAllowed, ", "))
+	}
+	return mirroredNamespaces, condition, nil
+}
+
+func setMirroredSecretMetadata(mirrored *v1.Secret, acrBinding *msiacrpullv1.AcrPullBinding, source, acrServer, identity string,
+	claims *types.ACRClaims, now time.Time) error {
+	if err := setPullSecretMetadata(mirrored, acrBinding, acrServer, identity, claims, now); err != nil {
+		return err
+	}
+	mirrored.Annotations[msiacrpullv1.MirrorSourceAnnotation] = source
+	return nil
+}
+
+// deleteMirroredSecrets deletes every copy of the pull secret of the binding.
+func (r *AcrPullBindingReconciler) deleteMirroredSecrets(ctx context.Context, acrBinding *msiacrpullv1.AcrPullBinding,
+	log logr.Logger) error {
+	var copies v1.SecretList
+	if err := r.List(ctx, &copies, client.MatchingFields{mirrorSourceKey: fmt.Sprintf("%s/%s", acrBinding.Namespace, acrBinding.Name)}); err != nil {
+		return err
+	}
+	for idx := range copies.Items {
+		log.Info("Deleting mirrored pull secret", logKeyTargetNamespace, copies.Items[idx].Namespace, logKeySecret, copies.Items[idx].Name)
+		if err := r.Delete(ctx, &copies.Items[idx]); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+// acrPullBindingCRDName is the name of the CustomResourceDefinition of AcrPullBinding.
+const acrPullBindingCRDName = "acrpullbindings.msi-acrpull.microsoft.com"
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=update;patch
+
+// StorageVersionMigrator rewrites every AcrPullBinding stored at an older version at the storage
+// version once on startup, and then drops the older versions from the stored versions of the CRD so
+// that they can be removed from it in a later release.
+type StorageVersionMigrator struct {
+	// Client writes the migrated objects and the status of the CRD.
+	Client client.Client
+	// Reader reads the CRD and the bindings, bypassing the cache.
+	Reader client.Reader
+	// Namespaces restricts the migration to the bindings of these namespaces, all namespaces when empty.
+	// A restricted migrator can not know that no other namespace holds a binding at an older version, so
+	// it leaves the stored versions of the CRD to a controller watching all namespaces.
+	Namespaces []string
+	Log        logr.Logger
+	Backoff    wait.Backoff
+}
+
+// NewStorageVersionMigrator returns a migrator of the bindings of namespaces, or of all namespaces when
+// empty, retrying failed migrations for about half an hour.
+func NewStorageVersionMigrator(c client.Client, reader client.Reader, namespaces []string, log logr.Logger) *StorageVersionMigrator {
+	return &StorageVersionMigrator{
+		Client:     c,
+		Reader:     reader,
+		Namespaces: namespaces,
+		Log:        log,
+		Backoff: wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    15,
+			Cap:      5 * time.Minute,
+		},
+	}
+}
+
+// NeedLeaderElection returns true so that only one replica migrates the bindings.
+func (m *StorageVersionMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Start migrates the stored versions, retrying with backoff. A migration that keeps failing is
+// logged rather than stopping the manager, as the bindings are still served by the conversion webhook.
+func (m *StorageVersionMigrator) Start(ctx context.Context) error {
+	err := wait.ExponentialBackoffWithContext(ctx, m.Backoff, func(ctx context.Context) (bool, error) {
+		if err := m.Migrate(ctx); err != nil {
+			m.Log.Error(err, "Failed to migrate the stored versions of AcrPullBindings, retrying")
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && ctx.Err() == nil {
+		m.Log.Error(err, "Gave up migrating the stored versions of AcrPullBindings")
+	}
+	return nil
+}
+
+// Migrate rewrites all bindings unless the storage version is the only stored version.
+func (m *StorageVersionMigrator) Migrate(ctx context.Context) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := m.Reader.Get(ctx, k8stypes.NamespacedName{Name: acrPullBindingCRDName}, &crd); err != nil {
+		return errors.Wrap(err, "failed to get the AcrPullBinding CRD")
+	}
+	storageVersion := msiacrpullv1.GroupVersion.Version
+	if len(crd.Status.StoredVersions) == 1 && crd.Status.StoredVersions[0] == storageVersion {
+		return nil
+	}
+	m.Log.Info("Migrating AcrPullBindings to the storage version", "storedVersions", crd.Status.StoredVersions,
+		"storageVersion", storageVersion)
+
+	acrBindings, err := m.listBindings(ctx)
+	if err != nil {
+		return err
+	}
+	for idx := range acrBindings {
+		// an update without changes makes the API server store the object at the storage version
+		err := m.Client.Update(ctx, &acrBindings[idx])
+		// a binding changed or deleted since it was listed is already stored at the storage version
+		if err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to migrate acr pull binding %s/%s",
+				acrBindings[idx].Namespace, acrBindings[idx].Name)
+		}
+	}
+
+	if len(m.Namespaces) > 0 {
+		m.Log.Info("Migrated the AcrPullBindings of the watched namespaces, the stored versions are left to a controller watching all namespaces",
+			"count", len(acrBindings), "namespaces", m.Namespaces)
+		return nil
+	}
+	crd.Status.StoredVersions = []string{storageVersion}
+	if err := m.Client.Status().Update(ctx, &crd); err != nil {
+		return errors.Wrap(err, "failed to update the stored versions of the AcrPullBinding CRD")
+	}
+	m.Log.Info("Migrated AcrPullBindings to the storage version", "count", len(acrBindings))
+	return nil
+}
+
+// listBindings lists the bindings of the namespaces of the migrator, so that a controller restricted to
+// some namespaces only needs permissions on them.
+func (m *StorageVersionMigrator) listBindings(ctx context.Context) ([]msiacrpullv1.AcrPullBinding, error) {
+	if len(m.Namespaces) == 0 {
+		var acrBindings msiacrpullv1.AcrPullBindingList
+		if err := m.Reader.List(ctx, &acrBindings); err != nil {
+			return nil, errors.Wrap(err, "failed to list acr pull bindings")
+		}
+		return acrBindings.Items, nil
+	}
+
+	var items []msiacrpullv1.AcrPullBinding
+	for _, namespace := range m.Namespaces {
+		var acrBindings msiacrpullv1.AcrPullBindingList
+		if err := m.Reader.List(ctx, &acrBindings, client.InNamespace(namespace)); err != nil {
+			return nil, errors.Wrapf(err, "failed to list acr pull bindings in namespace %s", namespace)
+		}
+		items = append(items, acrBindings.Items...)
+	}
+	return items, nil
+}
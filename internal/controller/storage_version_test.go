@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = Describe("StorageVersionMigrator", func() {
+	newMigrator := func(namespaces []string, storedVersions ...string) (*StorageVersionMigrator, *msiacrpullv1.AcrPullBinding) {
+		testScheme := runtime.NewScheme()
+		Expect(msiacrpullv1.AddToScheme(testScheme)).To(Succeed())
+		Expect(apiextensionsv1.AddToScheme(testScheme)).To(Succeed())
+
+		crd := &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: acrPullBindingCRDName},
+			Status:     apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+		}
+		acrBinding := &msiacrpullv1.AcrPullBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		}
+		c := fake.NewClientBuilder().
+			WithScheme(testScheme).
+			WithObjects(crd, acrBinding, &msiacrpullv1.AcrPullBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "other"},
+			}).
+			WithStatusSubresource(crd).
+			Build()
+		return NewStorageVersionMigrator(c, c, namespaces, ctrl.Log.WithName("storage-version")), acrBinding
+	}
+
+	It("Should rewrite the bindings and drop older stored versions", func() {
+		migrator, acrBinding := newMigrator(nil, "v1beta1", "v1")
+		var before msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: "default", Name: "test"}, &before)).To(Succeed())
+
+		Expect(migrator.Migrate(context.Background())).To(Succeed())
+
+		var after msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name}, &after)).To(Succeed())
+		Expect(after.ResourceVersion).ToNot(Equal(before.ResourceVersion))
+
+		var crd apiextensionsv1.CustomResourceDefinition
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Name: acrPullBindingCRDName}, &crd)).To(Succeed())
+		Expect(crd.Status.StoredVersions).To(Equal([]string{"v1"}))
+	})
+
+	It("Should not rewrite the bindings once migrated", func() {
+		migrator, acrBinding := newMigrator(nil, "v1")
+		var before msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name}, &before)).To(Succeed())
+
+		Expect(migrator.Migrate(context.Background())).To(Succeed())
+
+		var after msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name}, &after)).To(Succeed())
+		Expect(after.ResourceVersion).To(Equal(before.ResourceVersion))
+	})
+
+	It("Should only rewrite the bindings of the watched namespaces and keep the stored versions", func() {
+		migrator, acrBinding := newMigrator([]string{"default"}, "v1beta1", "v1")
+		otherName := k8stypes.NamespacedName{Namespace: "other", Name: "test"}
+		var before, otherBefore msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name}, &before)).To(Succeed())
+		Expect(migrator.Client.Get(context.Background(), otherName, &otherBefore)).To(Succeed())
+
+		Expect(migrator.Migrate(context.Background())).To(Succeed())
+
+		var after, otherAfter msiacrpullv1.AcrPullBinding
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Namespace: acrBinding.Namespace, Name: acrBinding.Name}, &after)).To(Succeed())
+		Expect(after.ResourceVersion).ToNot(Equal(before.ResourceVersion))
+		Expect(migrator.Client.Get(context.Background(), otherName, &otherAfter)).To(Succeed())
+		Expect(otherAfter.ResourceVersion).To(Equal(otherBefore.ResourceVersion))
+
+		var crd apiextensionsv1.CustomResourceDefinition
+		Expect(migrator.Client.Get(context.Background(), k8stypes.NamespacedName{Name: acrPullBindingCRDName}, &crd)).To(Succeed())
+		Expect(crd.Status.StoredVersions).To(Equal([]string{"v1beta1", "v1"}))
+	})
+})
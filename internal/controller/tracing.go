@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/Azure/msi-acrpull/pkg/redact"
+)
+
+// tracerName is the name of the tracer of the controller spans.
+const tracerName = "github.com/Azure/msi-acrpull/internal/controller"
+
+// endSpan records err, if any, on span and ends it. Token material is redacted from the error.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		err = redact.Error(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingClient records a span for every write to the API server. Reads are served by the cache
+// and are not traced.
+type tracingClient struct {
+	client.Client
+}
+
+func newTracingClient(c client.Client) client.Client {
+	return &tracingClient{Client: c}
+}
+
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) (err error) {
+	ctx, span := startWriteSpan(ctx, c.Client, "create", "", obj)
+	defer func() { endSpan(span, err) }()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := startWriteSpan(ctx, c.Client, "update", "", obj)
+	defer func() { endSpan(span, err) }()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := startWriteSpan(ctx, c.Client, "patch", "", obj)
+	defer func() { endSpan(span, err) }()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) (err error) {
+	ctx, span := startWriteSpan(ctx, c.Client, "delete", "", obj)
+	defer func() { endSpan(span, err) }()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *tracingClient) Status() client.SubResourceWriter {
+	return &tracingSubResourceWriter{SubResourceWriter: c.Client.Status(), client: c.Client, subResource: "status"}
+}
+
+// tracingSubResourceWriter records a span for every write to a subresource.
+type tracingSubResourceWriter struct {
+	client.SubResourceWriter
+	client      client.Client
+	subResource string
+}
+
+func (w *tracingSubResourceWriter) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) (err error) {
+	ctx, span := startWriteSpan(ctx, w.client, "create", w.subResource, obj)
+	defer func() { endSpan(span, err) }()
+	return w.SubResourceWriter.Create(ctx, obj, subResource, opts...)
+}
+
+func (w *tracingSubResourceWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) (err error) {
+	ctx, span := startWriteSpan(ctx, w.client, "update", w.subResource, obj)
+	defer func() { endSpan(span, err) }()
+	return w.SubResourceWriter.Update(ctx, obj, opts...)
+}
+
+func (w *tracingSubResourceWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) (err error) {
+	ctx, span := startWriteSpan(ctx, w.client, "patch", w.subResource, obj)
+	defer func() { endSpan(span, err) }()
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// startWriteSpan starts the span of a write of verb to obj, or to its subResource when not empty.
+func startWriteSpan(ctx context.Context, c client.Client, verb, subResource string, obj client.Object) (context.Context, trace.Span) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	name := "kubernetes." + verb + " " + kind
+	if subResource != "" {
+		name += "/" + subResource
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("k8s.verb", verb),
+		attribute.String("k8s.kind", kind),
+		attribute.String("k8s.namespace.name", obj.GetNamespace()),
+		attribute.String("k8s.object.name", obj.GetName()),
+	))
+}
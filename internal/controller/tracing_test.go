@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = Describe("Tracing client", func() {
+	It("Records a span for each write", func() {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+		acrBinding := &msiacrpullv1.AcrPullBinding{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		c := newTracingClient(fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(acrBinding).
+			WithStatusSubresource(acrBinding).
+			Build())
+
+		secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-msi-acrpull-secret", Namespace: "default"}}
+		Expect(c.Create(context.Background(), secret)).To(Succeed())
+		Expect(c.Status().Update(context.Background(), acrBinding)).To(Succeed())
+		Expect(c.Delete(context.Background(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"}})).NotTo(Succeed())
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[0].Name()).To(Equal("kubernetes.create Secret"))
+		Expect(spans[0].Attributes()).To(ContainElement(attribute.String("k8s.object.name", "test-msi-acrpull-secret")))
+		Expect(spans[1].Name()).To(Equal("kubernetes.update AcrPullBinding/status"))
+		Expect(spans[2].Name()).To(Equal("kubernetes.delete Secret"))
+		Expect(spans[2].Events()).NotTo(BeEmpty())
+	})
+})
@@ -0,0 +1,160 @@
+// Package diagnose walks the token acquisition path of the controller one step
+// at a time and reports the outcome of each step. Tokens are never printed.
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
+)
+
+// Target is the identity and registry to diagnose.
+type Target struct {
+	ACRServer                 string
+	ManagedIdentityClientID   string
+	ManagedIdentityResourceID string
+	// TenantID is the tenant the ACR token has to be issued for, not checked when empty.
+	TenantID string
+	// ProbeImage is pulled with the ACR token when set, as the pull verification of the binding does.
+	ProbeImage string
+}
+
+// Diagnoser runs the diagnostic steps.
+type Diagnoser struct {
+	TokenRetriever   authorizer.ManagedIdentityTokenRetriever
+	TokenExchanger   authorizer.ACRTokenExchanger
+	RegistryVerifier authorizer.ACRRegistryVerifier
+	Out              io.Writer
+	// Now returns the current time, used to report token lifetimes.
+	Now func() time.Time
+}
+
+// NewDiagnoser returns a diagnoser using the same clients as the controller.
+func NewDiagnoser(opts authorizer.Options, out io.Writer) *Diagnoser {
+	return &Diagnoser{
+		TokenRetriever:   authorizer.NewTokenRetrieverWithOptions(opts),
+		TokenExchanger:   authorizer.NewTokenExchangerWithOptions(opts),
+		RegistryVerifier: authorizer.NewRegistryVerifierWithOptions(opts),
+		Out:              out,
+		Now:              time.Now,
+	}
+}
+
+const stepCount = 6
+
+// Run diagnoses target and returns an error if any step failed. Steps after a
+// failed step are reported as skipped.
+func (d *Diagnoser) Run(ctx context.Context, target Target) error {
+	fmt.Fprintf(d.Out, "Diagnosing access to %s\n", target.ACRServer)
+	if target.ManagedIdentityClientID != "" {
+		fmt.Fprintf(d.Out, "  managed identity client ID: %s\n", target.ManagedIdentityClientID)
+	} else {
+		fmt.Fprintf(d.Out, "  managed identity resource ID: %s\n", target.ManagedIdentityResourceID)
+	}
+
+	armToken, err := d.TokenRetriever.AcquireARMToken(ctx, target.ManagedIdentityClientID, target.ManagedIdentityResourceID)
+	if err != nil {
+		return d.fail(1, fmt.Errorf("failed to get ARM access token: %w", err))
+	}
+	d.report(1, "OK")
+
+	details, err := d.armTokenDetails(armToken)
+	if err != nil {
+		return d.fail(2, fmt.Errorf("failed to decode ARM access token: %w", err))
+	}
+	d.report(2, "OK", details...)
+
+	acrToken, err := d.TokenExchanger.ExchangeACRAccessToken(ctx, armToken, target.ACRServer)
+	if err != nil {
+		return d.fail(3, fmt.Errorf("failed to exchange ACR access token: %w", err))
+	}
+	d.report(3, "OK")
+
+	claims, err := acrToken.GetACRClaims()
+	if err != nil {
+		return d.fail(4, fmt.Errorf("failed to decode ACR access token: %w", err))
+	}
+	// the same checks as the controller, so that a token it would reject does not pass here
+	if err := claims.Validate(target.ACRServer, target.TenantID, d.Now()); err != nil {
+		return d.fail(4, fmt.Errorf("invalid ACR access token: %w", err))
+	}
+	exp, _ := claims.Expiry()
+	d.report(4, "OK", fmt.Sprintf("tenant: %s", claims.TenantID), d.expiry(exp))
+
+	if err := d.RegistryVerifier.VerifyRegistryAccess(ctx, acrToken, target.ACRServer); err != nil {
+		return d.fail(5, fmt.Errorf("registry rejected ACR access token: %w", err))
+	}
+	d.report(5, "OK")
+
+	if target.ProbeImage == "" {
+		d.report(6, "SKIPPED", "no probe image set")
+		return nil
+	}
+	if err := d.RegistryVerifier.VerifyImageAccess(ctx, acrToken, target.ACRServer, target.ProbeImage); err != nil {
+		return d.fail(6, fmt.Errorf("failed to verify pull access to probe image %s: %w", target.ProbeImage, err))
+	}
+	d.report(6, "OK", fmt.Sprintf("image: %s", target.ProbeImage))
+
+	return nil
+}
+
+// fail reports step as failed and all later steps as skipped.
+func (d *Diagnoser) fail(step int, err error) error {
+	d.report(step, "FAILED", redact.String(err.Error()))
+	for step++; step <= stepCount; step++ {
+		d.report(step, "SKIPPED")
+	}
+	return err
+}
+
+var stepNames = [stepCount]string{
+	"Acquire ARM token from the instance metadata service",
+	"Decode ARM token claims",
+	"Exchange ARM token for an ACR token",
+	"Validate ACR token claims",
+	"Call the registry /v2/ endpoint with the ACR token",
+	"Request the manifest of the probe image with the ACR token",
+}
+
+func (d *Diagnoser) armTokenDetails(armToken types.AccessToken) ([]string, error) {
+	claims, err := armToken.GetARMClaims()
+	if err != nil {
+		return nil, err
+	}
+	exp, err := claims.Expiry()
+	if err != nil {
+		return nil, err
+	}
+	if claims.TenantID == "" {
+		return nil, fmt.Errorf("token has no tenant ID")
+	}
+
+	return []string{
+		fmt.Sprintf("tenant: %s", claims.TenantID),
+		fmt.Sprintf("object ID: %s", claims.ObjectID),
+		fmt.Sprintf("xms_mirid: %s", claims.ManagedIdentityResourceID),
+		fmt.Sprintf("audience: %s", strings.Join(claims.Audience, ", ")),
+		d.expiry(exp),
+	}, nil
+}
+
+func (d *Diagnoser) expiry(exp time.Time) string {
+	remaining := exp.Sub(d.Now())
+	if remaining < 0 {
+		return fmt.Sprintf("expires: %s (expired %s ago)", exp.UTC().Format(time.RFC3339), (-remaining).Round(time.Second))
+	}
+	return fmt.Sprintf("expires: %s (in %s)", exp.UTC().Format(time.RFC3339), remaining.Round(time.Second))
+}
+
+func (d *Diagnoser) report(step int, result string, details ...string) {
+	fmt.Fprintf(d.Out, "[%d/%d] %s: %s\n", step, stepCount, stepNames[step-1], result)
+	for _, detail := range details {
+		fmt.Fprintf(d.Out, "      %s\n", detail)
+	}
+}
@@ -0,0 +1,154 @@
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/mock_authorizer"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+const (
+	testTenantID   = "1b4e67bf-39b2-4eb1-bec3-5099dd556b07"
+	testResourceID = "/subscriptions/11b8b9f9-1812-4828-9cb5-b41ee15d63c7/resourceGroups/test-rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/test-mi"
+	testACR        = "testcr.azurecr.io"
+)
+
+var _ = Describe("Diagnose Tests", func() {
+	var (
+		mockCtrl *gomock.Controller
+		tr       *mock_authorizer.MockManagedIdentityTokenRetriever
+		te       *mock_authorizer.MockACRTokenExchanger
+		rv       *mock_authorizer.MockACRRegistryVerifier
+		out      *bytes.Buffer
+		d        *Diagnoser
+		now      time.Time
+		armToken types.AccessToken
+		acrToken types.AccessToken
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		tr = mock_authorizer.NewMockManagedIdentityTokenRetriever(mockCtrl)
+		te = mock_authorizer.NewMockACRTokenExchanger(mockCtrl)
+		rv = mock_authorizer.NewMockACRRegistryVerifier(mockCtrl)
+		out = &bytes.Buffer{}
+		now = time.Unix(1700000000, 0)
+		d = &Diagnoser{
+			TokenRetriever:   tr,
+			TokenExchanger:   te,
+			RegistryVerifier: rv,
+			Out:              out,
+			Now:              func() time.Time { return now },
+		}
+
+		armToken = getTestToken(jwt.MapClaims{
+			"aud":       "https://management.azure.com/",
+			"exp":       now.Add(time.Hour).Unix(),
+			"tid":       testTenantID,
+			"xms_mirid": testResourceID,
+		})
+		acrToken = getTestToken(jwt.MapClaims{
+			"aud":    testACR,
+			"exp":    now.Add(3 * time.Hour).Unix(),
+			"tenant": testTenantID,
+		})
+	})
+
+	It("Reports every step and claim on success", func() {
+		tr.EXPECT().AcquireARMToken(gomock.Any(), "", testResourceID).Return(armToken, nil)
+		te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil)
+		rv.EXPECT().VerifyRegistryAccess(gomock.Any(), acrToken, testACR).Return(nil)
+		rv.EXPECT().VerifyImageAccess(gomock.Any(), acrToken, testACR, "team/probe:latest").Return(nil)
+
+		err := d.Run(context.Background(), Target{ACRServer: testACR, ManagedIdentityResourceID: testResourceID,
+			TenantID: testTenantID, ProbeImage: "team/probe:latest"})
+		Expect(err).ToNot(HaveOccurred())
+
+		report := out.String()
+		Expect(report).To(ContainSubstring("[1/6] Acquire ARM token from the instance metadata service: OK"))
+		Expect(report).To(ContainSubstring("tenant: " + testTenantID))
+		Expect(report).To(ContainSubstring("xms_mirid: " + testResourceID))
+		Expect(report).To(ContainSubstring("audience: https://management.azure.com/"))
+		Expect(report).To(ContainSubstring("(in 1h0m0s)"))
+		Expect(report).To(ContainSubstring("[4/6] Validate ACR token claims: OK"))
+		Expect(report).To(ContainSubstring("(in 3h0m0s)"))
+		Expect(report).To(ContainSubstring("[5/6] Call the registry /v2/ endpoint with the ACR token: OK"))
+		Expect(report).To(ContainSubstring("[6/6] Request the manifest of the probe image with the ACR token: OK"))
+		Expect(report).ToNot(ContainSubstring(string(armToken)))
+		Expect(report).ToNot(ContainSubstring(string(acrToken)))
+	})
+
+	It("Skips the remaining steps after a failure", func() {
+		tr.EXPECT().AcquireARMToken(gomock.Any(), "", testResourceID).Return(armToken, nil)
+		te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(types.AccessToken(""),
+			errors.New("ACR token exchange endpoint returned error status: 401"))
+
+		err := d.Run(context.Background(), Target{ACRServer: testACR, ManagedIdentityResourceID: testResourceID})
+		Expect(err).To(HaveOccurred())
+
+		report := out.String()
+		Expect(report).To(ContainSubstring("[3/6] Exchange ARM token for an ACR token: FAILED"))
+		Expect(report).To(ContainSubstring("returned error status: 401"))
+		Expect(report).To(ContainSubstring("[5/6] Call the registry /v2/ endpoint with the ACR token: SKIPPED"))
+		Expect(report).To(ContainSubstring("[6/6] Request the manifest of the probe image with the ACR token: SKIPPED"))
+	})
+
+	It("Rejects an ACR token the controller would reject", func() {
+		tr.EXPECT().AcquireARMToken(gomock.Any(), "", testResourceID).Return(armToken, nil)
+		te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil)
+
+		err := d.Run(context.Background(), Target{ACRServer: testACR, ManagedIdentityResourceID: testResourceID,
+			TenantID: "72f988bf-86f1-41af-91ab-2d7cd011db47"})
+		Expect(err).To(MatchError(ContainSubstring("invalid ACR access token")))
+
+		report := out.String()
+		Expect(report).To(ContainSubstring("[4/6] Validate ACR token claims: FAILED"))
+		Expect(report).To(ContainSubstring("does not match 72f988bf-86f1-41af-91ab-2d7cd011db47"))
+		Expect(report).To(ContainSubstring("[5/6] Call the registry /v2/ endpoint with the ACR token: SKIPPED"))
+	})
+
+	It("Reports a probe image the token can not pull", func() {
+		tr.EXPECT().AcquireARMToken(gomock.Any(), "", testResourceID).Return(armToken, nil)
+		te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil)
+		rv.EXPECT().VerifyRegistryAccess(gomock.Any(), acrToken, testACR).Return(nil)
+		rv.EXPECT().VerifyImageAccess(gomock.Any(), acrToken, testACR, "team/probe:latest").Return(
+			errors.New("ACR manifest endpoint returned error status for team/probe:latest: 403"))
+
+		err := d.Run(context.Background(), Target{ACRServer: testACR, ManagedIdentityResourceID: testResourceID,
+			ProbeImage: "team/probe:latest"})
+		Expect(err).To(HaveOccurred())
+
+		report := out.String()
+		Expect(report).To(ContainSubstring("[5/6] Call the registry /v2/ endpoint with the ACR token: OK"))
+		Expect(report).To(ContainSubstring("[6/6] Request the manifest of the probe image with the ACR token: FAILED"))
+		Expect(report).To(ContainSubstring("403"))
+	})
+
+	It("Reports an undecodable ARM token", func() {
+		tr.EXPECT().AcquireARMToken(gomock.Any(), "client-id", "").Return(types.AccessToken("not-a-jwt"), nil)
+
+		err := d.Run(context.Background(), Target{ACRServer: testACR, ManagedIdentityClientID: "client-id"})
+		Expect(err).To(HaveOccurred())
+		Expect(out.String()).To(ContainSubstring("[2/6] Decode ARM token claims: FAILED"))
+	})
+})
+
+func getTestToken(claims jwt.MapClaims) types.AccessToken {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).ToNot(HaveOccurred())
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signingKey)
+	Expect(err).ToNot(HaveOccurred())
+
+	return types.AccessToken(token)
+}
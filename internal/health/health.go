@@ -0,0 +1,60 @@
+// Package health provides the readiness and liveness checks of the manager. A failing check returns
+// its reason, which the manager serves on /readyz/<name> and /healthz/<name>, while /readyz?verbose and
+// /healthz?verbose list the state of every check.
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// MetadataEndpointChecker checks that the instance metadata service answers.
+type MetadataEndpointChecker interface {
+	CheckMetadataEndpoint(ctx context.Context) error
+}
+
+// CacheSyncer waits for the informers of a cache to sync.
+type CacheSyncer interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+// MetadataEndpoint returns a check that fails when the instance metadata service does not answer
+// within timeout. Without it no credential can be issued, so a replica on a node with a broken metadata
+// service is taken out of rotation.
+func MetadataEndpoint(checker MetadataEndpointChecker, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		return checker.CheckMetadataEndpoint(ctx)
+	}
+}
+
+// CacheSync returns a check that fails until the informers of the cache have synced with the API
+// server, waiting at most timeout for them.
+func CacheSync(cache CacheSyncer, timeout time.Duration) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		if !cache.WaitForCacheSync(ctx) {
+			return errors.New("informer caches have not synced")
+		}
+		return nil
+	}
+}
+
+// Elected returns a check that is skipped, and always passes, until elected is closed. Checks of work
+// only the leader does are wrapped with it, so that standby replicas are not restarted.
+func Elected(elected <-chan struct{}, check healthz.Checker) healthz.Checker {
+	return func(req *http.Request) error {
+		select {
+		case <-elected:
+		default:
+			return nil
+		}
+		return check(req)
+	}
+}
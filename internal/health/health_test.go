@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+type metadataEndpointFunc func(ctx context.Context) error
+
+func (f metadataEndpointFunc) CheckMetadataEndpoint(ctx context.Context) error {
+	return f(ctx)
+}
+
+type cacheSyncerFunc func(ctx context.Context) bool
+
+func (f cacheSyncerFunc) WaitForCacheSync(ctx context.Context) bool {
+	return f(ctx)
+}
+
+var _ = Describe("Health checks", func() {
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	}
+
+	It("Bounds the metadata endpoint check by its timeout", func() {
+		check := MetadataEndpoint(metadataEndpointFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}), 10*time.Millisecond)
+		Expect(check(newRequest())).To(MatchError(context.DeadlineExceeded))
+
+		check = MetadataEndpoint(metadataEndpointFunc(func(context.Context) error { return nil }), time.Second)
+		Expect(check(newRequest())).To(Succeed())
+	})
+
+	It("Fails until the caches have synced", func() {
+		synced := false
+		check := CacheSync(cacheSyncerFunc(func(context.Context) bool { return synced }), time.Second)
+		Expect(check(newRequest())).To(MatchError("informer caches have not synced"))
+
+		synced = true
+		Expect(check(newRequest())).To(Succeed())
+	})
+
+	It("Only runs the checks of the leader once elected", func() {
+		elected := make(chan struct{})
+		check := Elected(elected, func(*http.Request) error { return errors.New("stuck") })
+		Expect(check(newRequest())).To(Succeed())
+
+		close(elected)
+		Expect(check(newRequest())).To(MatchError("stuck"))
+	})
+
+	It("Serves the reason of a failing check on its own path", func() {
+		handler := &healthz.Handler{Checks: map[string]healthz.Checker{
+			"metadata-endpoint": MetadataEndpoint(metadataEndpointFunc(func(context.Context) error {
+				return errors.New("failed to reach metadata endpoint: connection refused")
+			}), time.Second),
+		}}
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metadata-endpoint", nil))
+		Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+		Expect(resp.Body.String()).To(ContainSubstring("connection refused"))
+
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?verbose", nil))
+		Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+		Expect(resp.Body.String()).To(ContainSubstring("[-]metadata-endpoint failed"))
+	})
+})
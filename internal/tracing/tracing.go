@@ -0,0 +1,70 @@
+// Package tracing installs the OpenTelemetry tracer provider of the controller manager.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+// ServiceName is the service.name resource attribute of the exported spans.
+const ServiceName = "msi-acrpull"
+
+// Setup installs a global tracer provider exporting spans as cfg selects, and returns the function
+// flushing and stopping it. When the exporter is None the global provider, which drops every span,
+// is left in place and shutdown does nothing. Stdout spans are written to out.
+func Setup(ctx context.Context, cfg configv1alpha1.TracingConfiguration, out io.Writer) (shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", configv1alpha1.TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case configv1alpha1.TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case configv1alpha1.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio(cfg)))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// samplingRatio returns the sampling ratio of cfg, the default ratio when it is not set.
+func samplingRatio(cfg configv1alpha1.TracingConfiguration) float64 {
+	if cfg.SamplingRatio == nil {
+		return configv1alpha1.DefaultTracingSamplingRatio
+	}
+	return *cfg.SamplingRatio
+}
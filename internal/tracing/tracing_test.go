@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+)
+
+var _ = Describe("Tracing Tests", func() {
+	It("Does not record spans without an exporter", func() {
+		shutdown, err := Setup(context.Background(), configv1alpha1.TracingConfiguration{Exporter: configv1alpha1.TracingExporterNone}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shutdown(context.Background())).To(Succeed())
+
+		_, span := otel.Tracer("test").Start(context.Background(), "noop")
+		defer span.End()
+		Expect(span.IsRecording()).To(BeFalse())
+	})
+
+	It("Writes spans to the stdout exporter", func() {
+		var out bytes.Buffer
+		shutdown, err := Setup(context.Background(), configv1alpha1.TracingConfiguration{
+			Exporter: configv1alpha1.TracingExporterStdout,
+		}, &out)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, span := otel.Tracer("test").Start(context.Background(), "AcrPullBindingReconciler.Reconcile")
+		Expect(span.IsRecording()).To(BeTrue())
+		span.End()
+		Expect(shutdown(context.Background())).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring("AcrPullBindingReconciler.Reconcile"))
+		Expect(out.String()).To(ContainSubstring(ServiceName))
+	})
+
+	It("Samples no reconcile with a sampling ratio of 0", func() {
+		samplingRatio := 0.0
+		shutdown, err := Setup(context.Background(), configv1alpha1.TracingConfiguration{
+			Exporter:      configv1alpha1.TracingExporterStdout,
+			SamplingRatio: &samplingRatio,
+		}, &bytes.Buffer{})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, span := otel.Tracer("test").Start(context.Background(), "AcrPullBindingReconciler.Reconcile")
+		Expect(span.IsRecording()).To(BeFalse())
+		span.End()
+		Expect(shutdown(context.Background())).To(Succeed())
+	})
+
+	It("Rejects an unknown exporter", func() {
+		_, err := Setup(context.Background(), configv1alpha1.TracingConfiguration{Exporter: "Zipkin"}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+	"github.com/Azure/msi-acrpull/internal/controller"
+)
+
+// PodWebhookPath is the path the pod mutating webhook is served on.
+const PodWebhookPath = "/mutate--v1-pod"
+
+//+kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,timeoutSeconds=5,groups="",resources=pods,verbs=create,versions=v1,name=mpod.msi-acrpull.microsoft.com,admissionReviewVersions=v1
+
+// PodImagePullSecretInjector adds the pull secrets of the AcrPullBindings of a namespace to the
+// imagePullSecrets of its pods, so that pulls do not depend on the service account being patched.
+type PodImagePullSecretInjector struct {
+	Client  client.Reader
+	Decoder *admission.Decoder
+	Log     logr.Logger
+	// Strict denies pods pulling from a registry whose bindings are all not ready.
+	Strict bool
+	// DefaultACRServer is the registry of bindings that do not set one.
+	DefaultACRServer string
+	// Clock is the time the credentials of bindings are checked for expiry at, the real clock when nil.
+	Clock clock.PassiveClock
+
+	// mu guards the fields that can be changed by ApplyConfiguration while handling requests.
+	mu sync.RWMutex
+}
+
+// NewPodImagePullSecretInjector returns an injector configured from cfg.
+func NewPodImagePullSecretInjector(c client.Reader, decoder *admission.Decoder, log logr.Logger, strict bool,
+	cfg *configv1alpha1.ControllerConfiguration) *PodImagePullSecretInjector {
+	i := &PodImagePullSecretInjector{
+		Client:  c,
+		Decoder: decoder,
+		Log:     log,
+		Strict:  strict,
+	}
+	i.ApplyConfiguration(cfg)
+	return i
+}
+
+// ApplyConfiguration updates the settings that can change while the webhook is running.
+func (i *PodImagePullSecretInjector) ApplyConfiguration(cfg *configv1alpha1.ControllerConfiguration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.DefaultACRServer = cfg.Defaults.ACRServer
+}
+
+func (i *PodImagePullSecretInjector) defaultACRServer() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return i.DefaultACRServer
+}
+
+// now returns the current time of the clock of the injector.
+func (i *PodImagePullSecretInjector) now() time.Time {
+	if i.Clock == nil {
+		return time.Now()
+	}
+	return i.Clock.Now()
+}
+
+// Handle injects the pull secrets of the bindings of the registries the pod pulls from.
+func (i *PodImagePullSecretInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &v1.Pod{}
+	if err := i.Decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log := i.Log.WithValues("namespace", req.Namespace, "pod", podName(pod))
+
+	registries := podRegistries(pod)
+	if len(registries) == 0 {
+		return admission.Allowed("pod has no images")
+	}
+
+	var acrBindings msiacrpullv1.AcrPullBindingList
+	if err := i.Client.List(ctx, &acrBindings, client.InNamespace(req.Namespace)); err != nil {
+		// the webhook fails open, pods fall back to the secrets of their service account
+		log.Error(err, "Failed to list acr pull bindings")
+		return admission.Allowed("acr pull bindings are not available")
+	}
+
+	pullSecrets, failed := i.pullSecretsFor(acrBindings.Items, registries)
+	if i.Strict && len(failed) > 0 {
+		return admission.Denied(fmt.Sprintf("AcrPullBindings for the registries of the pod are not ready: %s",
+			strings.Join(failed, "; ")))
+	}
+
+	injected := false
+	for _, name := range pullSecrets {
+		if hasPullSecret(pod, name) {
+			continue
+		}
+		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, v1.LocalObjectReference{Name: name})
+		injected = true
+	}
+	if !injected {
+		return admission.Allowed("no pull secrets to inject")
+	}
+
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	log.V(controller.LogLevelDebug).Info("Injecting image pull secrets", "pullSecrets", pullSecrets)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// pullSecretsFor returns the pull secrets of the ready bindings of registries and, for every
+// registry whose bindings are all not ready, a description of their Ready conditions. Bindings
+// the controller has not reconciled yet have no Ready condition and count as neither.
+func (i *PodImagePullSecretInjector) pullSecretsFor(acrBindings []msiacrpullv1.AcrPullBinding,
+	registries map[string]bool) ([]string, []string) {
+	now := i.now()
+	defaultACRServer := i.defaultACRServer()
+
+	var pullSecrets, failed []string
+	for registry := range registries {
+		ready := false
+		var errs []string
+		for idx := range acrBindings {
+			acrBinding := &acrBindings[idx]
+			if !acrBinding.DeletionTimestamp.IsZero() || !controller.BindsRegistry(acrBinding, defaultACRServer, registry) {
+				continue
+			}
+			condition := meta.FindStatusCondition(acrBinding.Status.Conditions, msiacrpullv1.ConditionReady)
+			switch {
+			case condition == nil:
+			case condition.Status == metav1.ConditionFalse:
+				errs = append(errs, fmt.Sprintf("%s: %s", acrBinding.Name, conditionMessage(condition)))
+			case condition.Status == metav1.ConditionTrue && acrBinding.Status.TokenExpirationTime != nil &&
+				now.Before(acrBinding.Status.TokenExpirationTime.Time):
+				ready = true
+				pullSecrets = appendUnique(pullSecrets, controller.PullSecretName(acrBinding))
+			}
+		}
+		if !ready && len(errs) > 0 {
+			failed = append(failed, fmt.Sprintf("%s (%s)", registry, strings.Join(errs, ", ")))
+		}
+	}
+	sort.Strings(pullSecrets)
+	sort.Strings(failed)
+	return pullSecrets, failed
+}
+
+// conditionMessage returns the message of condition, or its reason when it has none.
+func conditionMessage(condition *metav1.Condition) string {
+	if condition.Message != "" {
+		return condition.Message
+	}
+	return condition.Reason
+}
+
+// podRegistries returns the registries of all images of the pod.
+func podRegistries(pod *v1.Pod) map[string]bool {
+	registries := map[string]bool{}
+	for _, image := range controller.PodSpecImages(&pod.Spec) {
+		if registry := controller.ImageRegistry(image); registry != "" {
+			registries[registry] = true
+		}
+	}
+	return registries
+}
+
+func hasPullSecret(pod *v1.Pod, name string) bool {
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		if ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(names []string, name string) []string {
+	for _, existing := range names {
+		if existing == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// podName returns the name of the pod, or its generate name prefix as pods of a controller are
+// named by the API server after admission.
+func podName(pod *v1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return pod.GenerateName
+}
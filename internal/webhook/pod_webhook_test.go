@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	configv1alpha1 "github.com/Azure/msi-acrpull/api/config/v1alpha1"
+	msiacrpullv1 "github.com/Azure/msi-acrpull/api/v1"
+)
+
+var _ = msiacrpullv1.AddToScheme(scheme.Scheme)
+
+func readyBinding(name, acrServer string) *msiacrpullv1.AcrPullBinding {
+	return &msiacrpullv1.AcrPullBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       msiacrpullv1.AcrPullBindingSpec{AcrServer: acrServer},
+		Status: msiacrpullv1.AcrPullBindingStatus{
+			TokenExpirationTime: &metav1.Time{Time: time.Now().Add(time.Hour)},
+			Conditions: []metav1.Condition{{
+				Type:   msiacrpullv1.ConditionReady,
+				Status: metav1.ConditionTrue,
+				Reason: msiacrpullv1.ReasonCredentialIssued,
+			}},
+		},
+	}
+}
+
+func podRequest(pod *v1.Pod) admission.Request {
+	raw, err := json.Marshal(pod)
+	Expect(err).ToNot(HaveOccurred())
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func newInjector(strict bool, objs ...*msiacrpullv1.AcrPullBinding) *PodImagePullSecretInjector {
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, obj := range objs {
+		builder = builder.WithObjects(obj)
+	}
+	return NewPodImagePullSecretInjector(builder.Build(), admission.NewDecoder(scheme.Scheme),
+		ctrl.Log.WithName("webhook"), strict, &configv1alpha1.ControllerConfiguration{
+			Defaults: configv1alpha1.DefaultsConfiguration{ACRServer: "default.azurecr.io"},
+		})
+}
+
+var _ = Describe("Pod Webhook Tests", func() {
+	Context("Handle", func() {
+		It("Injects the pull secrets of the bindings of the pod registries", func() {
+			other := readyBinding("other", "other.azurecr.io")
+			aliased := readyBinding("aliased", "aliased.azurecr.io")
+			aliased.Spec.HostAliases = []string{"aliased.westeurope.data.azurecr.io"}
+			aliased.Spec.ExistingSecretName = "shared"
+			injector := newInjector(false, readyBinding("test", ""), other, aliased)
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec: v1.PodSpec{
+					InitContainers:   []v1.Container{{Name: "init", Image: "aliased.westeurope.data.azurecr.io/init:1.0"}},
+					Containers:       []v1.Container{{Name: "app", Image: "default.azurecr.io/app:1.0"}, {Name: "sidecar", Image: "nginx"}},
+					ImagePullSecrets: []v1.LocalObjectReference{{Name: "shared"}},
+				},
+			}
+			resp := injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Patches).To(HaveLen(1))
+			Expect(resp.Patches[0].Path).To(Equal("/spec/imagePullSecrets/1"))
+			Expect(resp.Patches[0].Value).To(Equal(map[string]interface{}{"name": "test-msi-acrpull-secret"}))
+		})
+
+		It("Does not inject the pull secret of a binding that is not ready", func() {
+			failed := readyBinding("test", "test.azurecr.io")
+			failed.Status.Conditions = []metav1.Condition{{
+				Type:    msiacrpullv1.ConditionReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  msiacrpullv1.ReasonReconcileFailed,
+				Message: "failed to get token",
+			}}
+			pending := readyBinding("pending", "test.azurecr.io")
+			pending.Status = msiacrpullv1.AcrPullBindingStatus{}
+			injector := newInjector(false, failed, pending)
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}}},
+			}
+			resp := injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Patches).To(BeEmpty())
+		})
+
+		It("Denies pods in strict mode when the Ready condition of the binding is False without an error", func() {
+			conflict := readyBinding("test", "test.azurecr.io")
+			conflict.Status.Conditions = []metav1.Condition{{
+				Type:   msiacrpullv1.ConditionReady,
+				Status: metav1.ConditionFalse,
+				Reason: msiacrpullv1.ReasonSecretConflict,
+			}}
+			injector := newInjector(true, conflict)
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}}},
+			}
+			resp := injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Message).To(ContainSubstring("test.azurecr.io (test: SecretConflict)"))
+		})
+
+		It("Does not inject the pull secret of a binding whose credential expired", func() {
+			injector := newInjector(false, readyBinding("test", "test.azurecr.io"))
+			injector.Clock = clocktesting.NewFakePassiveClock(time.Now().Add(2 * time.Hour))
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}}},
+			}
+			resp := injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeTrue())
+			Expect(resp.Patches).To(BeEmpty())
+		})
+
+		It("Denies pods pulling from a registry whose bindings are in error in strict mode", func() {
+			failed := readyBinding("test", "test.azurecr.io")
+			failed.Status.Conditions = []metav1.Condition{{
+				Type:    msiacrpullv1.ConditionReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  msiacrpullv1.ReasonReconcileFailed,
+				Message: "failed to get token",
+			}}
+			injector := newInjector(true, failed)
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "test.azurecr.io/app:1.0"}}},
+			}
+			resp := injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(resp.Result.Message).To(ContainSubstring("test.azurecr.io (test: failed to get token)"))
+
+			pod.Spec.Containers[0].Image = "mcr.microsoft.com/app:1.0"
+			resp = injector.Handle(context.Background(), podRequest(pod))
+			Expect(resp.Allowed).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
+)
+
+const (
+	defaultActiveDirectoryEndpoint = "https://login.microsoftonline.com/"
+	clientAssertionType            = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// EntraTokenRetriever is an instance of ApplicationTokenRetriever. It requests ARM tokens from
+// Microsoft Entra ID with the client credentials flow.
+type EntraTokenRetriever struct {
+	activeDirectoryEndpoint string
+	armResource             string
+	client                  *rateLimitedClient
+}
+
+// NewEntraTokenRetriever returns a new Microsoft Entra token retriever
+func NewEntraTokenRetriever() *EntraTokenRetriever {
+	return &EntraTokenRetriever{
+		activeDirectoryEndpoint: defaultActiveDirectoryEndpoint,
+		armResource:             defaultARMResource,
+		client:                  newRateLimitedClient(),
+	}
+}
+
+// AcquireARMTokenWithClientAssertion acquires an ARM access token for an application that trusts the
+// issuer of assertion, such as a service account token federated with a workload identity.
+func (er *EntraTokenRetriever) AcquireARMTokenWithClientAssertion(ctx context.Context, tenantID, clientID, assertion string) (_ types.AccessToken, err error) {
+	ctx, span := tracer().Start(ctx, "EntraTokenRetriever.AcquireARMTokenWithClientAssertion", trace.WithAttributes(IdentityAttribute(clientID)))
+	defer func() { endSpan(span, err) }()
+
+	parameters := url.Values{}
+	parameters.Add("client_assertion_type", clientAssertionType)
+	parameters.Add("client_assertion", assertion)
+	return er.requestToken(ctx, tenantID, clientID, parameters)
+}
+
+// AcquireARMTokenWithClientSecret acquires an ARM access token for an application authenticating with a client secret.
+func (er *EntraTokenRetriever) AcquireARMTokenWithClientSecret(ctx context.Context, tenantID, clientID, clientSecret string) (_ types.AccessToken, err error) {
+	ctx, span := tracer().Start(ctx, "EntraTokenRetriever.AcquireARMTokenWithClientSecret", trace.WithAttributes(IdentityAttribute(clientID)))
+	defer func() { endSpan(span, err) }()
+
+	parameters := url.Values{}
+	parameters.Add("client_secret", clientSecret)
+	return er.requestToken(ctx, tenantID, clientID, parameters)
+}
+
+func (er *EntraTokenRetriever) requestToken(ctx context.Context, tenantID, clientID string, parameters url.Values) (types.AccessToken, error) {
+	if tenantID == "" {
+		return "", fmt.Errorf("no tenant ID given for application %s", clientID)
+	}
+
+	endpoint := er.activeDirectoryEndpoint
+	if endpoint == "" {
+		endpoint = defaultActiveDirectoryEndpoint
+	}
+	tokenURL, err := url.JoinPath(endpoint, url.PathEscape(tenantID), "oauth2/v2.0/token")
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token endpoint url: %w", err)
+	}
+
+	armResource := er.armResource
+	if armResource == "" {
+		armResource = defaultARMResource
+	}
+	parameters.Add("grant_type", "client_credentials")
+	parameters.Add("client_id", clientID)
+	parameters.Add("scope", strings.TrimSuffix(armResource, "/")+"/.default")
+	body := parameters.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to construct token request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Length", strconv.Itoa(len(body)))
+
+	var resp *http.Response
+	defer closeResponse(resp)
+
+	resp, err = er.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send token request: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		responseBytes, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("Microsoft Entra token endpoint returned error status: %d. body: %s", resp.StatusCode, redact.String(string(responseBytes)))
+	}
+
+	responseBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResp tokenResponse
+	err = json.Unmarshal(responseBytes, &tokenResp)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal token response: %w", err)
+	}
+
+	return types.AccessToken(tokenResp.AccessToken), nil
+}
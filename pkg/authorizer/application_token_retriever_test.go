@@ -0,0 +1,109 @@
+package authorizer
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Entra Token Retriever Tests", func() {
+	var (
+		server *ghttp.Server
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("Retrieve ARM Token", func() {
+		It("Get ARM Token with Client Assertion Successfully", func() {
+			armToken, err := getTestArmToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/"+testTenantID+"/oauth2/v2.0/token"),
+					ghttp.VerifyForm(url.Values{
+						"grant_type":            {"client_credentials"},
+						"client_id":             {testClientID},
+						"scope":                 {"https://management.azure.com/.default"},
+						"client_assertion_type": {clientAssertionType},
+						"client_assertion":      {"service-account-token"},
+					}),
+					ghttp.RespondWithJSONEncoded(200, &tokenResponse{AccessToken: string(armToken)}),
+				))
+
+			er := newTestEntraTokenRetriever(server)
+			token, err := er.AcquireARMTokenWithClientAssertion(context.Background(), testTenantID, testClientID, "service-account-token")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.ReceivedRequests()).Should(HaveLen(1))
+			Expect(token).To(Equal(armToken))
+		})
+
+		It("Get ARM Token with Client Secret Against Custom ARM Resource Successfully", func() {
+			armToken, err := getTestArmToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/"+testTenantID+"/oauth2/v2.0/token"),
+					ghttp.VerifyForm(url.Values{
+						"grant_type":    {"client_credentials"},
+						"client_id":     {testClientID},
+						"scope":         {"https://management.usgovcloudapi.net/.default"},
+						"client_secret": {"secret"},
+					}),
+					ghttp.RespondWithJSONEncoded(200, &tokenResponse{AccessToken: string(armToken)}),
+				))
+
+			er := newTestEntraTokenRetriever(server)
+			er.armResource = "https://management.usgovcloudapi.net/"
+			token, err := er.AcquireARMTokenWithClientSecret(context.Background(), testTenantID, testClientID, "secret")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal(armToken))
+		})
+
+		It("Returns Error when the Token Endpoint Rejects the Request", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/"+testTenantID+"/oauth2/v2.0/token"),
+					ghttp.RespondWith(401, `{"error":"invalid_client"}`),
+				))
+
+			er := newTestEntraTokenRetriever(server)
+			token, err := er.AcquireARMTokenWithClientSecret(context.Background(), testTenantID, testClientID, "secret")
+
+			Expect(string(token)).To(BeEmpty())
+			Expect(err).To(MatchError(ContainSubstring("returned error status: 401")))
+		})
+
+		It("Returns Error without a Tenant", func() {
+			er := newTestEntraTokenRetriever(server)
+			_, err := er.AcquireARMTokenWithClientSecret(context.Background(), "", testClientID, "secret")
+
+			Expect(err).To(MatchError(ContainSubstring("no tenant ID")))
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+})
+
+func newTestEntraTokenRetriever(server *ghttp.Server) *EntraTokenRetriever {
+	client := newRateLimitedClient()
+	client.httpClient = server.HTTPTestServer.Client()
+
+	return &EntraTokenRetriever{
+		activeDirectoryEndpoint: server.URL(),
+		armResource:             defaultARMResource,
+		client:                  client,
+	}
+}
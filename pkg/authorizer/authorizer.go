@@ -1,7 +1,12 @@
 package authorizer
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/utils/clock"
 
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
 )
@@ -9,33 +14,175 @@ import (
 // Authorizer is an instance of authorizer
 type Authorizer struct {
 	tokenRetriever ManagedIdentityTokenRetriever
+	appRetriever   ApplicationTokenRetriever
 	tokenExchanger ACRTokenExchanger
+	verifier       ACRRegistryVerifier
+	clients        []*rateLimitedClient
+}
+
+// Options configures an authorizer. Zero values are replaced with defaults.
+type Options struct {
+	// MetadataEndpoint is the instance metadata service token endpoint.
+	MetadataEndpoint string
+	// ARMResource is the resource ARM tokens are requested for.
+	ARMResource string
+	// ActiveDirectoryEndpoint is the Microsoft Entra endpoint applications request ARM tokens from.
+	ActiveDirectoryEndpoint string
+	// CacheExpiration is how long an ARM token is reused.
+	CacheExpiration time.Duration
+	// RPS and Burst limit the requests sent to the metadata endpoint and to ACR.
+	RPS   float64
+	Burst int
+	// HTTPClient sends the requests, http.DefaultClient when nil. Tests use it to reach a fake server.
+	HTTPClient *http.Client
+	// Clock decides when cached ARM tokens expire, the real clock when nil.
+	Clock clock.PassiveClock
 }
 
 // NewAuthorizer returns an authorizer
 func NewAuthorizer() *Authorizer {
+	return NewAuthorizerWithOptions(Options{})
+}
+
+// NewAuthorizerWithOptions returns an authorizer configured with opts
+func NewAuthorizerWithOptions(opts Options) *Authorizer {
+	tokenRetriever := NewTokenRetrieverWithOptions(opts)
+	appRetriever := NewEntraTokenRetrieverWithOptions(opts)
+	tokenExchanger := NewTokenExchangerWithOptions(opts)
+	verifier := NewRegistryVerifierWithOptions(opts)
+
 	return &Authorizer{
-		tokenRetriever: NewTokenRetriever(),
-		tokenExchanger: NewTokenExchanger(),
+		tokenRetriever: tokenRetriever,
+		appRetriever:   appRetriever,
+		tokenExchanger: tokenExchanger,
+		verifier:       verifier,
+		clients:        []*rateLimitedClient{tokenRetriever.client, appRetriever.client, tokenExchanger.client, verifier.client},
+	}
+}
+
+// NewTokenRetrieverWithOptions returns a token retriever configured with opts
+func NewTokenRetrieverWithOptions(opts Options) *TokenRetriever {
+	tokenRetriever := NewTokenRetriever()
+	tokenRetriever.client = opts.newRateLimitedClient()
+	if opts.MetadataEndpoint != "" {
+		tokenRetriever.metadataEndpoint = opts.MetadataEndpoint
+	}
+	if opts.ARMResource != "" {
+		tokenRetriever.armResource = opts.ARMResource
+	}
+	if opts.CacheExpiration != 0 {
+		tokenRetriever.cacheExpiration = opts.CacheExpiration
+	}
+	if opts.Clock != nil {
+		tokenRetriever.clock = opts.Clock
+	}
+	return tokenRetriever
+}
+
+// NewEntraTokenRetrieverWithOptions returns a Microsoft Entra token retriever configured with opts
+func NewEntraTokenRetrieverWithOptions(opts Options) *EntraTokenRetriever {
+	appRetriever := NewEntraTokenRetriever()
+	appRetriever.client = opts.newRateLimitedClient()
+	if opts.ActiveDirectoryEndpoint != "" {
+		appRetriever.activeDirectoryEndpoint = opts.ActiveDirectoryEndpoint
+	}
+	if opts.ARMResource != "" {
+		appRetriever.armResource = opts.ARMResource
+	}
+	return appRetriever
+}
+
+// NewTokenExchangerWithOptions returns a token exchanger configured with opts
+func NewTokenExchangerWithOptions(opts Options) *TokenExchanger {
+	tokenExchanger := NewTokenExchanger()
+	tokenExchanger.client = opts.newRateLimitedClient()
+	return tokenExchanger
+}
+
+// NewRegistryVerifierWithOptions returns a registry verifier configured with opts
+func NewRegistryVerifierWithOptions(opts Options) *RegistryVerifier {
+	registryVerifier := NewRegistryVerifier()
+	registryVerifier.client = opts.newRateLimitedClient()
+	return registryVerifier
+}
+
+func (opts Options) newRateLimitedClient() *rateLimitedClient {
+	rps, burst := opts.RPS, opts.Burst
+	if rps == 0 {
+		rps = defaultRPS
+	}
+	if burst == 0 {
+		burst = defaultBurst
+	}
+	client := newRateLimitedClientWithRPS(rps, burst)
+	if opts.HTTPClient != nil {
+		client.httpClient = opts.HTTPClient
+	}
+	return client
+}
+
+// SetRateLimit changes the rate limit of requests to the metadata endpoint and to ACR.
+func (az *Authorizer) SetRateLimit(rps float64, burst int) {
+	for _, client := range az.clients {
+		client.setLimit(rps, burst)
 	}
 }
 
 // AcquireACRAccessTokenWithResourceID acquires ACR access token using managed identity resource ID (/subscriptions/{id}/resourceGroups/{group}/providers/Microsoft.ManagedIdentity/userAssignedIdentities/{name}).
-func (az *Authorizer) AcquireACRAccessTokenWithResourceID(identityResourceID string, acrFQDN string) (types.AccessToken, error) {
-	armToken, err := az.tokenRetriever.AcquireARMToken("", identityResourceID)
+func (az *Authorizer) AcquireACRAccessTokenWithResourceID(ctx context.Context, identityResourceID string, acrFQDN string) (types.AccessToken, error) {
+	armToken, err := az.tokenRetriever.AcquireARMToken(ctx, "", identityResourceID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get ARM access token: %w", err)
 	}
 
-	return az.tokenExchanger.ExchangeACRAccessToken(armToken, acrFQDN)
+	return az.tokenExchanger.ExchangeACRAccessToken(ctx, armToken, acrFQDN)
 }
 
 // AcquireACRAccessTokenWithClientID acquires ACR access token using managed identity client ID.
-func (az *Authorizer) AcquireACRAccessTokenWithClientID(clientID string, acrFQDN string) (types.AccessToken, error) {
-	armToken, err := az.tokenRetriever.AcquireARMToken(clientID, "")
+func (az *Authorizer) AcquireACRAccessTokenWithClientID(ctx context.Context, clientID string, acrFQDN string) (types.AccessToken, error) {
+	armToken, err := az.tokenRetriever.AcquireARMToken(ctx, clientID, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get ARM access token: %w", err)
+	}
+
+	return az.tokenExchanger.ExchangeACRAccessToken(ctx, armToken, acrFQDN)
+}
+
+// AcquireACRAccessTokenWithClientAssertion acquires ACR access token using a Microsoft Entra application that
+// trusts the issuer of assertion, such as a workload identity federated with a service account.
+func (az *Authorizer) AcquireACRAccessTokenWithClientAssertion(ctx context.Context, tenantID, clientID, assertion string, acrFQDN string) (types.AccessToken, error) {
+	armToken, err := az.appRetriever.AcquireARMTokenWithClientAssertion(ctx, tenantID, clientID, assertion)
 	if err != nil {
 		return "", fmt.Errorf("failed to get ARM access token: %w", err)
 	}
 
-	return az.tokenExchanger.ExchangeACRAccessToken(armToken, acrFQDN)
+	return az.tokenExchanger.ExchangeACRAccessToken(ctx, armToken, acrFQDN)
+}
+
+// AcquireACRAccessTokenWithClientSecret acquires ACR access token using a Microsoft Entra application
+// authenticating with a client secret.
+func (az *Authorizer) AcquireACRAccessTokenWithClientSecret(ctx context.Context, tenantID, clientID, clientSecret string, acrFQDN string) (types.AccessToken, error) {
+	armToken, err := az.appRetriever.AcquireARMTokenWithClientSecret(ctx, tenantID, clientID, clientSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to get ARM access token: %w", err)
+	}
+
+	return az.tokenExchanger.ExchangeACRAccessToken(ctx, armToken, acrFQDN)
+}
+
+// CheckMetadataEndpoint checks that the instance metadata service ARM tokens are requested from answers.
+func (az *Authorizer) CheckMetadataEndpoint(ctx context.Context) error {
+	return az.tokenRetriever.CheckMetadataEndpoint(ctx)
+}
+
+// VerifyPullAccess checks that the registry accepts the ACR access token and, when probeImage
+// is not empty, that the token grants pull access to probeImage.
+func (az *Authorizer) VerifyPullAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string, probeImage string) error {
+	if err := az.verifier.VerifyRegistryAccess(ctx, acrToken, acrFQDN); err != nil {
+		return err
+	}
+	if probeImage == "" {
+		return nil
+	}
+	return az.verifier.VerifyImageAccess(ctx, acrToken, acrFQDN, probeImage)
 }
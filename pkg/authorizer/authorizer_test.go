@@ -1,13 +1,20 @@
 package authorizer
 
 import (
+	"context"
 	"errors"
 	"time"
 
+	"github.com/Azure/msi-acrpull/pkg/authorizer/fake"
 	"github.com/Azure/msi-acrpull/pkg/authorizer/mock_authorizer"
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
 
 	"github.com/golang/mock/gomock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -22,6 +29,30 @@ var _ = Describe("Authorizer Tests", func() {
 		mockCtrl = gomock.NewController(GinkgoT())
 	})
 
+	Context("New Authorizer With Options", func() {
+		It("Applies options and rate limit changes", func() {
+			az := NewAuthorizerWithOptions(Options{
+				MetadataEndpoint: "http://localhost/token",
+				ARMResource:      "https://management.usgovcloudapi.net/",
+				CacheExpiration:  time.Minute,
+				RPS:              10,
+				Burst:            20,
+			})
+
+			tr := az.tokenRetriever.(*TokenRetriever)
+			Expect(tr.metadataEndpoint).To(Equal("http://localhost/token"))
+			Expect(tr.armResource).To(Equal("https://management.usgovcloudapi.net/"))
+			Expect(tr.cacheExpiration).To(Equal(time.Minute))
+			Expect(tr.client.rateLimiter.Burst()).To(Equal(20))
+
+			az.SetRateLimit(2, 3)
+			for _, client := range az.clients {
+				Expect(float64(client.rateLimiter.Limit())).To(Equal(float64(2)))
+				Expect(client.rateLimiter.Burst()).To(Equal(3))
+			}
+		})
+	})
+
 	Context("Acquire ACR Access Token With ResourceID", func() {
 		It("Get ACR Token with Resource ID Successfully", func() {
 			armToken, err := getTestArmToken(time.Now().Add(time.Hour).Unix(), signingKey)
@@ -38,10 +69,10 @@ var _ = Describe("Authorizer Tests", func() {
 				tokenExchanger: te,
 			}
 
-			tr.EXPECT().AcquireARMToken("", testResourceID).Return(armToken, nil).Times(1)
-			te.EXPECT().ExchangeACRAccessToken(armToken, testACR).Return(acrToken, nil).Times(1)
+			tr.EXPECT().AcquireARMToken(gomock.Any(), "", testResourceID).Return(armToken, nil).Times(1)
+			te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil).Times(1)
 
-			t, err := az.AcquireACRAccessTokenWithResourceID(testResourceID, testACR)
+			t, err := az.AcquireACRAccessTokenWithResourceID(context.Background(), testResourceID, testACR)
 			Expect(err).To(BeNil())
 			Expect(t).NotTo(BeNil())
 			Expect(t).To(Equal(acrToken))
@@ -62,10 +93,10 @@ var _ = Describe("Authorizer Tests", func() {
 				tokenExchanger: te,
 			}
 
-			tr.EXPECT().AcquireARMToken(testClientID, "").Return(armToken, nil).Times(1)
-			te.EXPECT().ExchangeACRAccessToken(armToken, testACR).Return(acrToken, nil).Times(1)
+			tr.EXPECT().AcquireARMToken(gomock.Any(), testClientID, "").Return(armToken, nil).Times(1)
+			te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil).Times(1)
 
-			t, err := az.AcquireACRAccessTokenWithClientID(testClientID, testACR)
+			t, err := az.AcquireACRAccessTokenWithClientID(context.Background(), testClientID, testACR)
 			Expect(err).To(BeNil())
 			Expect(t).NotTo(BeNil())
 			Expect(t).To(Equal(acrToken))
@@ -80,12 +111,154 @@ var _ = Describe("Authorizer Tests", func() {
 				tokenExchanger: te,
 			}
 
-			tr.EXPECT().AcquireARMToken(testClientID, "").Return(types.AccessToken(""), errors.New("test error")).Times(1)
+			tr.EXPECT().AcquireARMToken(gomock.Any(), testClientID, "").Return(types.AccessToken(""), errors.New("test error")).Times(1)
 
-			t, err := az.AcquireACRAccessTokenWithClientID(testClientID, testACR)
+			t, err := az.AcquireACRAccessTokenWithClientID(context.Background(), testClientID, testACR)
 			Expect(string(t)).To(Equal(""))
 			Expect(err).NotTo(BeNil())
 			Expect(err.Error()).To(ContainSubstring("test error"))
 		})
 	})
+
+	Context("Acquire ACR Access Token With an Application", func() {
+		It("Get ACR Token with Client Assertion Successfully", func() {
+			armToken, err := getTestArmToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			acrToken, err := getTestAcrToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			ar := mock_authorizer.NewMockApplicationTokenRetriever(mockCtrl)
+			te := mock_authorizer.NewMockACRTokenExchanger(mockCtrl)
+
+			az := &Authorizer{
+				appRetriever:   ar,
+				tokenExchanger: te,
+			}
+
+			ar.EXPECT().AcquireARMTokenWithClientAssertion(gomock.Any(), testTenantID, testClientID, "assertion").Return(armToken, nil).Times(1)
+			te.EXPECT().ExchangeACRAccessToken(gomock.Any(), armToken, testACR).Return(acrToken, nil).Times(1)
+
+			t, err := az.AcquireACRAccessTokenWithClientAssertion(context.Background(), testTenantID, testClientID, "assertion", testACR)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t).To(Equal(acrToken))
+		})
+
+		It("Returns Error when the Client Secret is Rejected", func() {
+			ar := mock_authorizer.NewMockApplicationTokenRetriever(mockCtrl)
+			te := mock_authorizer.NewMockACRTokenExchanger(mockCtrl)
+
+			az := &Authorizer{
+				appRetriever:   ar,
+				tokenExchanger: te,
+			}
+
+			ar.EXPECT().AcquireARMTokenWithClientSecret(gomock.Any(), testTenantID, testClientID, "secret").Return(types.AccessToken(""), errors.New("test error")).Times(1)
+
+			_, err := az.AcquireACRAccessTokenWithClientSecret(context.Background(), testTenantID, testClientID, "secret", testACR)
+			Expect(err).To(MatchError(ContainSubstring("test error")))
+		})
+	})
+
+	Context("Check Metadata Endpoint", func() {
+		It("Returns the result of the token retriever", func() {
+			tr := mock_authorizer.NewMockManagedIdentityTokenRetriever(mockCtrl)
+
+			az := &Authorizer{
+				tokenRetriever: tr,
+			}
+
+			tr.EXPECT().CheckMetadataEndpoint(gomock.Any()).Return(errors.New("test error")).Times(1)
+
+			err := az.CheckMetadataEndpoint(context.Background())
+			Expect(err).To(MatchError("test error"))
+		})
+	})
+
+	Context("Verify Pull Access", func() {
+		It("Checks the registry and the probe image", func() {
+			acrToken, err := getTestAcrToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			rv := mock_authorizer.NewMockACRRegistryVerifier(mockCtrl)
+			az := &Authorizer{
+				verifier: rv,
+			}
+
+			rv.EXPECT().VerifyRegistryAccess(gomock.Any(), acrToken, testACR).Return(nil).Times(2)
+			rv.EXPECT().VerifyImageAccess(gomock.Any(), acrToken, testACR, "probe/image:1.0").Return(nil).Times(1)
+
+			Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "")).To(Succeed())
+			Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "probe/image:1.0")).To(Succeed())
+		})
+
+		It("Skips the probe image when the registry rejects the token", func() {
+			acrToken, err := getTestAcrToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			rv := mock_authorizer.NewMockACRRegistryVerifier(mockCtrl)
+			az := &Authorizer{
+				verifier: rv,
+			}
+
+			rv.EXPECT().VerifyRegistryAccess(gomock.Any(), acrToken, testACR).Return(errors.New("test error")).Times(1)
+
+			err = az.VerifyPullAccess(context.Background(), acrToken, testACR, "probe/image:1.0")
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("test error"))
+		})
+	})
+
+	Context("Against the fake server", func() {
+		It("Reuses the ARM token of an identity", func() {
+			server := fake.NewServer()
+			defer server.Close()
+			identity := server.AddIdentity("test-mi")
+			server.AddRegistry(testACR)
+			server.AssignRole(identity.ClientID, testACR, fake.RoleAcrPull)
+
+			az := NewAuthorizerWithOptions(Options{
+				MetadataEndpoint: server.MetadataEndpoint(),
+				HTTPClient:       server.Client(),
+			})
+			for i := 0; i < 2; i++ {
+				acrToken, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "")).To(Succeed())
+			}
+			Expect(server.Requests(fake.EndpointIMDS)).To(Equal(1))
+			Expect(server.Requests(fake.EndpointExchange)).To(Equal(2))
+		})
+
+		It("Records a span for each token call and request", func() {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+			server := fake.NewServer()
+			defer server.Close()
+			identity := server.AddIdentity("test-mi")
+			server.AddRegistry(testACR)
+			server.AssignRole(identity.ClientID, testACR, fake.RoleAcrPull)
+
+			az := NewAuthorizerWithOptions(Options{
+				MetadataEndpoint: server.MetadataEndpoint(),
+				HTTPClient:       server.Client(),
+			})
+			_, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+			Expect(err).ToNot(HaveOccurred())
+
+			spans := map[string]sdktrace.ReadOnlySpan{}
+			for _, span := range recorder.Ended() {
+				spans[span.Name()] = span
+			}
+			Expect(spans).To(HaveKey("TokenRetriever.AcquireARMToken"))
+			Expect(spans["TokenRetriever.AcquireARMToken"].Attributes()).To(ContainElement(IdentityAttribute(identity.ClientID)))
+			Expect(spans).To(HaveKey("TokenExchanger.ExchangeACRAccessToken"))
+			Expect(spans["TokenExchanger.ExchangeACRAccessToken"].Attributes()).To(ContainElement(RegistryAttribute(testACR)))
+			Expect(spans).To(HaveKey("rateLimitedClient.Do"))
+			Expect(spans["rateLimitedClient.Do"].Attributes()).To(ContainElement(semconv.HTTPResponseStatusCode(200)))
+			Expect(spans["rateLimitedClient.Do"].Parent().SpanID()).To(Equal(spans["TokenExchanger.ExchangeACRAccessToken"].SpanContext().SpanID()))
+		})
+	})
 })
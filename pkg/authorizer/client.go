@@ -1,10 +1,13 @@
 package authorizer
 
 import (
-	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
@@ -30,16 +33,33 @@ func newRateLimitedClientWithRPS(rps float64, burst int) *rateLimitedClient {
 	return client
 }
 
-func (client *rateLimitedClient) Do(req *http.Request) (*http.Response, error) {
-	ctx := context.Background()
-	err := client.rateLimiter.Wait(ctx)
+func (client *rateLimitedClient) setLimit(rps float64, burst int) {
+	client.rateLimiter.SetLimit(rate.Limit(rps))
+	client.rateLimiter.SetBurst(burst)
+}
+
+// Do waits for the rate limiter and sends req. The wait is bounded by the context of req, and
+// recorded on the span of the request together with its response status.
+func (client *rateLimitedClient) Do(req *http.Request) (resp *http.Response, err error) {
+	ctx, span := tracer().Start(req.Context(), "rateLimitedClient.Do", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		))
+	defer func() { endSpan(span, err) }()
+
+	waitStart := time.Now()
+	err = client.rateLimiter.Wait(ctx)
+	span.SetAttributes(attribute.Int64("ratelimit.wait_ms", time.Since(waitStart).Milliseconds()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to wait for rate limit token: %w", err)
 	}
 
-	resp, err := client.httpClient.Do(req)
+	resp, err = client.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 	return resp, nil
 }
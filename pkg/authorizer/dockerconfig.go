@@ -0,0 +1,150 @@
+package authorizer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// DockerConfigEntry is the credential of a single registry host.
+type DockerConfigEntry struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// DockerConfigEntries maps registry hosts to their credentials. It is the content of a legacy
+// .dockercfg file.
+type DockerConfigEntries map[string]DockerConfigEntry
+
+// DockerConfigJSON is the content of a .docker/config.json file.
+type DockerConfigJSON struct {
+	Auths DockerConfigEntries `json:"auths"`
+}
+
+// DockerConfigBuilder builds docker registry credentials from ACR tokens.
+type DockerConfigBuilder struct {
+	// IdentityToken writes the ACR refresh token as an identitytoken, which clients exchange for
+	// access tokens themselves, instead of as a username and password.
+	IdentityToken bool
+
+	entries DockerConfigEntries
+}
+
+// NewDockerConfigBuilder returns an empty builder.
+func NewDockerConfigBuilder() *DockerConfigBuilder {
+	return &DockerConfigBuilder{
+		entries: DockerConfigEntries{},
+	}
+}
+
+// Add adds the credential for accessToken under acrFQDN and every host alias of the registry,
+// such as geo-replica or data endpoint host names.
+func (b *DockerConfigBuilder) Add(acrFQDN string, accessToken types.AccessToken, hostAliases ...string) *DockerConfigBuilder {
+	var entry DockerConfigEntry
+	if b.IdentityToken {
+		entry = DockerConfigEntry{
+			Username:      acrUsername,
+			IdentityToken: string(accessToken),
+		}
+	} else {
+		entry = DockerConfigEntry{
+			Username: acrUsername,
+			Password: string(accessToken),
+			Auth:     base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", acrUsername, accessToken))),
+		}
+	}
+
+	for _, host := range append([]string{acrFQDN}, hostAliases...) {
+		if host != "" {
+			b.entries[host] = entry
+		}
+	}
+	return b
+}
+
+// DockerConfigJSON returns the credentials in the .docker/config.json format used by
+// kubernetes.io/dockerconfigjson secrets.
+func (b *DockerConfigBuilder) DockerConfigJSON() ([]byte, error) {
+	return json.Marshal(DockerConfigJSON{Auths: b.entries})
+}
+
+// DockerCfg returns the credentials in the legacy .dockercfg format used by
+// kubernetes.io/dockercfg secrets.
+func (b *DockerConfigBuilder) DockerCfg() ([]byte, error) {
+	return json.Marshal(b.entries)
+}
+
+// MergeDockerConfigJSON writes the credentials into an existing .docker/config.json, replacing
+// the entries of the same hosts and removing the entries of removeHosts. Other entries and
+// fields are left untouched.
+func (b *DockerConfigBuilder) MergeDockerConfigJSON(existing []byte, removeHosts []string) ([]byte, error) {
+	config := map[string]json.RawMessage{}
+	if len(existing) > 0 {
+		if err := json.Unmarshal(existing, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse docker config: %w", err)
+		}
+	}
+
+	auths, err := b.merge(config["auths"], removeHosts)
+	if err != nil {
+		return nil, err
+	}
+	config["auths"] = auths
+	return json.Marshal(config)
+}
+
+// MergeDockerCfg is MergeDockerConfigJSON for the legacy .dockercfg format.
+func (b *DockerConfigBuilder) MergeDockerCfg(existing []byte, removeHosts []string) ([]byte, error) {
+	return b.merge(existing, removeHosts)
+}
+
+func (b *DockerConfigBuilder) merge(existing []byte, removeHosts []string) ([]byte, error) {
+	entries := map[string]json.RawMessage{}
+	if len(existing) > 0 {
+		if err := json.Unmarshal(existing, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse docker config entries: %w", err)
+		}
+	}
+
+	for _, host := range removeHosts {
+		delete(entries, host)
+	}
+	for host, entry := range b.entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		entries[host] = data
+	}
+	return json.Marshal(entries)
+}
+
+// Token returns the ACR token of the entry: its identity token, or its password.
+func (e DockerConfigEntry) Token() types.AccessToken {
+	if e.IdentityToken != "" {
+		return types.AccessToken(e.IdentityToken)
+	}
+	return types.AccessToken(e.Password)
+}
+
+// ParseDockerConfigJSON returns the entries of a .docker/config.json.
+func ParseDockerConfigJSON(data []byte) (DockerConfigEntries, error) {
+	var config DockerConfigJSON
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+	return config.Auths, nil
+}
+
+// ParseDockerCfg returns the entries of a legacy .dockercfg.
+func ParseDockerCfg(data []byte) (DockerConfigEntries, error) {
+	var entries DockerConfigEntries
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config entries: %w", err)
+	}
+	return entries, nil
+}
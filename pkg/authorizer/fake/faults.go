@@ -0,0 +1,118 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Endpoint is an endpoint of the server faults can be injected into.
+type Endpoint string
+
+const (
+	// EndpointIMDS is the instance metadata service token endpoint.
+	EndpointIMDS Endpoint = "imds"
+	// EndpointExchange is the ACR /oauth2/exchange endpoint issuing refresh tokens.
+	EndpointExchange Endpoint = "exchange"
+	// EndpointToken is the ACR /oauth2/token endpoint issuing access tokens.
+	EndpointToken Endpoint = "token"
+	// EndpointRegistry is the ACR /v2/ distribution API.
+	EndpointRegistry Endpoint = "registry"
+)
+
+// Fault changes how an endpoint responds.
+type Fault struct {
+	// Endpoint is the endpoint the fault applies to, every endpoint when empty.
+	Endpoint Endpoint
+	// StatusCode is returned instead of handling the request when not zero. Throttling responses
+	// carry a Retry-After header of RetryAfter.
+	StatusCode int
+	// RetryAfter is the Retry-After of a 429 response, one second when zero.
+	RetryAfter time.Duration
+	// Latency delays the response, or the error response when StatusCode is set.
+	Latency time.Duration
+	// Times is the number of requests the fault applies to, all requests until ClearFaults when zero.
+	Times int
+}
+
+// Throttle returns a fault answering the next times requests to endpoint with 429 Too Many Requests.
+func Throttle(endpoint Endpoint, times int) Fault {
+	return Fault{Endpoint: endpoint, StatusCode: http.StatusTooManyRequests, Times: times}
+}
+
+// Unavailable returns a fault answering the next times requests to endpoint with 503 Service Unavailable.
+func Unavailable(endpoint Endpoint, times int) Fault {
+	return Fault{Endpoint: endpoint, StatusCode: http.StatusServiceUnavailable, Times: times}
+}
+
+// Slow returns a fault delaying every response of endpoint by latency.
+func Slow(endpoint Endpoint, latency time.Duration) Fault {
+	return Fault{Endpoint: endpoint, Latency: latency}
+}
+
+// InjectFault adds a fault. Faults apply in the order they were added, and only the first fault
+// matching a request applies to it.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// nextFault counts a request to endpoint and returns the fault that applies to it, if any.
+func (s *Server) nextFault(endpoint Endpoint) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[endpoint]++
+	for i, fault := range s.faults {
+		if fault.Endpoint != "" && fault.Endpoint != endpoint {
+			continue
+		}
+		applied := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &applied
+	}
+	return nil
+}
+
+// handle wraps the handler of endpoint with fault injection.
+func (s *Server) handle(endpoint Endpoint, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := s.nextFault(endpoint)
+		if fault == nil {
+			handler(w, r)
+			return
+		}
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode == 0 {
+			handler(w, r)
+			return
+		}
+		if fault.StatusCode == http.StatusTooManyRequests {
+			retryAfter := fault.RetryAfter
+			if retryAfter == 0 {
+				retryAfter = time.Second
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		}
+		writeError(w, fault.StatusCode, "injected_fault", fmt.Sprintf("injected %d response", fault.StatusCode))
+	})
+}
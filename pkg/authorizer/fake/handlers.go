@@ -0,0 +1,324 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+// roleActions are the repository actions granted by each role.
+var roleActions = map[Role][]string{
+	RoleAcrPull: {"pull"},
+	RoleAcrPush: {"pull", "push"},
+}
+
+// serveIMDS issues ARM tokens like the token endpoint of the instance metadata service.
+func (s *Server) serveIMDS(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != MetadataPath {
+		writeError(w, http.StatusNotFound, "not_found", "unknown path")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "only GET is supported")
+		return
+	}
+	if r.Header.Get("Metadata") != "true" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Required metadata header not specified")
+		return
+	}
+	query := r.URL.Query()
+	resource := query.Get("resource")
+	if resource == "" || query.Get("api-version") == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Required query variable 'resource' or 'api-version' is missing")
+		return
+	}
+	resourceID := query.Get("mi_res_id")
+	if resourceID == "" {
+		resourceID = query.Get("msi_res_id")
+	}
+	identity := s.findIdentity(query.Get("client_id"), query.Get("object_id"), resourceID)
+	if identity == nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Identity not found")
+		return
+	}
+
+	now := s.Clock.Now()
+	expiresAt := now.Add(s.ARMTokenLifetime)
+	token, err := s.sign(&types.ARMClaims{
+		Claims: types.Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    fmt.Sprintf("https://sts.windows.net/%s/", s.TenantID),
+			Subject:   identity.ObjectID,
+			Audience:  jwt.ClaimStrings{resource},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewString(),
+		}},
+		TenantID:                  s.TenantID,
+		ObjectID:                  identity.ObjectID,
+		ManagedIdentityResourceID: identity.ResourceID,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{
+		"access_token": token,
+		"client_id":    identity.ClientID,
+		"expires_in":   strconv.Itoa(int(s.ARMTokenLifetime.Seconds())),
+		"expires_on":   strconv.FormatInt(expiresAt.Unix(), 10),
+		"not_before":   strconv.FormatInt(now.Unix(), 10),
+		"resource":     resource,
+		"token_type":   "Bearer",
+	})
+}
+
+// serveExchange exchanges an ARM token for an ACR refresh token. Like ACR, it issues refresh tokens to
+// any identity of the tenant; role assignments are checked when the refresh token is redeemed.
+func (s *Server) serveExchange(w http.ResponseWriter, r *http.Request) {
+	registryName, ok := s.parseTokenRequest(w, r, "access_token")
+	if !ok {
+		return
+	}
+	armClaims := &types.ARMClaims{}
+	if err := s.verify(r.PostForm.Get("access_token"), armClaims); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	if err := armClaims.ValidateAudience(s.ARMResource); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	if err := armClaims.ValidateTenant(r.PostForm.Get("tenant")); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	now := s.Clock.Now()
+	token, err := s.sign(&types.ACRClaims{
+		Claims: types.Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ACRIssuer,
+			Subject:   armClaims.ObjectID,
+			Audience:  jwt.ClaimStrings{registryName},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.RefreshTokenLifetime)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewString(),
+		}},
+		TenantID:  armClaims.TenantID,
+		GrantType: "refresh_token",
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"refresh_token": token})
+}
+
+// serveToken redeems an ACR refresh token for an access token granting the requested repository
+// actions the role of the identity allows.
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	registryName, ok := s.parseTokenRequest(w, r, "refresh_token")
+	if !ok {
+		return
+	}
+	refreshClaims, err := s.verifyACRToken(r.PostForm.Get("refresh_token"), registryName, "refresh_token")
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	role, ok := s.role(registryName, refreshClaims.Subject)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "the identity has no role assigned on the registry")
+		return
+	}
+
+	var access []types.ACRAccess
+	for _, scope := range strings.Fields(r.PostForm.Get("scope")) {
+		parts := strings.Split(scope, ":")
+		if len(parts) != 3 || parts[0] != "repository" {
+			writeError(w, http.StatusBadRequest, "invalid_scope", fmt.Sprintf("invalid scope %q", scope))
+			return
+		}
+		var actions []string
+		for _, action := range strings.Split(parts[2], ",") {
+			if containsString(roleActions[role], action) {
+				actions = append(actions, action)
+			}
+		}
+		access = append(access, types.ACRAccess{Type: parts[0], Name: parts[1], Actions: actions})
+	}
+
+	now := s.Clock.Now()
+	token, err := s.sign(&types.ACRClaims{
+		Claims: types.Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ACRIssuer,
+			Subject:   refreshClaims.Subject,
+			Audience:  jwt.ClaimStrings{registryName},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.AccessTokenLifetime)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewString(),
+		}},
+		TenantID:  refreshClaims.TenantID,
+		GrantType: "access_token",
+		Access:    access,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"access_token": token})
+}
+
+// serveRegistry serves the base endpoint and the manifests of the distribution API.
+func (s *Server) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	registryName := requestHost(r)
+	if !s.hasRegistry(registryName) {
+		writeError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("registry %s does not exist", registryName))
+		return
+	}
+	bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	var claims *types.ACRClaims
+	var err error
+	if found {
+		claims, err = s.verifyACRToken(bearer, registryName, "access_token")
+	}
+	if !found || err != nil {
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="https://%s/oauth2/token",service="%s"`, registryName, registryName))
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+		return
+	}
+
+	if r.URL.Path == "/v2/" {
+		writeJSON(w, map[string]string{})
+		return
+	}
+	repository, reference, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+	if !found || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		writeError(w, http.StatusNotFound, "UNSUPPORTED", "only manifests are served")
+		return
+	}
+	if !claims.HasAction("repository", repository, "pull") {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "pull access to the repository is not granted")
+		return
+	}
+	if !s.hasManifest(registryName, repository, reference) {
+		writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseTokenRequest checks the form of a request to an ACR token endpoint and returns the registry
+// it is for.
+func (s *Server) parseTokenRequest(w http.ResponseWriter, r *http.Request, grantType string) (string, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request", "only POST is supported")
+		return "", false
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return "", false
+	}
+	if r.PostForm.Get("grant_type") != grantType {
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant_type must be %s", grantType))
+		return "", false
+	}
+	registryName := requestHost(r)
+	if !s.hasRegistry(registryName) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("registry %s does not exist", registryName))
+		return "", false
+	}
+	if !strings.EqualFold(r.PostForm.Get("service"), registryName) {
+		writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("service must be %s", registryName))
+		return "", false
+	}
+	return registryName, true
+}
+
+// verifyACRToken checks the signature, expiry, audience and grant type of an ACR token.
+func (s *Server) verifyACRToken(token, registryName, grantType string) (*types.ACRClaims, error) {
+	claims := &types.ACRClaims{}
+	if err := s.verify(token, claims); err != nil {
+		return nil, err
+	}
+	if err := claims.ValidateAudience(registryName); err != nil {
+		return nil, err
+	}
+	if claims.GrantType != grantType {
+		return nil, fmt.Errorf("token grant type %s is not %s", claims.GrantType, grantType)
+	}
+	return claims, nil
+}
+
+func (s *Server) sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.key)
+}
+
+// verify checks the signature and the time claims of a token issued by the server.
+func (s *Server) verify(token string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return &s.key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithTimeFunc(s.Clock.Now))
+	return err
+}
+
+func (s *Server) hasRegistry(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.registries[name]
+	return ok
+}
+
+func (s *Server) role(registryName, objectID string) (Role, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.registries[registryName].roles[objectID]
+	return role, ok
+}
+
+func (s *Server) hasManifest(registryName, repository, reference string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.registries[registryName].repositories[repository][reference]
+}
+
+// requestHost returns the lower case host of a request without its port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
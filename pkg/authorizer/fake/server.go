@@ -0,0 +1,277 @@
+// Package fake runs an in-process instance metadata service and Azure Container Registry for tests.
+//
+// The server issues signed ARM tokens for the identities added to it, exchanges them for ACR refresh
+// and access tokens, and serves the registry endpoints used to verify pull access. Role assignments
+// decide which identities may pull from which registries, and faults such as throttling, server
+// errors and latency can be injected per endpoint. The client returned by Client routes every
+// request, whatever its host, to the server, so the real authorizer can be pointed at it unchanged:
+//
+//	server := fake.NewServer()
+//	defer server.Close()
+//	identity := server.AddIdentity("my-acr-puller")
+//	server.AddRegistry("test.azurecr.io")
+//	server.AssignRole(identity.ClientID, "test.azurecr.io", fake.RoleAcrPull)
+//	az := authorizer.NewAuthorizerWithOptions(authorizer.Options{HTTPClient: server.Client()})
+package fake
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultTenantID is the tenant of the server unless TenantID is changed.
+	DefaultTenantID = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+	// DefaultARMResource is the ARM resource tokens are issued for unless ARMResource is changed.
+	DefaultARMResource = "https://management.azure.com/"
+	// MetadataPath is the path of the instance metadata service token endpoint.
+	MetadataPath = "/metadata/identity/oauth2/token"
+	// ACRIssuer is the issuer of the ACR tokens.
+	ACRIssuer = "Azure Container Registry"
+)
+
+// Role is a role that can be assigned to an identity on a registry.
+type Role string
+
+const (
+	// RoleAcrPull grants pull access to every repository of a registry.
+	RoleAcrPull Role = "AcrPull"
+	// RoleAcrPush grants pull and push access to every repository of a registry.
+	RoleAcrPush Role = "AcrPush"
+)
+
+// Identity is a user assigned managed identity known to the instance metadata service.
+type Identity struct {
+	ClientID   string
+	ObjectID   string
+	ResourceID string
+}
+
+// Server is a stateful instance metadata service and container registry. Its exported fields are read
+// on every request and must be set before the first one; a fake Clock can be stepped at any time.
+type Server struct {
+	// TenantID is the tenant of the identities.
+	TenantID string
+	// ARMResource is the audience the registry expects of the ARM tokens it exchanges.
+	ARMResource string
+	// ARMTokenLifetime, RefreshTokenLifetime and AccessTokenLifetime are how long the issued tokens are valid.
+	ARMTokenLifetime     time.Duration
+	RefreshTokenLifetime time.Duration
+	AccessTokenLifetime  time.Duration
+	// Clock is the time tokens are issued and validated at.
+	Clock clock.PassiveClock
+
+	mu          sync.Mutex
+	key         *rsa.PrivateKey
+	identities  []*Identity
+	registries  map[string]*registry
+	faults      []*Fault
+	requests    map[Endpoint]int
+	certificate *certificateAuthority
+
+	imds *httptest.Server
+	acr  *httptest.Server
+}
+
+type registry struct {
+	// roles are the roles assigned on the registry by identity object ID.
+	roles map[string]Role
+	// repositories are the references of the manifests in each repository.
+	repositories map[string]map[string]bool
+}
+
+// NewServer starts a server. It panics when the server can not be started, like httptest.NewServer.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("fake: failed to generate signing key: %v", err))
+	}
+	ca, err := newCertificateAuthority()
+	if err != nil {
+		panic(fmt.Sprintf("fake: failed to generate certificate authority: %v", err))
+	}
+
+	s := &Server{
+		TenantID:             DefaultTenantID,
+		ARMResource:          DefaultARMResource,
+		ARMTokenLifetime:     24 * time.Hour,
+		RefreshTokenLifetime: 3 * time.Hour,
+		AccessTokenLifetime:  75 * time.Minute,
+		Clock:                clock.RealClock{},
+		key:                  key,
+		registries:           map[string]*registry{},
+		requests:             map[Endpoint]int{},
+		certificate:          ca,
+	}
+
+	s.imds = httptest.NewServer(s.handle(EndpointIMDS, s.serveIMDS))
+
+	acrMux := http.NewServeMux()
+	acrMux.Handle("/oauth2/exchange", s.handle(EndpointExchange, s.serveExchange))
+	acrMux.Handle("/oauth2/token", s.handle(EndpointToken, s.serveToken))
+	acrMux.Handle("/v2/", s.handle(EndpointRegistry, s.serveRegistry))
+	s.acr = httptest.NewUnstartedServer(acrMux)
+	s.acr.TLS = &tls.Config{GetCertificate: ca.getCertificate}
+	s.acr.StartTLS()
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.imds.Close()
+	s.acr.Close()
+}
+
+// MetadataEndpoint returns the URL of the instance metadata service token endpoint.
+func (s *Server) MetadataEndpoint() string {
+	return s.imds.URL + MetadataPath
+}
+
+// Client returns an HTTP client that sends HTTPS requests for any host to the registry and other
+// requests to the instance metadata service, trusting the certificates the registry serves.
+func (s *Server) Client() *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.certificate.cert)
+	imdsAddr := s.imds.Listener.Addr().String()
+	acrAddr := s.acr.Listener.Addr().String()
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if _, port, err := net.SplitHostPort(addr); err == nil && port == "443" {
+					return dialer.DialContext(ctx, network, acrAddr)
+				}
+				return dialer.DialContext(ctx, network, imdsAddr)
+			},
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		},
+	}
+}
+
+// PublicKey returns the key verifying the signature of the issued tokens.
+func (s *Server) PublicKey() *rsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+// AddIdentity adds a managed identity named name, in a resource group of a random subscription.
+func (s *Server) AddIdentity(name string) Identity {
+	identity := Identity{
+		ClientID: uuid.NewString(),
+		ObjectID: uuid.NewString(),
+		ResourceID: fmt.Sprintf("/subscriptions/%s/resourceGroups/identities/providers/Microsoft.ManagedIdentity/userAssignedIdentities/%s",
+			uuid.NewString(), name),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identities = append(s.identities, &identity)
+	return identity
+}
+
+// RemoveIdentity removes the identity with clientID, as if it was unassigned from the node.
+func (s *Server) RemoveIdentity(clientID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, identity := range s.identities {
+		if strings.EqualFold(identity.ClientID, clientID) {
+			s.identities = append(s.identities[:i], s.identities[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddRegistry adds a registry with the login server name, for example test.azurecr.io.
+func (s *Server) AddRegistry(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.registries[strings.ToLower(name)]; !ok {
+		s.registries[strings.ToLower(name)] = &registry{roles: map[string]Role{}, repositories: map[string]map[string]bool{}}
+	}
+}
+
+// AddManifest adds a manifest to a repository of a registry, addressable by each of references.
+func (s *Server) AddManifest(registryName, repository string, references ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.mustGetRegistry(registryName)
+	if r.repositories[repository] == nil {
+		r.repositories[repository] = map[string]bool{}
+	}
+	for _, reference := range references {
+		r.repositories[repository][reference] = true
+	}
+}
+
+// AssignRole assigns role on a registry to the identity with clientID.
+func (s *Server) AssignRole(clientID, registryName string, role Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identity := s.mustGetIdentity(clientID)
+	s.mustGetRegistry(registryName).roles[identity.ObjectID] = role
+}
+
+// RemoveRoleAssignment removes the role of the identity with clientID on a registry.
+func (s *Server) RemoveRoleAssignment(clientID, registryName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	identity := s.mustGetIdentity(clientID)
+	delete(s.mustGetRegistry(registryName).roles, identity.ObjectID)
+}
+
+// Requests returns the number of requests received by endpoint, including failed ones.
+func (s *Server) Requests(endpoint Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[endpoint]
+}
+
+func (s *Server) mustGetIdentity(clientID string) *Identity {
+	for _, identity := range s.identities {
+		if strings.EqualFold(identity.ClientID, clientID) {
+			return identity
+		}
+	}
+	panic(fmt.Sprintf("fake: identity %s does not exist", clientID))
+}
+
+func (s *Server) mustGetRegistry(name string) *registry {
+	r, ok := s.registries[strings.ToLower(name)]
+	if !ok {
+		panic(fmt.Sprintf("fake: registry %s does not exist", name))
+	}
+	return r
+}
+
+// findIdentity returns the identity selected by the query of an instance metadata service request.
+// Like on a node with a single user assigned identity, no selector selects the only identity.
+func (s *Server) findIdentity(clientID, objectID, resourceID string) *Identity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if clientID == "" && objectID == "" && resourceID == "" && len(s.identities) == 1 {
+		copied := *s.identities[0]
+		return &copied
+	}
+	for _, identity := range s.identities {
+		switch {
+		case clientID != "" && strings.EqualFold(identity.ClientID, clientID),
+			objectID != "" && strings.EqualFold(identity.ObjectID, objectID),
+			resourceID != "" && strings.EqualFold(identity.ResourceID, resourceID):
+			copied := *identity
+			return &copied
+		}
+	}
+	return nil
+}
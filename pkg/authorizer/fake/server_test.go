@@ -0,0 +1,143 @@
+package fake
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+const testACR = "test.azurecr.io"
+
+var _ = Describe("Fake Server", func() {
+	var (
+		server   *Server
+		identity Identity
+		az       *authorizer.Authorizer
+	)
+
+	BeforeEach(func() {
+		server = NewServer()
+		identity = server.AddIdentity("test-mi")
+		server.AddRegistry(testACR)
+		server.AddManifest(testACR, "app", "1.0")
+		az = authorizer.NewAuthorizerWithOptions(authorizer.Options{
+			HTTPClient: server.Client(),
+			RPS:        100,
+			Burst:      100,
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Should issue ACR tokens to identities with a role on the registry", func() {
+		server.AssignRole(identity.ClientID, testACR, RoleAcrPull)
+
+		acrToken, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		claims, err := acrToken.GetACRClaims()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.ValidateAudience(testACR)).To(Succeed())
+		Expect(claims.ValidateIssuer(ACRIssuer)).To(Succeed())
+		Expect(claims.ValidateTenant(DefaultTenantID)).To(Succeed())
+		Expect(claims.GrantType).To(Equal("refresh_token"))
+
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "app:1.0")).To(Succeed())
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "app:2.0")).To(MatchError(ContainSubstring("404")))
+
+		_, err = az.AcquireACRAccessTokenWithResourceID(context.Background(), identity.ResourceID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.Requests(EndpointIMDS)).To(Equal(2))
+		Expect(server.Requests(EndpointExchange)).To(Equal(2))
+	})
+
+	It("Should deny pulls of identities without a role", func() {
+		acrToken, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "")).To(MatchError(ContainSubstring("401")))
+
+		server.AssignRole(identity.ClientID, testACR, RoleAcrPull)
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "app:1.0")).To(Succeed())
+
+		server.RemoveRoleAssignment(identity.ClientID, testACR)
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "app:1.0")).To(MatchError(ContainSubstring("401")))
+	})
+
+	It("Should reject unknown identities and registries", func() {
+		_, err := az.AcquireACRAccessTokenWithClientID(context.Background(), "00000000-0000-0000-0000-000000000000", testACR)
+		Expect(err).To(MatchError(ContainSubstring("Identity not found")))
+
+		_, err = az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, "other.azurecr.io")
+		Expect(err).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("Should reject expired tokens", func() {
+		clock := clocktesting.NewFakePassiveClock(time.Now())
+		server.Clock = clock
+		server.AssignRole(identity.ClientID, testACR, RoleAcrPull)
+
+		acrToken, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		exp, err := acrToken.GetTokenExp()
+		Expect(err).ToNot(HaveOccurred())
+
+		clock.SetTime(exp.Add(time.Second))
+		Expect(az.VerifyPullAccess(context.Background(), acrToken, testACR, "")).To(MatchError(ContainSubstring("401")))
+	})
+
+	It("Should inject faults", func() {
+		server.InjectFault(Throttle(EndpointIMDS, 1))
+		_, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).To(MatchError(ContainSubstring("429")))
+
+		server.InjectFault(Unavailable(EndpointExchange, 2))
+		for i := 0; i < 2; i++ {
+			_, err = az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+			Expect(err).To(MatchError(ContainSubstring("503")))
+		}
+		_, err = az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+
+		server.InjectFault(Slow(EndpointExchange, 200*time.Millisecond))
+		start := time.Now()
+		_, err = az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+
+		server.ClearFaults()
+		start = time.Now()
+		_, err = az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 200*time.Millisecond))
+	})
+
+	It("Should require the metadata header", func() {
+		resp, err := server.Client().Get(server.MetadataEndpoint() + "?" + url.Values{
+			"api-version": {"2018-02-01"},
+			"resource":    {DefaultARMResource},
+		}.Encode())
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("Should sign tokens with its key", func() {
+		acrToken, err := az.AcquireACRAccessTokenWithClientID(context.Background(), identity.ClientID, testACR)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.verify(string(acrToken), &types.ACRClaims{})).To(Succeed())
+
+		parts := strings.Split(string(acrToken), ".")
+		tampered := types.AccessToken(parts[0] + "." + parts[1] + ".c2lnbmF0dXJl")
+		Expect(server.verify(string(tampered), &types.ACRClaims{})).ToNot(Succeed())
+	})
+})
@@ -1,21 +1,41 @@
 package authorizer
 
-import "github.com/Azure/msi-acrpull/pkg/authorizer/types"
+import (
+	"context"
 
-//go:generate sh -c "mockgen github.com/Azure/msi-acrpull/pkg/authorizer Interface,ManagedIdentityTokenRetriever,ACRTokenExchanger > ./mock_$GOPACKAGE/interfaces.go"
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+)
+
+//go:generate sh -c "mockgen github.com/Azure/msi-acrpull/pkg/authorizer Interface,ManagedIdentityTokenRetriever,ApplicationTokenRetriever,ACRTokenExchanger,ACRRegistryVerifier > ./mock_$GOPACKAGE/interfaces.go"
 
 // Interface is the authorizer interface to acquire ACR access tokens.
 type Interface interface {
-	AcquireACRAccessTokenWithResourceID(identityResourceID string, acrFQDN string) (types.AccessToken, error)
-	AcquireACRAccessTokenWithClientID(clientID string, acrFQDN string) (types.AccessToken, error)
+	AcquireACRAccessTokenWithResourceID(ctx context.Context, identityResourceID string, acrFQDN string) (types.AccessToken, error)
+	AcquireACRAccessTokenWithClientID(ctx context.Context, clientID string, acrFQDN string) (types.AccessToken, error)
+	AcquireACRAccessTokenWithClientAssertion(ctx context.Context, tenantID, clientID, assertion string, acrFQDN string) (types.AccessToken, error)
+	AcquireACRAccessTokenWithClientSecret(ctx context.Context, tenantID, clientID, clientSecret string, acrFQDN string) (types.AccessToken, error)
+	VerifyPullAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string, probeImage string) error
 }
 
 // ManagedIdentityTokenRetriever is the interface to acquire an ARM access token.
 type ManagedIdentityTokenRetriever interface {
-	AcquireARMToken(clientID string, resourceID string) (types.AccessToken, error)
+	AcquireARMToken(ctx context.Context, clientID string, resourceID string) (types.AccessToken, error)
+	CheckMetadataEndpoint(ctx context.Context) error
+}
+
+// ApplicationTokenRetriever is the interface to acquire an ARM access token for a Microsoft Entra application.
+type ApplicationTokenRetriever interface {
+	AcquireARMTokenWithClientAssertion(ctx context.Context, tenantID, clientID, assertion string) (types.AccessToken, error)
+	AcquireARMTokenWithClientSecret(ctx context.Context, tenantID, clientID, clientSecret string) (types.AccessToken, error)
 }
 
 // ACRTokenExchanger is the interface to exchange an ACR access token.
 type ACRTokenExchanger interface {
-	ExchangeACRAccessToken(armToken types.AccessToken, acrFQDN string) (types.AccessToken, error)
+	ExchangeACRAccessToken(ctx context.Context, armToken types.AccessToken, acrFQDN string) (types.AccessToken, error)
+}
+
+// ACRRegistryVerifier is the interface to check that an ACR token is accepted by the registry.
+type ACRRegistryVerifier interface {
+	VerifyRegistryAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string) error
+	VerifyImageAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string, image string) error
 }
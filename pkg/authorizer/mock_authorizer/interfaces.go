@@ -1,140 +1,304 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/Azure/msi-acrpull/pkg/authorizer (interfaces: Interface,ManagedIdentityTokenRetriever,ACRTokenExchanger)
+// Source: github.com/Azure/msi-acrpull/pkg/authorizer (interfaces: Interface,ManagedIdentityTokenRetriever,ApplicationTokenRetriever,ACRTokenExchanger,ACRRegistryVerifier)
 
 // Package mock_authorizer is a generated GoMock package.
 package mock_authorizer
 
 import (
+	context "context"
+	reflect "reflect"
+
 	types "github.com/Azure/msi-acrpull/pkg/authorizer/types"
 	gomock "github.com/golang/mock/gomock"
-	reflect "reflect"
 )
 
-// MockInterface is a mock of Interface interface
+// MockInterface is a mock of Interface interface.
 type MockInterface struct {
 	ctrl     *gomock.Controller
 	recorder *MockInterfaceMockRecorder
 }
 
-// MockInterfaceMockRecorder is the mock recorder for MockInterface
+// MockInterfaceMockRecorder is the mock recorder for MockInterface.
 type MockInterfaceMockRecorder struct {
 	mock *MockInterface
 }
 
-// NewMockInterface creates a new mock instance
+// NewMockInterface creates a new mock instance.
 func NewMockInterface(ctrl *gomock.Controller) *MockInterface {
 	mock := &MockInterface{ctrl: ctrl}
 	mock.recorder = &MockInterfaceMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockInterface) EXPECT() *MockInterfaceMockRecorder {
 	return m.recorder
 }
 
-// AcquireACRAccessTokenWithClientID mocks base method
-func (m *MockInterface) AcquireACRAccessTokenWithClientID(arg0, arg1 string) (types.AccessToken, error) {
+// AcquireACRAccessTokenWithClientAssertion mocks base method.
+func (m *MockInterface) AcquireACRAccessTokenWithClientAssertion(arg0 context.Context, arg1, arg2, arg3, arg4 string) (types.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithClientAssertion", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(types.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireACRAccessTokenWithClientAssertion indicates an expected call of AcquireACRAccessTokenWithClientAssertion.
+func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithClientAssertion(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithClientAssertion", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithClientAssertion), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AcquireACRAccessTokenWithClientID mocks base method.
+func (m *MockInterface) AcquireACRAccessTokenWithClientID(arg0 context.Context, arg1, arg2 string) (types.AccessToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithClientID", arg0, arg1)
+	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithClientID", arg0, arg1, arg2)
 	ret0, _ := ret[0].(types.AccessToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AcquireACRAccessTokenWithClientID indicates an expected call of AcquireACRAccessTokenWithClientID
-func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithClientID(arg0, arg1 interface{}) *gomock.Call {
+// AcquireACRAccessTokenWithClientID indicates an expected call of AcquireACRAccessTokenWithClientID.
+func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithClientID(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithClientID", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithClientID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithClientID", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithClientID), arg0, arg1, arg2)
 }
 
-// AcquireACRAccessTokenWithResourceID mocks base method
-func (m *MockInterface) AcquireACRAccessTokenWithResourceID(arg0, arg1 string) (types.AccessToken, error) {
+// AcquireACRAccessTokenWithClientSecret mocks base method.
+func (m *MockInterface) AcquireACRAccessTokenWithClientSecret(arg0 context.Context, arg1, arg2, arg3, arg4 string) (types.AccessToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithResourceID", arg0, arg1)
+	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithClientSecret", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(types.AccessToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AcquireACRAccessTokenWithResourceID indicates an expected call of AcquireACRAccessTokenWithResourceID
-func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithResourceID(arg0, arg1 interface{}) *gomock.Call {
+// AcquireACRAccessTokenWithClientSecret indicates an expected call of AcquireACRAccessTokenWithClientSecret.
+func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithClientSecret(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithResourceID", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithResourceID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithClientSecret", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithClientSecret), arg0, arg1, arg2, arg3, arg4)
 }
 
-// MockManagedIdentityTokenRetriever is a mock of ManagedIdentityTokenRetriever interface
+// AcquireACRAccessTokenWithResourceID mocks base method.
+func (m *MockInterface) AcquireACRAccessTokenWithResourceID(arg0 context.Context, arg1, arg2 string) (types.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireACRAccessTokenWithResourceID", arg0, arg1, arg2)
+	ret0, _ := ret[0].(types.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireACRAccessTokenWithResourceID indicates an expected call of AcquireACRAccessTokenWithResourceID.
+func (mr *MockInterfaceMockRecorder) AcquireACRAccessTokenWithResourceID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireACRAccessTokenWithResourceID", reflect.TypeOf((*MockInterface)(nil).AcquireACRAccessTokenWithResourceID), arg0, arg1, arg2)
+}
+
+// VerifyPullAccess mocks base method.
+func (m *MockInterface) VerifyPullAccess(arg0 context.Context, arg1 types.AccessToken, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyPullAccess", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyPullAccess indicates an expected call of VerifyPullAccess.
+func (mr *MockInterfaceMockRecorder) VerifyPullAccess(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyPullAccess", reflect.TypeOf((*MockInterface)(nil).VerifyPullAccess), arg0, arg1, arg2, arg3)
+}
+
+// MockManagedIdentityTokenRetriever is a mock of ManagedIdentityTokenRetriever interface.
 type MockManagedIdentityTokenRetriever struct {
 	ctrl     *gomock.Controller
 	recorder *MockManagedIdentityTokenRetrieverMockRecorder
 }
 
-// MockManagedIdentityTokenRetrieverMockRecorder is the mock recorder for MockManagedIdentityTokenRetriever
+// MockManagedIdentityTokenRetrieverMockRecorder is the mock recorder for MockManagedIdentityTokenRetriever.
 type MockManagedIdentityTokenRetrieverMockRecorder struct {
 	mock *MockManagedIdentityTokenRetriever
 }
 
-// NewMockManagedIdentityTokenRetriever creates a new mock instance
+// NewMockManagedIdentityTokenRetriever creates a new mock instance.
 func NewMockManagedIdentityTokenRetriever(ctrl *gomock.Controller) *MockManagedIdentityTokenRetriever {
 	mock := &MockManagedIdentityTokenRetriever{ctrl: ctrl}
 	mock.recorder = &MockManagedIdentityTokenRetrieverMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockManagedIdentityTokenRetriever) EXPECT() *MockManagedIdentityTokenRetrieverMockRecorder {
 	return m.recorder
 }
 
-// AcquireARMToken mocks base method
-func (m *MockManagedIdentityTokenRetriever) AcquireARMToken(arg0, arg1 string) (types.AccessToken, error) {
+// AcquireARMToken mocks base method.
+func (m *MockManagedIdentityTokenRetriever) AcquireARMToken(arg0 context.Context, arg1, arg2 string) (types.AccessToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcquireARMToken", arg0, arg1)
+	ret := m.ctrl.Call(m, "AcquireARMToken", arg0, arg1, arg2)
 	ret0, _ := ret[0].(types.AccessToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AcquireARMToken indicates an expected call of AcquireARMToken
-func (mr *MockManagedIdentityTokenRetrieverMockRecorder) AcquireARMToken(arg0, arg1 interface{}) *gomock.Call {
+// AcquireARMToken indicates an expected call of AcquireARMToken.
+func (mr *MockManagedIdentityTokenRetrieverMockRecorder) AcquireARMToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireARMToken", reflect.TypeOf((*MockManagedIdentityTokenRetriever)(nil).AcquireARMToken), arg0, arg1, arg2)
+}
+
+// CheckMetadataEndpoint mocks base method.
+func (m *MockManagedIdentityTokenRetriever) CheckMetadataEndpoint(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckMetadataEndpoint", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckMetadataEndpoint indicates an expected call of CheckMetadataEndpoint.
+func (mr *MockManagedIdentityTokenRetrieverMockRecorder) CheckMetadataEndpoint(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireARMToken", reflect.TypeOf((*MockManagedIdentityTokenRetriever)(nil).AcquireARMToken), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMetadataEndpoint", reflect.TypeOf((*MockManagedIdentityTokenRetriever)(nil).CheckMetadataEndpoint), arg0)
 }
 
-// MockACRTokenExchanger is a mock of ACRTokenExchanger interface
+// MockApplicationTokenRetriever is a mock of ApplicationTokenRetriever interface.
+type MockApplicationTokenRetriever struct {
+	ctrl     *gomock.Controller
+	recorder *MockApplicationTokenRetrieverMockRecorder
+}
+
+// MockApplicationTokenRetrieverMockRecorder is the mock recorder for MockApplicationTokenRetriever.
+type MockApplicationTokenRetrieverMockRecorder struct {
+	mock *MockApplicationTokenRetriever
+}
+
+// NewMockApplicationTokenRetriever creates a new mock instance.
+func NewMockApplicationTokenRetriever(ctrl *gomock.Controller) *MockApplicationTokenRetriever {
+	mock := &MockApplicationTokenRetriever{ctrl: ctrl}
+	mock.recorder = &MockApplicationTokenRetrieverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockApplicationTokenRetriever) EXPECT() *MockApplicationTokenRetrieverMockRecorder {
+	return m.recorder
+}
+
+// AcquireARMTokenWithClientAssertion mocks base method.
+func (m *MockApplicationTokenRetriever) AcquireARMTokenWithClientAssertion(arg0 context.Context, arg1, arg2, arg3 string) (types.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireARMTokenWithClientAssertion", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(types.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireARMTokenWithClientAssertion indicates an expected call of AcquireARMTokenWithClientAssertion.
+func (mr *MockApplicationTokenRetrieverMockRecorder) AcquireARMTokenWithClientAssertion(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireARMTokenWithClientAssertion", reflect.TypeOf((*MockApplicationTokenRetriever)(nil).AcquireARMTokenWithClientAssertion), arg0, arg1, arg2, arg3)
+}
+
+// AcquireARMTokenWithClientSecret mocks base method.
+func (m *MockApplicationTokenRetriever) AcquireARMTokenWithClientSecret(arg0 context.Context, arg1, arg2, arg3 string) (types.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireARMTokenWithClientSecret", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(types.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireARMTokenWithClientSecret indicates an expected call of AcquireARMTokenWithClientSecret.
+func (mr *MockApplicationTokenRetrieverMockRecorder) AcquireARMTokenWithClientSecret(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireARMTokenWithClientSecret", reflect.TypeOf((*MockApplicationTokenRetriever)(nil).AcquireARMTokenWithClientSecret), arg0, arg1, arg2, arg3)
+}
+
+// MockACRTokenExchanger is a mock of ACRTokenExchanger interface.
 type MockACRTokenExchanger struct {
 	ctrl     *gomock.Controller
 	recorder *MockACRTokenExchangerMockRecorder
 }
 
-// MockACRTokenExchangerMockRecorder is the mock recorder for MockACRTokenExchanger
+// MockACRTokenExchangerMockRecorder is the mock recorder for MockACRTokenExchanger.
 type MockACRTokenExchangerMockRecorder struct {
 	mock *MockACRTokenExchanger
 }
 
-// NewMockACRTokenExchanger creates a new mock instance
+// NewMockACRTokenExchanger creates a new mock instance.
 func NewMockACRTokenExchanger(ctrl *gomock.Controller) *MockACRTokenExchanger {
 	mock := &MockACRTokenExchanger{ctrl: ctrl}
 	mock.recorder = &MockACRTokenExchangerMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockACRTokenExchanger) EXPECT() *MockACRTokenExchangerMockRecorder {
 	return m.recorder
 }
 
-// ExchangeACRAccessToken mocks base method
-func (m *MockACRTokenExchanger) ExchangeACRAccessToken(arg0 types.AccessToken, arg1 string) (types.AccessToken, error) {
+// ExchangeACRAccessToken mocks base method.
+func (m *MockACRTokenExchanger) ExchangeACRAccessToken(arg0 context.Context, arg1 types.AccessToken, arg2 string) (types.AccessToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExchangeACRAccessToken", arg0, arg1)
+	ret := m.ctrl.Call(m, "ExchangeACRAccessToken", arg0, arg1, arg2)
 	ret0, _ := ret[0].(types.AccessToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ExchangeACRAccessToken indicates an expected call of ExchangeACRAccessToken
-func (mr *MockACRTokenExchangerMockRecorder) ExchangeACRAccessToken(arg0, arg1 interface{}) *gomock.Call {
+// ExchangeACRAccessToken indicates an expected call of ExchangeACRAccessToken.
+func (mr *MockACRTokenExchangerMockRecorder) ExchangeACRAccessToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExchangeACRAccessToken", reflect.TypeOf((*MockACRTokenExchanger)(nil).ExchangeACRAccessToken), arg0, arg1, arg2)
+}
+
+// MockACRRegistryVerifier is a mock of ACRRegistryVerifier interface.
+type MockACRRegistryVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockACRRegistryVerifierMockRecorder
+}
+
+// MockACRRegistryVerifierMockRecorder is the mock recorder for MockACRRegistryVerifier.
+type MockACRRegistryVerifierMockRecorder struct {
+	mock *MockACRRegistryVerifier
+}
+
+// NewMockACRRegistryVerifier creates a new mock instance.
+func NewMockACRRegistryVerifier(ctrl *gomock.Controller) *MockACRRegistryVerifier {
+	mock := &MockACRRegistryVerifier{ctrl: ctrl}
+	mock.recorder = &MockACRRegistryVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockACRRegistryVerifier) EXPECT() *MockACRRegistryVerifierMockRecorder {
+	return m.recorder
+}
+
+// VerifyImageAccess mocks base method.
+func (m *MockACRRegistryVerifier) VerifyImageAccess(arg0 context.Context, arg1 types.AccessToken, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyImageAccess", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyImageAccess indicates an expected call of VerifyImageAccess.
+func (mr *MockACRRegistryVerifierMockRecorder) VerifyImageAccess(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyImageAccess", reflect.TypeOf((*MockACRRegistryVerifier)(nil).VerifyImageAccess), arg0, arg1, arg2, arg3)
+}
+
+// VerifyRegistryAccess mocks base method.
+func (m *MockACRRegistryVerifier) VerifyRegistryAccess(arg0 context.Context, arg1 types.AccessToken, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyRegistryAccess", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyRegistryAccess indicates an expected call of VerifyRegistryAccess.
+func (mr *MockACRRegistryVerifierMockRecorder) VerifyRegistryAccess(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExchangeACRAccessToken", reflect.TypeOf((*MockACRTokenExchanger)(nil).ExchangeACRAccessToken), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyRegistryAccess", reflect.TypeOf((*MockACRRegistryVerifier)(nil).VerifyRegistryAccess), arg0, arg1, arg2)
 }
@@ -0,0 +1,172 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
+)
+
+// RegistryVerifier is an instance of ACRRegistryVerifier
+type RegistryVerifier struct {
+	acrServerScheme string
+	client          *rateLimitedClient
+}
+
+// NewRegistryVerifier returns a new registry verifier
+func NewRegistryVerifier() *RegistryVerifier {
+	return &RegistryVerifier{
+		acrServerScheme: "https",
+		client:          newRateLimitedClient(),
+	}
+}
+
+// VerifyRegistryAccess checks that the registry accepts the ACR refresh token by
+// redeeming it for an access token and calling the distribution API base endpoint.
+func (rv *RegistryVerifier) VerifyRegistryAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string) (err error) {
+	ctx, span := tracer().Start(ctx, "RegistryVerifier.VerifyRegistryAccess", trace.WithAttributes(RegistryAttribute(acrFQDN)))
+	defer func() { endSpan(span, err) }()
+
+	accessToken, err := rv.acquireAccessToken(ctx, acrToken, acrFQDN, "")
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s://%s/v2/", rv.scheme(), acrFQDN), nil)
+	if err != nil {
+		return fmt.Errorf("failed to construct registry request: %w", err)
+	}
+	req.Header.Add("Authorization", "Bearer "+accessToken)
+
+	resp, err := rv.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send registry request: %w", err)
+	}
+	defer closeResponse(resp)
+
+	if resp.StatusCode != 200 {
+		responseBytes, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("ACR registry endpoint returned error status: %d. body: %s", resp.StatusCode, redact.String(string(responseBytes)))
+	}
+
+	return nil
+}
+
+// VerifyImageAccess checks that the ACR refresh token grants pull access to image by
+// requesting its manifest with a repository scoped access token. image is a repository
+// in the registry with an optional tag or digest, and may be prefixed with acrFQDN.
+func (rv *RegistryVerifier) VerifyImageAccess(ctx context.Context, acrToken types.AccessToken, acrFQDN string, image string) (err error) {
+	ctx, span := tracer().Start(ctx, "RegistryVerifier.VerifyImageAccess", trace.WithAttributes(RegistryAttribute(acrFQDN)))
+	defer func() { endSpan(span, err) }()
+
+	repository, reference, err := parseImage(acrFQDN, image)
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := rv.acquireAccessToken(ctx, acrToken, acrFQDN, fmt.Sprintf("repository:%s:pull", repository))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", fmt.Sprintf("%s://%s/v2/%s/manifests/%s", rv.scheme(), acrFQDN, repository, reference), nil)
+	if err != nil {
+		return fmt.Errorf("failed to construct manifest request: %w", err)
+	}
+	req.Header.Add("Authorization", "Bearer "+accessToken)
+	for _, mediaType := range manifestMediaTypes {
+		req.Header.Add("Accept", mediaType)
+	}
+
+	resp, err := rv.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send manifest request: %w", err)
+	}
+	defer closeResponse(resp)
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("ACR manifest endpoint returned error status for %s:%s: %d", repository, reference, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// manifestMediaTypes are accepted when requesting a manifest, so that the registry does
+// not reject the request for an image stored in a format the client does not list.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// parseImage splits image into a repository and a tag or digest, defaulting to the latest tag.
+func parseImage(acrFQDN string, image string) (string, string, error) {
+	repository := strings.TrimPrefix(image, acrFQDN+"/")
+	reference := "latest"
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, reference = repository[:i], repository[i+1:]
+	} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:i], repository[i+1:]
+	}
+	if repository == "" || reference == "" {
+		return "", "", fmt.Errorf("invalid image reference %q", image)
+	}
+	return repository, reference, nil
+}
+
+// acquireAccessToken redeems the ACR refresh token for an access token with the given scope.
+func (rv *RegistryVerifier) acquireAccessToken(ctx context.Context, acrToken types.AccessToken, acrFQDN, scope string) (string, error) {
+	tokenURL := fmt.Sprintf("%s://%s/oauth2/token", rv.scheme(), acrFQDN)
+	ul, err := url.Parse(tokenURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token url: %w", err)
+	}
+	parameters := url.Values{}
+	parameters.Add("grant_type", "refresh_token")
+	parameters.Add("service", ul.Hostname())
+	parameters.Add("scope", scope)
+	parameters.Add("refresh_token", string(acrToken))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(parameters.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to construct token request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Length", strconv.Itoa(len(parameters.Encode())))
+
+	resp, err := rv.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send token request: %w", err)
+	}
+	defer closeResponse(resp)
+
+	if resp.StatusCode != 200 {
+		responseBytes, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("ACR token endpoint returned error status: %d. body: %s", resp.StatusCode, redact.String(string(responseBytes)))
+	}
+
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	return tokenResp.AccessToken, nil
+}
+
+func (rv *RegistryVerifier) scheme() string {
+	if rv.acrServerScheme == "" {
+		return "https"
+	}
+	return rv.acrServerScheme
+}
@@ -1,6 +1,7 @@
 package authorizer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,7 +10,10 @@ import (
 	"strconv"
 	"strings"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
 )
 
 // TokenExchanger is an instance of ACRTokenExchanger
@@ -27,11 +31,18 @@ func NewTokenExchanger() *TokenExchanger {
 }
 
 // ExchangeACRAccessToken exchanges an ARM access token to an ACR access token
-func (te *TokenExchanger) ExchangeACRAccessToken(armToken types.AccessToken, acrFQDN string) (types.AccessToken, error) {
-	tenantID, err := armToken.GetTokenTenantId()
+func (te *TokenExchanger) ExchangeACRAccessToken(ctx context.Context, armToken types.AccessToken, acrFQDN string) (_ types.AccessToken, err error) {
+	ctx, span := tracer().Start(ctx, "TokenExchanger.ExchangeACRAccessToken", trace.WithAttributes(RegistryAttribute(acrFQDN)))
+	defer func() { endSpan(span, err) }()
+
+	armClaims, err := armToken.GetARMClaims()
 	if err != nil {
 		return "", fmt.Errorf("failed to get tenant id from ARM token: %w", err)
 	}
+	tenantID := armClaims.TenantID
+	if tenantID == "" {
+		return "", fmt.Errorf("failed to get tenant id from ARM token: token has no tenant ID")
+	}
 
 	scheme := te.acrServerScheme
 	if scheme == "" {
@@ -49,7 +60,7 @@ func (te *TokenExchanger) ExchangeACRAccessToken(armToken types.AccessToken, acr
 	parameters.Add("tenant", tenantID)
 	parameters.Add("access_token", string(armToken))
 
-	req, err := http.NewRequest("POST", exchangeURL, strings.NewReader(parameters.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", exchangeURL, strings.NewReader(parameters.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to construct token exchange reqeust: %w", err)
 	}
@@ -67,7 +78,7 @@ func (te *TokenExchanger) ExchangeACRAccessToken(armToken types.AccessToken, acr
 
 	if resp.StatusCode != 200 {
 		responseBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("ACR token exchange endpoint returned error status: %d. body: %s", resp.StatusCode, string(responseBytes))
+		return "", fmt.Errorf("ACR token exchange endpoint returned error status: %d. body: %s", resp.StatusCode, redact.String(string(responseBytes)))
 	}
 
 	responseBytes, err := ioutil.ReadAll(resp.Body)
@@ -78,7 +89,7 @@ func (te *TokenExchanger) ExchangeACRAccessToken(armToken types.AccessToken, acr
 	var tokenResp tokenResponse
 	err = json.Unmarshal(responseBytes, &tokenResp)
 	if err != nil {
-		return "", fmt.Errorf("failed to read token exchange response: %w. response: %s", err, string(responseBytes))
+		return "", fmt.Errorf("failed to read token exchange response: %w. response: %s", err, redact.String(string(responseBytes)))
 	}
 
 	return types.AccessToken(tokenResp.RefreshToken), nil
@@ -1,12 +1,15 @@
 package authorizer
 
 import (
+	"context"
 	"net/url"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
+
+	"github.com/Azure/msi-acrpull/pkg/redact"
 )
 
 var _ = Describe("Token Exchanger Tests", func() {
@@ -48,7 +51,7 @@ var _ = Describe("Token Exchanger Tests", func() {
 				))
 
 			te := newTestTokenExchanger(server)
-			token, err := te.ExchangeACRAccessToken(armToken, ul.Host)
+			token, err := te.ExchangeACRAccessToken(context.Background(), armToken, ul.Host)
 
 			Expect(err).To(BeNil())
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -74,7 +77,7 @@ var _ = Describe("Token Exchanger Tests", func() {
 				))
 
 			te := newTestTokenExchanger(server)
-			token, err := te.ExchangeACRAccessToken(armToken, ul.Host)
+			token, err := te.ExchangeACRAccessToken(context.Background(), armToken, ul.Host)
 
 			Expect(err).NotTo(BeNil())
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -82,6 +85,31 @@ var _ = Describe("Token Exchanger Tests", func() {
 
 			Expect(err.Error()).To(ContainSubstring("Unauthorized"))
 		})
+
+		It("Does not leak tokens echoed back in the response", func() {
+			armToken, err := getTestArmToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+			acrToken, err := getTestAcrToken(time.Now().Add(time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			ul, err := url.Parse(server.URL())
+			Expect(err).ToNot(HaveOccurred())
+
+			server.AppendHandlers(
+				ghttp.RespondWith(400, `{"error":"invalid_request","access_token":"`+string(armToken)+`"}`),
+				// a response that can not be decoded is included in the error
+				ghttp.RespondWith(200, `{"refresh_token":"`+string(acrToken)+`",`),
+			)
+
+			te := newTestTokenExchanger(server)
+			for i := 0; i < 2; i++ {
+				_, err := te.ExchangeACRAccessToken(context.Background(), armToken, ul.Host)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(redact.Placeholder))
+				Expect(err.Error()).NotTo(ContainSubstring(string(armToken)))
+				Expect(err.Error()).NotTo(ContainSubstring(string(acrToken)))
+			}
+		})
 	})
 })
 
@@ -1,22 +1,27 @@
 package authorizer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/utils/clock"
+
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
+	"github.com/Azure/msi-acrpull/pkg/redact"
 )
 
 const (
 	defaultARMResource              = "https://management.azure.com/"
-	customARMResourceEnvVar         = "ARM_RESOURCE"
 	msiMetadataEndpoint             = "http://169.254.169.254/metadata/identity/oauth2/token"
 	defaultCacheExpirationInSeconds = 600
 )
@@ -24,9 +29,12 @@ const (
 // TokenRetriever is an instance of ManagedIdentityTokenRetriever
 type TokenRetriever struct {
 	metadataEndpoint string
+	armResource      string
 	cache            sync.Map
 	cacheExpiration  time.Duration
 	client           *rateLimitedClient
+	// clock is the time cached tokens expire at, the real clock when nil.
+	clock clock.PassiveClock
 }
 
 type cachedToken struct {
@@ -38,39 +46,72 @@ type cachedToken struct {
 func NewTokenRetriever() *TokenRetriever {
 	return &TokenRetriever{
 		metadataEndpoint: msiMetadataEndpoint,
+		armResource:      defaultARMResource,
 		cache:            sync.Map{},
 		cacheExpiration:  time.Duration(defaultCacheExpirationInSeconds) * time.Second,
 		client:           newRateLimitedClient(),
+		clock:            clock.RealClock{},
 	}
 }
 
 // AcquireARMToken acquires the managed identity ARM access token
-func (tr *TokenRetriever) AcquireARMToken(clientID string, resourceID string) (types.AccessToken, error) {
+func (tr *TokenRetriever) AcquireARMToken(ctx context.Context, clientID string, resourceID string) (_ types.AccessToken, err error) {
 	cacheKey := strings.ToLower(clientID)
 	if cacheKey == "" {
 		cacheKey = strings.ToLower(resourceID)
 	}
 
+	ctx, span := tracer().Start(ctx, "TokenRetriever.AcquireARMToken", trace.WithAttributes(IdentityAttribute(cacheKey)))
+	defer func() { endSpan(span, err) }()
+
 	cached, ok := tr.cache.Load(cacheKey)
 	if ok {
 		token := cached.(cachedToken)
-		if time.Now().UTC().Sub(token.notAfter) < 0 {
+		if tr.now().Before(token.notAfter) {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
 			return token.token, nil
 		}
 
 		tr.cache.Delete(cacheKey)
 	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
-	token, err := tr.refreshToken(clientID, resourceID)
+	token, err := tr.refreshToken(ctx, clientID, resourceID)
 	if err != nil {
 		return "", fmt.Errorf("failed to refresh ARM access token: %w", err)
 	}
 
-	tr.cache.Store(cacheKey, cachedToken{token: token, notAfter: time.Now().UTC().Add(tr.cacheExpiration)})
+	tr.cache.Store(cacheKey, cachedToken{token: token, notAfter: tr.cacheNotAfter(token)})
 	return token, nil
 }
 
-func (tr *TokenRetriever) refreshToken(clientID, resourceID string) (types.AccessToken, error) {
+// cacheNotAfter returns when a token stops being reused: after the cache expiration, or earlier when
+// the token expires within it, allowing for the clock of the issuer to be ahead by types.DefaultClockSkew.
+func (tr *TokenRetriever) cacheNotAfter(token types.AccessToken) time.Time {
+	now := tr.now()
+	notAfter := now.Add(tr.cacheExpiration)
+	claims, err := token.GetARMClaims()
+	if err != nil {
+		return notAfter
+	}
+	exp, err := claims.Expiry()
+	if err != nil {
+		return notAfter
+	}
+	if expiring := exp.Add(-types.DefaultClockSkew); expiring.Before(notAfter) {
+		return expiring
+	}
+	return notAfter
+}
+
+func (tr *TokenRetriever) now() time.Time {
+	if tr.clock == nil {
+		return time.Now()
+	}
+	return tr.clock.Now()
+}
+
+func (tr *TokenRetriever) refreshToken(ctx context.Context, clientID, resourceID string) (types.AccessToken, error) {
 	msiEndpoint, err := url.Parse(tr.metadataEndpoint)
 	if err != nil {
 		return "", err
@@ -83,18 +124,17 @@ func (tr *TokenRetriever) refreshToken(clientID, resourceID string) (types.Acces
 		parameters.Add("mi_res_id", resourceID)
 	}
 
-	customARMResource := os.Getenv(customARMResourceEnvVar)
-	if customARMResource == "" {
-		parameters.Add("resource", defaultARMResource)
-	} else {
-		parameters.Add("resource", customARMResource)
+	armResource := tr.armResource
+	if armResource == "" {
+		armResource = defaultARMResource
 	}
+	parameters.Add("resource", armResource)
 
 	parameters.Add("api-version", "2018-02-01")
 
 	msiEndpoint.RawQuery = parameters.Encode()
 
-	req, err := http.NewRequest("GET", msiEndpoint.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", msiEndpoint.String(), nil)
 	if err != nil {
 		return "", err
 	}
@@ -110,7 +150,7 @@ func (tr *TokenRetriever) refreshToken(clientID, resourceID string) (types.Acces
 
 	if resp.StatusCode != 200 {
 		responseBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("Metadata endpoint returned error status: %d. body: %s", resp.StatusCode, string(responseBytes))
+		return "", fmt.Errorf("Metadata endpoint returned error status: %d. body: %s", resp.StatusCode, redact.String(string(responseBytes)))
 	}
 
 	responseBytes, err := ioutil.ReadAll(resp.Body)
@@ -127,6 +167,35 @@ func (tr *TokenRetriever) refreshToken(clientID, resourceID string) (types.Acces
 	return types.AccessToken(tokenResp.AccessToken), nil
 }
 
+// CheckMetadataEndpoint checks that the metadata endpoint answers. It requests no token, so the
+// endpoint rejects the request, and only a failure to connect or a server error is returned. The
+// request bypasses the rate limiter, which would otherwise delay it behind token requests.
+func (tr *TokenRetriever) CheckMetadataEndpoint(ctx context.Context) error {
+	msiEndpoint, err := url.Parse(tr.metadataEndpoint)
+	if err != nil {
+		return err
+	}
+	msiEndpoint.RawQuery = url.Values{"api-version": []string{"2018-02-01"}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", msiEndpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Metadata", "true")
+
+	resp, err := tr.client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach metadata endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("metadata endpoint returned error status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func closeResponse(resp *http.Response) {
 	if resp == nil {
 		return
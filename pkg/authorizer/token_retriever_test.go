@@ -1,14 +1,17 @@
 package authorizer
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
 )
 
 var _ = Describe("Token Retriever Tests", func() {
@@ -39,7 +42,7 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
-			token, err := tr.AcquireARMToken("", testResourceID)
+			token, err := tr.AcquireARMToken(context.Background(), "", testResourceID)
 
 			Expect(err).To(BeNil())
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -52,8 +55,6 @@ var _ = Describe("Token Retriever Tests", func() {
 
 			tokenResp := &tokenResponse{AccessToken: string(armToken)}
 
-			os.Setenv(customARMResourceEnvVar, "https://management.usgovcloudapi.net/")
-
 			server.AppendHandlers(
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/", fmt.Sprintf("mi_res_id=%s&resource=https://management.usgovcloudapi.net/&api-version=2018-02-01", testResourceID)),
@@ -61,9 +62,8 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
-			token, err := tr.AcquireARMToken("", testResourceID)
-
-			os.Unsetenv(customARMResourceEnvVar)
+			tr.armResource = "https://management.usgovcloudapi.net/"
+			token, err := tr.AcquireARMToken(context.Background(), "", testResourceID)
 
 			Expect(err).To(BeNil())
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -83,7 +83,7 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
-			token, err := tr.AcquireARMToken(testClientID, "")
+			token, err := tr.AcquireARMToken(context.Background(), testClientID, "")
 
 			Expect(err).To(BeNil())
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -98,7 +98,7 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
-			token, err := tr.AcquireARMToken(testClientID, "")
+			token, err := tr.AcquireARMToken(context.Background(), testClientID, "")
 
 			Expect(err).NotTo(BeNil())
 			Expect(err.Error()).To(ContainSubstring("404"))
@@ -119,12 +119,12 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds*1000)
-			token, err := tr.AcquireARMToken(testClientID, "")
+			token, err := tr.AcquireARMToken(context.Background(), testClientID, "")
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
 
-			token, err = tr.AcquireARMToken(testClientID, "")
+			token, err = tr.AcquireARMToken(context.Background(), testClientID, "")
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -143,12 +143,12 @@ var _ = Describe("Token Retriever Tests", func() {
 				))
 
 			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds*1000)
-			token, err := tr.AcquireARMToken("", testResourceID)
+			token, err := tr.AcquireARMToken(context.Background(), "", testResourceID)
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
 
-			token, err = tr.AcquireARMToken("", testResourceID)
+			token, err = tr.AcquireARMToken(context.Background(), "", testResourceID)
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
@@ -172,19 +172,118 @@ var _ = Describe("Token Retriever Tests", func() {
 
 			// set cache expire immediately
 			tr := newTestTokenRetriever(server, 0)
-			token, err := tr.AcquireARMToken(testClientID, "")
+			token, err := tr.AcquireARMToken(context.Background(), testClientID, "")
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(1))
 
-			token, err = tr.AcquireARMToken(testClientID, "")
+			token, err = tr.AcquireARMToken(context.Background(), testClientID, "")
 			Expect(err).To(BeNil())
 			Expect(token).To(Equal(armToken))
 			Expect(server.ReceivedRequests()).Should(HaveLen(2))
 		})
+
+		It("Refresh ARM Token when the cache expiration elapses on the clock", func() {
+			fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+			armToken, err := getTestArmToken(fakeClock.Now().Add(24*time.Hour).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+			appendTokenResponses(server, armToken, 2)
+
+			tr := newTestTokenRetriever(server, int((10 * time.Minute).Milliseconds()))
+			tr.clock = fakeClock
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+
+			fakeClock.SetTime(fakeClock.Now().Add(10*time.Minute - time.Second))
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).Should(HaveLen(1))
+
+			fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).Should(HaveLen(2))
+		})
+
+		It("Refresh ARM Token that expires before the cache expiration", func() {
+			fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+			armToken, err := getTestArmToken(fakeClock.Now().Add(20*time.Minute).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+			appendTokenResponses(server, armToken, 2)
+
+			tr := newTestTokenRetriever(server, int(time.Hour.Milliseconds()))
+			tr.clock = fakeClock
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+
+			fakeClock.SetTime(fakeClock.Now().Add(10 * time.Minute))
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).Should(HaveLen(1))
+
+			// the token is reused no later than the clock skew before it expires
+			fakeClock.SetTime(fakeClock.Now().Add(10*time.Minute - types.DefaultClockSkew))
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).Should(HaveLen(2))
+		})
+
+		It("Does not cache an ARM Token that is already expired", func() {
+			fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+			armToken, err := getTestArmToken(fakeClock.Now().Add(-time.Minute).Unix(), signingKey)
+			Expect(err).ToNot(HaveOccurred())
+			appendTokenResponses(server, armToken, 2)
+
+			tr := newTestTokenRetriever(server, int(time.Hour.Milliseconds()))
+			tr.clock = fakeClock
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			_, err = tr.AcquireARMToken(context.Background(), testClientID, "")
+			Expect(err).To(BeNil())
+			Expect(server.ReceivedRequests()).Should(HaveLen(2))
+		})
+	})
+
+	Context("Check Metadata Endpoint", func() {
+		It("Accepts a rejected request", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/", "api-version=2018-02-01"),
+					ghttp.VerifyHeaderKV("Metadata", "true"),
+					ghttp.RespondWith(400, `{"error":"invalid_request","error_description":"Required query variable 'resource' is missing"}`),
+				))
+
+			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
+			Expect(tr.CheckMetadataEndpoint(context.Background())).To(Succeed())
+		})
+
+		It("Fails on a server error", func() {
+			server.AppendHandlers(ghttp.RespondWith(503, "unavailable"))
+
+			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
+			err := tr.CheckMetadataEndpoint(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("503"))
+		})
+
+		It("Fails when the endpoint can not be reached", func() {
+			tr := newTestTokenRetriever(server, defaultCacheExpirationInSeconds)
+			server.Close()
+			Expect(tr.CheckMetadataEndpoint(context.Background())).To(MatchError(ContainSubstring("failed to reach metadata endpoint")))
+		})
 	})
 })
 
+func appendTokenResponses(server *ghttp.Server, armToken types.AccessToken, times int) {
+	for i := 0; i < times; i++ {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/", fmt.Sprintf("client_id=%s&resource=https://management.azure.com/&api-version=2018-02-01", testClientID)),
+				ghttp.RespondWithJSONEncoded(200, &tokenResponse{AccessToken: string(armToken)}),
+			))
+	}
+}
+
 func newTestTokenRetriever(server *ghttp.Server, cacheExpirationInMilliSeconds int) *TokenRetriever {
 	client := newRateLimitedClient()
 	client.httpClient = server.HTTPTestServer.Client()
@@ -0,0 +1,57 @@
+package authorizer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Azure/msi-acrpull/pkg/redact"
+)
+
+const (
+	// TracerName is the name of the tracer of the authorizer spans.
+	TracerName = "github.com/Azure/msi-acrpull/pkg/authorizer"
+
+	// RegistryKey is the span attribute holding the login server of a registry.
+	RegistryKey = attribute.Key("acr.registry")
+	// IdentityHashKey is the span attribute holding the hash of a managed identity, see IdentityHash.
+	IdentityHashKey = attribute.Key("msi.identity_hash")
+)
+
+// tracer returns the tracer of the global tracer provider, which does not record spans unless
+// the program installs a provider.
+func tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// IdentityHash returns a short, stable hash of a managed identity client or resource ID, so that
+// spans of the same identity can be correlated without exporting the ID itself.
+func IdentityHash(identity string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(identity)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RegistryAttribute returns the span attribute of a registry.
+func RegistryAttribute(acrFQDN string) attribute.KeyValue {
+	return RegistryKey.String(strings.ToLower(acrFQDN))
+}
+
+// IdentityAttribute returns the span attribute of a managed identity client or resource ID.
+func IdentityAttribute(identity string) attribute.KeyValue {
+	return IdentityHashKey.String(IdentityHash(identity))
+}
+
+// endSpan records err, if any, on span and ends it. Token material is redacted from the error.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		err = redact.Error(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,7 +1,6 @@
 package types
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -10,55 +9,46 @@ import (
 
 type AccessToken string
 
+// tenantClaims are the claims naming the tenant of an ARM token, tid, or of an ACR token, tenant.
+type tenantClaims struct {
+	Claims
+
+	TenantID string `json:"tid,omitempty"`
+	Tenant   string `json:"tenant,omitempty"`
+}
+
+// GetTokenTenantId returns the tenant of an ARM or ACR token.
 func (t AccessToken) GetTokenTenantId() (string, error) {
-	claims, err := t.GetTokenClaims()
-	if err != nil {
+	claims := &tenantClaims{}
+	if err := t.parseClaims(claims); err != nil {
 		return "", err
 	}
-	tenantID, ok := claims["tid"].(string)
-	if ok {
-		return tenantID, nil
+	if claims.TenantID != "" {
+		return claims.TenantID, nil
 	}
-
-	tenantID, ok = claims["tenant"].(string)
-	if ok {
-		return tenantID, nil
+	if claims.Tenant != "" {
+		return claims.Tenant, nil
 	}
 
 	return "", fmt.Errorf("token has no tenant ID")
 }
 
-func (t AccessToken) GetTokenClaims() (jwt.MapClaims, error) {
-	p := &jwt.Parser{}
-	skipValidations := jwt.WithoutClaimsValidation()
-	skipValidations(p)
-
-	token, _, err := p.ParseUnverified(string(t), jwt.MapClaims{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token")
-	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, fmt.Errorf("unexpected claim type from token")
-	}
-
-	return claims, nil
-}
-
+// GetTokenExp returns the expiration time of the token.
 func (t AccessToken) GetTokenExp() (time.Time, error) {
-	claims, err := t.GetTokenClaims()
-	if err != nil {
+	claims := &Claims{}
+	if err := t.parseClaims(claims); err != nil {
 		return time.Time{}, err
 	}
+	return claims.Expiry()
+}
 
-	switch exp := claims["exp"].(type) {
-	case float64:
-		return time.Unix(int64(exp), 0), nil
-	case json.Number:
-		timestamp, _ := exp.Int64()
-		return time.Unix(timestamp, 0), nil
-	default:
-		return time.Time{}, fmt.Errorf("failed to parse token experation")
+// GetTokenClaims returns the claims of the token as a map.
+//
+// Deprecated: use GetARMClaims or GetACRClaims, which return typed claims.
+func (t AccessToken) GetTokenClaims() (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if err := t.parseClaims(claims); err != nil {
+		return nil, err
 	}
+	return claims, nil
 }
@@ -47,6 +47,24 @@ var _ = Describe("Access Token Tests", func() {
 		})
 	})
 
+	Context("GetTokenClaims", func() {
+		It("Returns the Claims of an ACR Token as a Map", func() {
+			exp := time.Now().Add(time.Hour).Unix()
+			token, err := getTestAcrToken(exp, signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := token.GetTokenClaims()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims["tenant"]).To(Equal(testTenantID))
+			Expect(claims["exp"]).To(BeNumerically("==", exp))
+		})
+
+		It("Returns Error for a Malformed Token", func() {
+			_, err := AccessToken("not-a-token").GetTokenClaims()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("GetTokenExp", func() {
 		It("Retrieves Correct Exp Time from ARM Token", func() {
 			expExpected := time.Now().Add(time.Hour).Unix()
@@ -0,0 +1,178 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultClockSkew is the tolerance allowed between the local clock and the token issuer.
+const DefaultClockSkew = 5 * time.Minute
+
+// Claims are the registered claims shared by ARM and ACR tokens, with validation helpers.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// ARMClaims are the claims of an ARM access token issued to a managed identity.
+type ARMClaims struct {
+	Claims
+
+	// TenantID is the tenant of the managed identity.
+	TenantID string `json:"tid,omitempty"`
+	// ObjectID is the object ID of the managed identity's service principal.
+	ObjectID string `json:"oid,omitempty"`
+	// ManagedIdentityResourceID is the resource ID of the managed identity.
+	ManagedIdentityResourceID string `json:"xms_mirid,omitempty"`
+}
+
+// ACRClaims are the claims of a token issued by ACR.
+type ACRClaims struct {
+	Claims
+
+	// TenantID is the tenant the token was exchanged for.
+	TenantID string `json:"tenant,omitempty"`
+	// GrantType is refresh_token for tokens returned by /oauth2/exchange and access_token for tokens
+	// returned by /oauth2/token.
+	GrantType string `json:"grant_type,omitempty"`
+	// Access lists the repository actions granted by an access token.
+	Access []ACRAccess `json:"access,omitempty"`
+}
+
+// ACRAccess is a resource and the actions an ACR access token grants on it.
+type ACRAccess struct {
+	// Type is the resource type, for example repository.
+	Type string `json:"type"`
+	// Name is the resource name, for example the repository name.
+	Name string `json:"name"`
+	// Actions are the granted actions, for example pull.
+	Actions []string `json:"actions"`
+}
+
+// GetARMClaims parses the claims of an ARM token without verifying its signature.
+func (t AccessToken) GetARMClaims() (*ARMClaims, error) {
+	claims := &ARMClaims{}
+	if err := t.parseClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// GetACRClaims parses the claims of an ACR token without verifying its signature.
+func (t AccessToken) GetACRClaims() (*ACRClaims, error) {
+	claims := &ACRClaims{}
+	if err := t.parseClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (t AccessToken) parseClaims(claims jwt.Claims) error {
+	p := jwt.NewParser(jwt.WithoutClaimsValidation())
+	if _, _, err := p.ParseUnverified(string(t), claims); err != nil {
+		return fmt.Errorf("failed to parse token")
+	}
+	return nil
+}
+
+// Expiry returns the expiration time of the token.
+func (c *Claims) Expiry() (time.Time, error) {
+	if c.ExpiresAt == nil {
+		return time.Time{}, fmt.Errorf("token has no expiration")
+	}
+	return c.ExpiresAt.Time, nil
+}
+
+// ValidateAudience checks that the token was issued for audience. A trailing slash is ignored,
+// as ARM resources are used both with and without one.
+func (c *Claims) ValidateAudience(audience string) error {
+	for _, aud := range c.Audience {
+		if strings.EqualFold(strings.TrimSuffix(aud, "/"), strings.TrimSuffix(audience, "/")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("token audience %v does not match %s", []string(c.Audience), audience)
+}
+
+// ValidateIssuer checks that the token was issued by issuer.
+func (c *Claims) ValidateIssuer(issuer string) error {
+	if c.Issuer != issuer {
+		return fmt.Errorf("token issuer %s does not match %s", c.Issuer, issuer)
+	}
+	return nil
+}
+
+// ValidateNotBefore checks that the token is valid at now, allowing the issuer clock to be up to
+// skew ahead. A token without a not-before claim is valid.
+func (c *Claims) ValidateNotBefore(now time.Time, skew time.Duration) error {
+	if c.NotBefore != nil && now.Add(skew).Before(c.NotBefore.Time) {
+		return fmt.Errorf("token is not valid before %s", c.NotBefore.Time.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ValidateExpiry checks that the token has not expired at now, allowing the issuer clock to be up
+// to skew behind.
+func (c *Claims) ValidateExpiry(now time.Time, skew time.Duration) error {
+	exp, err := c.Expiry()
+	if err != nil {
+		return err
+	}
+	if !now.Add(-skew).Before(exp) {
+		return fmt.Errorf("token expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ValidateTenant checks that the token was issued for tenantID.
+func (c *ACRClaims) ValidateTenant(tenantID string) error {
+	if !strings.EqualFold(c.TenantID, tenantID) {
+		return fmt.Errorf("token tenant %s does not match %s", c.TenantID, tenantID)
+	}
+	return nil
+}
+
+// ValidateTenant checks that the token was issued for tenantID.
+func (c *ARMClaims) ValidateTenant(tenantID string) error {
+	if !strings.EqualFold(c.TenantID, tenantID) {
+		return fmt.Errorf("token tenant %s does not match %s", c.TenantID, tenantID)
+	}
+	return nil
+}
+
+// Validate rejects an ACR token that was not issued for acrServer, has no expiry, is not yet valid
+// at now, or, when tenantID is not empty, was issued for another tenant.
+func (c *ACRClaims) Validate(acrServer, tenantID string, now time.Time) error {
+	if err := c.ValidateAudience(acrServer); err != nil {
+		return err
+	}
+	if _, err := c.Expiry(); err != nil {
+		return err
+	}
+	if err := c.ValidateNotBefore(now, DefaultClockSkew); err != nil {
+		return err
+	}
+	if tenantID != "" {
+		if err := c.ValidateTenant(tenantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasAction reports whether the token grants action on the resource of type resourceType and name.
+func (c *ACRClaims) HasAction(resourceType, name, action string) bool {
+	for _, access := range c.Access {
+		if access.Type != resourceType || access.Name != name {
+			continue
+		}
+		for _, granted := range access.Actions {
+			if granted == action || granted == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package types
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Claims Tests", func() {
+	var (
+		signingKey *rsa.PrivateKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		signingKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("GetARMClaims", func() {
+		It("Parses ARM token claims", func() {
+			exp := time.Now().Add(time.Hour).Unix()
+			token, err := getTestArmToken(exp, signingKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := token.GetARMClaims()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.TenantID).To(Equal(testTenantID))
+			Expect(claims.ManagedIdentityResourceID).To(Equal("fake/msi/resource/id"))
+			Expect(claims.Issuer).To(Equal("https://sts.windows.net/" + testTenantID + "/"))
+
+			expiry, err := claims.Expiry()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expiry.Unix()).To(Equal(exp))
+
+			Expect(claims.ValidateAudience("https://management.azure.com")).To(Succeed())
+			Expect(claims.ValidateAudience("https://management.usgovcloudapi.net/")).NotTo(Succeed())
+			Expect(claims.ValidateIssuer("https://sts.windows.net/" + testTenantID + "/")).To(Succeed())
+			Expect(claims.ValidateTenant(testTenantID)).To(Succeed())
+			Expect(claims.ValidateTenant("72f988bf-86f1-41af-91ab-2d7cd011db47")).NotTo(Succeed())
+		})
+
+		It("Returns error for a malformed token", func() {
+			_, err := AccessToken("not-a-jwt").GetARMClaims()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("GetACRClaims", func() {
+		It("Parses ACR token claims and access entries", func() {
+			token := signTestToken(jwt.MapClaims{
+				"aud":        "test.azurecr.io",
+				"exp":        time.Now().Add(time.Hour).Unix(),
+				"grant_type": "access_token",
+				"tenant":     testTenantID,
+				"access": []map[string]interface{}{
+					{"type": "repository", "name": "team/app", "actions": []string{"pull"}},
+				},
+			}, signingKey)
+
+			claims, err := token.GetACRClaims()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.TenantID).To(Equal(testTenantID))
+			Expect(claims.GrantType).To(Equal("access_token"))
+			Expect(claims.Access).To(Equal([]ACRAccess{{Type: "repository", Name: "team/app", Actions: []string{"pull"}}}))
+			Expect(claims.HasAction("repository", "team/app", "pull")).To(BeTrue())
+			Expect(claims.HasAction("repository", "team/app", "push")).To(BeFalse())
+			Expect(claims.ValidateAudience("test.azurecr.io")).To(Succeed())
+			Expect(claims.ValidateTenant(testTenantID)).To(Succeed())
+		})
+	})
+
+	Context("Validate", func() {
+		It("Checks the audience, expiry, not before and tenant of an ACR token", func() {
+			now := time.Now()
+			token := signTestToken(jwt.MapClaims{
+				"aud":    "test.azurecr.io",
+				"exp":    now.Add(time.Hour).Unix(),
+				"nbf":    now.Unix(),
+				"tenant": testTenantID,
+			}, signingKey)
+
+			claims, err := token.GetACRClaims()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(claims.Validate("test.azurecr.io", testTenantID, now)).To(Succeed())
+			Expect(claims.Validate("test.azurecr.io", "", now)).To(Succeed())
+			Expect(claims.Validate("other.azurecr.io", testTenantID, now)).To(MatchError(ContainSubstring("audience")))
+			Expect(claims.Validate("test.azurecr.io", "other-tenant", now)).To(MatchError(ContainSubstring("tenant")))
+			Expect(claims.Validate("test.azurecr.io", testTenantID, now.Add(-time.Hour))).To(MatchError(ContainSubstring("not valid before")))
+		})
+	})
+
+	Context("Time Validation", func() {
+		It("Tolerates clock skew", func() {
+			now := time.Now()
+			token := signTestToken(jwt.MapClaims{
+				"nbf": now.Add(time.Minute).Unix(),
+				"exp": now.Add(-time.Minute).Unix(),
+			}, signingKey)
+
+			claims, err := token.GetACRClaims()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(claims.ValidateNotBefore(now, DefaultClockSkew)).To(Succeed())
+			Expect(claims.ValidateNotBefore(now, 0)).NotTo(Succeed())
+			Expect(claims.ValidateExpiry(now, DefaultClockSkew)).To(Succeed())
+			Expect(claims.ValidateExpiry(now, 0)).NotTo(Succeed())
+		})
+
+		It("Returns error when the token has no expiration", func() {
+			token := signTestToken(jwt.MapClaims{"aud": "test.azurecr.io"}, signingKey)
+
+			claims, err := token.GetACRClaims()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.ValidateNotBefore(time.Now(), 0)).To(Succeed())
+			Expect(claims.ValidateExpiry(time.Now(), DefaultClockSkew)).NotTo(Succeed())
+		})
+	})
+})
+
+func signTestToken(claims jwt.MapClaims, signingKey *rsa.PrivateKey) AccessToken {
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signingKey)
+	Expect(err).ToNot(HaveOccurred())
+	return AccessToken(tokenString)
+}
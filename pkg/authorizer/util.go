@@ -1,9 +1,6 @@
 package authorizer
 
 import (
-	"encoding/base64"
-	"fmt"
-
 	"github.com/Azure/msi-acrpull/pkg/authorizer/types"
 )
 
@@ -20,9 +17,7 @@ type tokenResponse struct {
 
 // CreateACRDockerCfg creates an ACR docker config using given access token.
 func CreateACRDockerCfg(acrFQDN string, accessToken types.AccessToken) string {
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", acrUsername, accessToken)))
-	dockercfg := fmt.Sprintf("{\"auths\":{\"%s\":{\"username\":\"%s\",\"password\":\"%s\",\"email\":\"msi-acrpull@azurecr.io\",\"auth\":\"%s\"}}}",
-		acrFQDN, acrUsername, accessToken, auth)
-
-	return dockercfg
+	// marshalling string fields can not fail
+	dockercfg, _ := NewDockerConfigBuilder().Add(acrFQDN, accessToken).DockerConfigJSON()
+	return string(dockercfg)
 }
@@ -22,5 +22,104 @@ var _ = Describe("Util Tests", func() {
 
 			Expect(err).To(BeNil())
 		})
+
+		It("Escapes the token and omits the email", func() {
+			cfg := CreateACRDockerCfg(testACR, `token"with"quotes`)
+
+			var dockerConfig DockerConfigJSON
+			Expect(json.Unmarshal([]byte(cfg), &dockerConfig)).To(Succeed())
+			Expect(dockerConfig.Auths[testACR].Password).To(Equal(`token"with"quotes`))
+			Expect(cfg).NotTo(ContainSubstring("email"))
+		})
+	})
+
+	Context("Docker Config Builder", func() {
+		It("Adds the credential for every host alias", func() {
+			b := NewDockerConfigBuilder().Add(testACR, "acr-token", "testcr.westeurope.data.azurecr.io", "")
+
+			data, err := b.DockerConfigJSON()
+			Expect(err).ToNot(HaveOccurred())
+
+			var dockerConfig DockerConfigJSON
+			Expect(json.Unmarshal(data, &dockerConfig)).To(Succeed())
+			Expect(dockerConfig.Auths).To(HaveLen(2))
+			Expect(dockerConfig.Auths["testcr.westeurope.data.azurecr.io"]).To(Equal(dockerConfig.Auths[testACR]))
+			Expect(dockerConfig.Auths[testACR]).To(Equal(DockerConfigEntry{
+				Username: acrUsername,
+				Password: "acr-token",
+				Auth:     "MDAwMDAwMDAtMDAwMC0wMDAwLTAwMDAtMDAwMDAwMDAwMDAwOmFjci10b2tlbg==",
+			}))
+		})
+
+		It("Writes an identity token", func() {
+			b := NewDockerConfigBuilder()
+			b.IdentityToken = true
+
+			data, err := b.Add(testACR, "acr-token").DockerConfigJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"auths":{"` + testACR + `":{"username":"` + acrUsername + `","identitytoken":"acr-token"}}}`))
+		})
+
+		It("Writes the legacy format", func() {
+			data, err := NewDockerConfigBuilder().Add(testACR, "acr-token").DockerCfg()
+			Expect(err).ToNot(HaveOccurred())
+
+			var entries DockerConfigEntries
+			Expect(json.Unmarshal(data, &entries)).To(Succeed())
+			Expect(entries[testACR].Password).To(Equal("acr-token"))
+		})
+
+		It("Merges into an existing docker config", func() {
+			existing := []byte(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="},"old.azurecr.io":{"auth":"b2xk"}},"credHelpers":{"gcr.io":"gcloud"}}`)
+
+			data, err := NewDockerConfigBuilder().Add(testACR, "acr-token").MergeDockerConfigJSON(existing, []string{"old.azurecr.io"})
+			Expect(err).ToNot(HaveOccurred())
+
+			var config map[string]interface{}
+			Expect(json.Unmarshal(data, &config)).To(Succeed())
+			Expect(config["credHelpers"]).To(Equal(map[string]interface{}{"gcr.io": "gcloud"}))
+			auths := config["auths"].(map[string]interface{})
+			Expect(auths).To(HaveLen(2))
+			Expect(auths["docker.io"]).To(Equal(map[string]interface{}{"auth": "ZG9ja2VyOmh1Yg=="}))
+			Expect(auths[testACR].(map[string]interface{})["password"]).To(Equal("acr-token"))
+
+			data, err = NewDockerConfigBuilder().MergeDockerConfigJSON(data, []string{testACR})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"auths":{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}},"credHelpers":{"gcr.io":"gcloud"}}`))
+		})
+
+		It("Merges into an existing legacy docker config", func() {
+			data, err := NewDockerConfigBuilder().Add(testACR, "acr-token").MergeDockerCfg([]byte(`{"docker.io":{"auth":"ZG9ja2VyOmh1Yg=="}}`), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			var entries DockerConfigEntries
+			Expect(json.Unmarshal(data, &entries)).To(Succeed())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries["docker.io"].Auth).To(Equal("ZG9ja2VyOmh1Yg=="))
+		})
+
+		It("Returns error for a malformed docker config", func() {
+			_, err := NewDockerConfigBuilder().Add(testACR, "acr-token").MergeDockerConfigJSON([]byte(`{"auths":[]}`), nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Reads back the tokens it writes", func() {
+			data, err := NewDockerConfigBuilder().Add(testACR, "acr-token").DockerConfigJSON()
+			Expect(err).ToNot(HaveOccurred())
+			entries, err := ParseDockerConfigJSON(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries[testACR].Token()).To(BeEquivalentTo("acr-token"))
+
+			b := NewDockerConfigBuilder()
+			b.IdentityToken = true
+			data, err = b.Add(testACR, "refresh-token").DockerCfg()
+			Expect(err).ToNot(HaveOccurred())
+			entries, err = ParseDockerCfg(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries[testACR].Token()).To(BeEquivalentTo("refresh-token"))
+
+			_, err = ParseDockerConfigJSON([]byte(`{"auths":[]}`))
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
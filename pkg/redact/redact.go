@@ -0,0 +1,142 @@
+// Package redact removes token material from text before it leaves the controller in error
+// messages, logs, spans or the status of a resource. JWTs are recognized by their base64url
+// encoded JSON header, and the values of access_token and refresh_token fields are removed
+// whether they are JSON or form encoded, as registry and identity endpoints echo them back in
+// error responses.
+package redact
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/go-logr/logr"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+var (
+	// a JWT header always starts with {" which base64url encodes to eyJ; JWS have three segments
+	// and JWE five
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*(?:\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)?`)
+	// "access_token": "..." with any escaped quotes in the value
+	jsonFieldPattern = regexp.MustCompile(`("(?:access_token|refresh_token)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// access_token=... in form bodies and query strings
+	formFieldPattern = regexp.MustCompile(`\b((?:access_token|refresh_token)=)[^&\s"]*`)
+)
+
+// String returns s with every JWT and token field value replaced by Placeholder.
+func String(s string) string {
+	s = jsonFieldPattern.ReplaceAllString(s, `$1"`+Placeholder+`"`)
+	s = formFieldPattern.ReplaceAllString(s, "${1}"+Placeholder)
+	return jwtPattern.ReplaceAllString(s, Placeholder)
+}
+
+// Error returns err with a redacted message, or nil when err is nil. The returned error wraps
+// err, so errors.Is and errors.As still see through it.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*redactedError); ok {
+		return err
+	}
+	return &redactedError{err: err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return String(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Value returns v with its string form redacted when it is an error, a fmt.Stringer, a string or
+// a byte slice. Other values are returned as they are.
+func Value(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return Error(value)
+	case fmt.Stringer:
+		return String(value.String())
+	case []byte:
+		return String(string(value))
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return String(rv.String())
+	}
+	return v
+}
+
+// Logger returns a logger that redacts the messages, errors and values logged through it
+// before passing them to the sink of l.
+func Logger(l logr.Logger) logr.Logger {
+	if l.GetSink() == nil {
+		return l
+	}
+	return logr.New(&logSink{sink: l.GetSink()})
+}
+
+// logSink redacts everything logged before passing it to sink.
+type logSink struct {
+	sink logr.LogSink
+}
+
+var _ logr.CallDepthLogSink = &logSink{}
+
+func (s *logSink) Init(info logr.RuntimeInfo) {
+	// account for the frame of this sink so the underlying sink reports the caller
+	info.CallDepth++
+	s.sink.Init(info)
+}
+
+func (s *logSink) Enabled(level int) bool {
+	return s.sink.Enabled(level)
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(level, String(msg), values(keysAndValues)...)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(Error(err), String(msg), values(keysAndValues)...)
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{sink: s.sink.WithValues(values(keysAndValues)...)}
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	return &logSink{sink: s.sink.WithName(name)}
+}
+
+func (s *logSink) WithCallDepth(depth int) logr.LogSink {
+	if sink, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return &logSink{sink: sink.WithCallDepth(depth)}
+	}
+	return s
+}
+
+// values redacts the values of a list of alternating keys and values.
+func values(keysAndValues []interface{}) []interface{} {
+	if len(keysAndValues) == 0 {
+		return keysAndValues
+	}
+	redacted := make([]interface{}, len(keysAndValues))
+	for i, v := range keysAndValues {
+		if i%2 == 0 {
+			redacted[i] = v
+			continue
+		}
+		redacted[i] = Value(v)
+	}
+	return redacted
+}
@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redact Tests", func() {
+	var token string
+
+	BeforeEach(func() {
+		var err error
+		token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"aud": "test.azurecr.io",
+			"jti": "bb8d6d3d-c7b0-4f96-a390-8738f730e8c6",
+		}).SignedString([]byte("secret"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("String", func() {
+		It("Should redact JWTs anywhere in the text", func() {
+			redacted := String("token " + token + ". done")
+			Expect(redacted).To(Equal("token " + Placeholder + ". done"))
+		})
+
+		It("Should redact the token fields of JSON bodies", func() {
+			body := `{"refresh_token": "opaque-refresh", "access_token":"opaque\"access", "resource": "https://management.azure.com/"}`
+			redacted := String(body)
+			Expect(redacted).ToNot(ContainSubstring("opaque"))
+			Expect(redacted).To(ContainSubstring(`"refresh_token": "` + Placeholder + `"`))
+			Expect(redacted).To(ContainSubstring(`"access_token":"` + Placeholder + `"`))
+			Expect(redacted).To(ContainSubstring(`"resource": "https://management.azure.com/"`))
+		})
+
+		It("Should redact the token fields of form bodies", func() {
+			redacted := String("grant_type=refresh_token&refresh_token=opaque-refresh&access_token=opaque-access&service=test.azurecr.io")
+			Expect(redacted).To(Equal("grant_type=refresh_token&refresh_token=" + Placeholder + "&access_token=" + Placeholder + "&service=test.azurecr.io"))
+		})
+
+		It("Should leave other text alone", func() {
+			text := "ACR token exchange endpoint returned error status: 401. body: {\"errors\":[{\"code\":\"UNAUTHORIZED\"}]}"
+			Expect(String(text)).To(Equal(text))
+		})
+	})
+
+	Context("Error", func() {
+		It("Should redact the message and keep the chain", func() {
+			cause := errors.New("root cause")
+			err := Error(fmt.Errorf("response: %s: %w", token, cause))
+			Expect(err.Error()).To(Equal("response: " + Placeholder + ": root cause"))
+			Expect(errors.Is(err, cause)).To(BeTrue())
+			Expect(Error(err)).To(BeIdenticalTo(err))
+			Expect(Error(nil)).To(BeNil())
+		})
+	})
+
+	Context("Logger", func() {
+		It("Should redact messages, errors and values", func() {
+			var lines []string
+			log := Logger(funcr.New(func(prefix, args string) {
+				lines = append(lines, prefix+" "+args)
+			}, funcr.Options{Verbosity: 1}))
+
+			type accessToken string
+			log = log.WithName("test").WithValues("preset", token)
+			log.Info("issued "+token, "token", accessToken(token), "bytes", []byte(token), "count", 1)
+			log.V(1).Info("verbose", "body", `{"access_token":"opaque-access"}`)
+			log.Error(fmt.Errorf("failed: %s", token), "failed", "err", errors.New(token))
+
+			Expect(lines).To(HaveLen(3))
+			for _, line := range lines {
+				Expect(line).ToNot(ContainSubstring(token))
+				Expect(line).ToNot(ContainSubstring(strings.Split(token, ".")[1]))
+				Expect(line).ToNot(ContainSubstring("opaque-access"))
+			}
+			Expect(lines[0]).To(ContainSubstring(`"count"=1`))
+			Expect(lines[0]).To(ContainSubstring(`"preset"="` + Placeholder + `"`))
+		})
+
+		It("Should keep a logger without a sink", func() {
+			Expect(Logger(logr.Discard()).GetSink()).To(BeNil())
+		})
+	})
+})